@@ -14,7 +14,17 @@ import (
 func main() {
 	cfg := config.MustLoad()
 	log := setupLogger()
-	app := app.New(log, cfg.StoragePath, cfg.Port)
+
+	// Подкоманда migrate применяет миграции схемы БД и завершает работу
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := app.Migrate(log, cfg.StoragePath); err != nil {
+			log.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	app := app.New(log, cfg)
 
 	go func() {
 		app.MustRun()
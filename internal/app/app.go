@@ -7,27 +7,70 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"simple-invest/internal/config"
+	"simple-invest/internal/curves"
 	"simple-invest/internal/handlers"
+	"simple-invest/internal/portfolio"
 	"simple-invest/internal/repository"
+	"simple-invest/internal/repository/migrations"
+	"simple-invest/internal/scheduler"
 	"simple-invest/internal/securities"
+	"time"
 )
 
 type App struct {
-	log     *slog.Logger
-	db      *sql.DB
-	server  *http.Server
-	handler *handlers.Handler
+	log       *slog.Logger
+	db        *sql.DB
+	server    *http.Server
+	handler   *handlers.Handler
+	scheduler *scheduler.Scheduler
 }
 
-func New(log *slog.Logger, storagePath, port string) *App {
-	db, err := dbPostgreSQL(storagePath)
+func New(log *slog.Logger, cfg *config.Config) *App {
+	db, err := dbPostgreSQL(cfg.StoragePath)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := migrate(log, db); err != nil {
+		panic(err)
+	}
+
+	moex, err := securities.NewMoexClient(cfg.MoexURL, http.DefaultClient)
 	if err != nil {
 		panic(err)
 	}
 
+	fx, err := fxSource(cfg, moex)
+	if err != nil {
+		panic(err)
+	}
+
+	var priceSources []string
+	if cfg.PriceChain != "" {
+		if priceSources, err = securities.ParsePriceSources(cfg.PriceChain); err != nil {
+			panic(err)
+		}
+	}
+
 	repo := repository.NewPostgresRepo(db)
-	service := securities.New(repo)
-	handler := handlers.New(service)
+	service := securities.New(repo, moex, securities.Options{
+		BondizationMaxAge: cfg.BondizationMaxAge,
+		BatchWorkers:      cfg.BatchWorkers,
+		KeyRate:           cfg.KeyRate,
+		Inflation:         cfg.Inflation,
+		FX:                fx,
+		PriceSources:      priceSources,
+	})
+	ofz := curves.New(repo, moex)
+	jobs := scheduler.New(log,
+		scheduler.Job{Name: "shares", Interval: cfg.SharesRefreshInterval, Run: service.DownloadShares},
+		scheduler.Job{Name: "bonds", Interval: cfg.BondsRefreshInterval, Run: service.DownloadBonds},
+		scheduler.Job{Name: "bondization", Interval: cfg.BondizationRefreshInterval, Run: service.RefreshBondizations},
+		scheduler.Job{Name: "indicators", Interval: cfg.IndicatorsRefreshInterval, Run: service.RefreshIndicatorSnapshots},
+		scheduler.Job{Name: "zcyc", Interval: cfg.ZCYCRefreshInterval, Run: ofz.Refresh},
+	)
+	handler := handlers.New(service, portfolio.New(repo, service), ofz, jobs)
 
 	mux := http.NewServeMux()
 	setupRoutes(mux, handler)
@@ -37,9 +80,10 @@ func New(log *slog.Logger, storagePath, port string) *App {
 		db:  db,
 		server: &http.Server{
 			Handler: mux,
-			Addr:    fmt.Sprintf(":%s", port),
+			Addr:    fmt.Sprintf(":%s", cfg.Port),
 		},
-		handler: handler,
+		handler:   handler,
+		scheduler: jobs,
 	}
 
 	return app
@@ -49,13 +93,43 @@ func setupRoutes(mux *http.ServeMux, h *handlers.Handler) {
 	mux.HandleFunc("/", h.DefaultHandle)
 	mux.HandleFunc("GET /shares", h.Shares)
 	mux.HandleFunc("GET /bonds", h.Bonds)
+	mux.HandleFunc("GET /bonds/screen", h.ScreenBonds)
 	mux.HandleFunc("GET /dividends", h.Dividends)
+	mux.HandleFunc("GET /dividendyield", h.DividendYield)
 	mux.HandleFunc("GET /coupons", h.Coupons)
 	mux.HandleFunc("GET /amortizations", h.Amortizations)
 	mux.HandleFunc("GET /bondindicators", h.BondIndicators)
+	mux.HandleFunc("GET /bondindicators/price", h.BondPrice)
+	mux.HandleFunc("POST /bondindicators/batch", h.BatchBondIndicators)
+	mux.HandleFunc("GET /payments", h.Payments)
+	mux.HandleFunc("GET /curves/ofz", h.OFZCurve)
+	mux.HandleFunc("POST /scenarios", h.Scenarios)
+	mux.HandleFunc("GET /cashflows", h.CashFlows)
+	mux.HandleFunc("GET /calendar.ics", h.Calendar)
+	mux.HandleFunc("GET /jobs", h.Jobs)
+
+	mux.HandleFunc("GET /portfolios", h.Portfolios)
+	mux.HandleFunc("POST /portfolios", h.CreatePortfolio)
+	mux.HandleFunc("GET /portfolios/{id}", h.Portfolio)
+	mux.HandleFunc("PUT /portfolios/{id}", h.UpdatePortfolio)
+	mux.HandleFunc("DELETE /portfolios/{id}", h.DeletePortfolio)
+	mux.HandleFunc("GET /portfolios/{id}/accounts", h.Accounts)
+	mux.HandleFunc("POST /portfolios/{id}/accounts", h.CreateAccount)
+	mux.HandleFunc("PUT /portfolios/{id}/accounts/{account}", h.UpdateAccount)
+	mux.HandleFunc("DELETE /portfolios/{id}/accounts/{account}", h.DeleteAccount)
+	mux.HandleFunc("GET /portfolios/{id}/trades", h.Trades)
+	mux.HandleFunc("POST /portfolios/{id}/trades", h.CreateTrade)
+	mux.HandleFunc("PUT /portfolios/{id}/trades/{trade}", h.UpdateTrade)
+	mux.HandleFunc("DELETE /portfolios/{id}/trades/{trade}", h.DeleteTrade)
+	mux.HandleFunc("GET /portfolios/{id}/positions", h.Positions)
+	mux.HandleFunc("GET /portfolios/{id}/valuation", h.Valuation)
+	mux.HandleFunc("GET /portfolios/{id}/taxes", h.Taxes)
+	mux.HandleFunc("GET /portfolios/{id}/cashflows", h.PortfolioCashFlows)
 }
 
 func (app *App) MustRun() {
+	app.scheduler.Start()
+
 	app.log.Info("server started")
 	if err := app.server.ListenAndServe(); err != nil {
 		if !errors.Is(err, http.ErrServerClosed) {
@@ -70,11 +144,43 @@ func (app *App) Stop(ctx context.Context) {
 		app.log.Error(err.Error())
 	}
 
+	if err := app.scheduler.Stop(ctx); err != nil {
+		app.log.Error(err.Error())
+	}
+
 	if err := app.db.Close(); err != nil {
 		app.log.Error(err.Error())
 	}
 }
 
+// Migrate применяет миграции схемы БД без запуска сервиса
+func Migrate(log *slog.Logger, storagePath string) error {
+	db, err := dbPostgreSQL(storagePath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return migrate(log, db)
+}
+
+func migrate(log *slog.Logger, db *sql.DB) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	applied, err := migrations.Up(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	version, err := migrations.Version(ctx, db)
+	if err != nil {
+		return err
+	}
+	log.Info("database schema is up to date", slog.Int("version", version), slog.Int("applied", applied))
+	return nil
+}
+
 func dbPostgreSQL(storagePath string) (*sql.DB, error) {
 	db, err := sql.Open("postgres", storagePath)
 	if err != nil {
@@ -82,3 +188,16 @@ func dbPostgreSQL(storagePath string) (*sql.DB, error) {
 	}
 	return db, nil
 }
+
+// fxSource возвращает источник курсов валют, заданный в настройках
+func fxSource(cfg *config.Config, moex *securities.MoexClient) (securities.FXSource, error) {
+	switch cfg.FXSource {
+	case "", securities.FXMoex:
+		return securities.NewMoexFX(moex), nil
+	case securities.FXCBR:
+		return securities.NewCBRClient(cfg.CBRURL, http.DefaultClient), nil
+	case securities.FXStatic:
+		return securities.ParseStaticFX(cfg.FXRates)
+	}
+	return nil, fmt.Errorf("unknown fx source %q", cfg.FXSource)
+}
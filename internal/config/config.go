@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -14,6 +16,22 @@ type Config struct {
 	StoragePath string
 	Port        string
 	Timeout     int
+	MoexURL     string // Адрес ISS Мосбиржи, по умолчанию https://iss.moex.com
+	FXSource    string // Источник курсов валют: moex (по умолчанию), cbr, static
+	CBRURL      string // Адрес сайта Банка России, по умолчанию https://www.cbr.ru
+	FXRates     string // Курсы валют для источника static в формате USD=90.5,CNY=12.4
+	PriceChain  string // Порядок источников цены облигации, например last,marketprice,waprice,prevprice,mid
+
+	BondizationMaxAge time.Duration // Срок актуальности сохранённого графика выплат облигаций
+	BatchWorkers      int           // Количество одновременных расчётов в пакетном запросе показателей облигаций
+	KeyRate           float64       // Ключевая ставка Банка России для прогноза купонов флоатеров, %, 0 - использовать RUONIA
+	Inflation         float64       // Ожидаемая инфляция для индексации номинала линкеров, % годовых
+
+	SharesRefreshInterval      time.Duration // Интервал загрузки списка акций, 0 - не загружать
+	BondsRefreshInterval       time.Duration // Интервал загрузки списка облигаций, 0 - не загружать
+	BondizationRefreshInterval time.Duration // Интервал обновления графиков выплат облигаций, 0 - не обновлять
+	IndicatorsRefreshInterval  time.Duration // Интервал расчёта показателей облигаций для отбора, 0 - не рассчитывать
+	ZCYCRefreshInterval        time.Duration // Интервал загрузки параметров G-кривой, 0 - не загружать
 }
 
 func MustLoad() *Config {
@@ -27,7 +45,65 @@ func MustLoad() *Config {
 		StoragePath: storagePath(),
 		Port:        os.Getenv("APP_PORT"),
 		Timeout:     timeout,
+		MoexURL:     os.Getenv("MOEX_URL"),
+		FXSource:    os.Getenv("FX_SOURCE"),
+		CBRURL:      os.Getenv("CBR_URL"),
+		FXRates:     os.Getenv("FX_RATES"),
+		PriceChain:  os.Getenv("PRICE_SOURCES"),
+
+		BondizationMaxAge: duration("BONDIZATION_MAX_AGE", time.Hour*24),
+		BatchWorkers:      integer("BATCH_WORKERS", 8),
+		KeyRate:           float("KEY_RATE", 0),
+		Inflation:         float("INFLATION", 4),
+
+		SharesRefreshInterval:      duration("SHARES_REFRESH_INTERVAL", time.Hour*24),
+		BondsRefreshInterval:       duration("BONDS_REFRESH_INTERVAL", time.Hour*24),
+		BondizationRefreshInterval: duration("BONDIZATION_REFRESH_INTERVAL", time.Hour*24),
+		IndicatorsRefreshInterval:  duration("INDICATORS_REFRESH_INTERVAL", time.Hour),
+		ZCYCRefreshInterval:        duration("ZCYC_REFRESH_INTERVAL", time.Hour*24),
+	}
+}
+
+// duration возвращает длительность из переменной окружения name в формате time.ParseDuration (например, 12h)
+func duration(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("incorrect %s value %q, using %s", name, value, def)
+		return def
+	}
+	return d
+}
+
+// integer возвращает целое число из переменной окружения name
+func integer(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("incorrect %s value %q, using %d", name, value, def)
+		return def
+	}
+	return n
+}
+
+// float возвращает число из переменной окружения name
+func float(name string, def float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("incorrect %s value %q, using %g", name, value, def)
+		return def
 	}
+	return f
 }
 
 func storagePath() string {
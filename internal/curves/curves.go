@@ -0,0 +1,181 @@
+// Пакет curves рассчитывает доходности по кривой бескупонной доходности ОФЗ (G-кривой) Мосбиржи
+// и хранит её параметры по датам.
+package curves
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"simple-invest/internal/models"
+	"simple-invest/internal/repository"
+)
+
+// Параметры поправочных слагаемых G-кривой по методике Мосбиржи
+const (
+	termA = 0.6 // Положение второго слагаемого, лет
+	termK = 1.6 // Коэффициент роста положения и ширины слагаемых
+)
+
+const (
+	maxTenor    = 30.0      // Максимальный срок, на который Мосбиржа рассчитывает G-кривую, лет
+	precision   = 4         // Точность доходностей
+	resolvedTTL = time.Hour // Время, в течение которого дата без расчёта кривой не запрашивается повторно
+)
+
+// DefaultTenors - сроки, на которые по умолчанию рассчитываются доходности, лет
+var DefaultTenors = []float64{0.25, 0.5, 0.75, 1, 2, 3, 5, 7, 10, 15, 20, 30}
+
+// ErrIncorrectTenor возвращается при сроке вне интервала, на котором определена G-кривая
+var ErrIncorrectTenor = errors.New("incorrect tenor")
+
+// Source загружает параметры G-кривой. Нулевая дата соответствует последним рассчитанным параметрам.
+type Source interface {
+	ZCYC(ctx context.Context, date time.Time) (models.ZCYC, error)
+}
+
+// TenorYield - доходность G-кривой на срок
+type TenorYield struct {
+	Tenor float64 `json:"tenor"` // Срок, лет
+	Yield float64 `json:"yield"` // Бескупонная доходность, в долях, годовых
+}
+
+// Curve - параметры G-кривой с доходностями на заданные сроки
+type Curve struct {
+	models.ZCYC
+	Yields []TenorYield `json:"yields"`
+}
+
+type CurveService struct {
+	repo repository.Repository
+	src  Source
+
+	mu       sync.Mutex
+	resolved map[string]resolvedDate // Даты без расчёта кривой
+}
+
+// resolvedDate - дата последнего расчёта кривой, полученная с Мосбиржи по запросу на более позднюю дату
+type resolvedDate struct {
+	date    string
+	expires time.Time
+}
+
+func New(repo repository.Repository, src Source) *CurveService {
+	return &CurveService{repo: repo, src: src, resolved: make(map[string]resolvedDate)}
+}
+
+// ZCYC возвращает параметры G-кривой на дату date. Сохранённые параметры используются, если они рассчитаны
+// на эту дату или на дату последнего расчёта до неё, которую Мосбиржа вернула на запрос даты без торгов,
+// иначе параметры загружаются с Мосбиржи и сохраняются. При недоступности Мосбиржи возвращаются сохранённые
+// параметры на ближайшую предшествующую дату.
+func (s *CurveService) ZCYC(ctx context.Context, date time.Time) (models.ZCYC, error) {
+	day := date.Format(time.DateOnly)
+	stored, err := s.repo.GetZCYC(date)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return stored, err
+	}
+	if err == nil && (stored.Date == day || stored.Date == s.resolvedDate(day)) {
+		return stored, nil
+	}
+
+	loaded, loadErr := s.src.ZCYC(ctx, date)
+	if loadErr != nil {
+		if err == nil {
+			log.Printf("zcyc %s: using stored curve for %s: %v", date.Format(time.DateOnly), stored.Date, loadErr)
+			return stored, nil
+		}
+		return loaded, loadErr
+	}
+	if err := s.repo.UpdateZCYC(loaded); err != nil {
+		return loaded, err
+	}
+	if loaded.Date != day {
+		s.resolve(day, loaded.Date)
+	}
+	return loaded, nil
+}
+
+// resolvedDate возвращает дату последнего расчёта кривой, полученную с Мосбиржи по запросу даты day
+func (s *CurveService) resolvedDate(day string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.resolved[day]
+	if !ok || time.Now().After(r.expires) {
+		delete(s.resolved, day)
+		return ""
+	}
+	return r.date
+}
+
+// resolve запоминает, что на запрос даты day Мосбиржа вернула кривую на более раннюю дату date
+func (s *CurveService) resolve(day, date string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolved[day] = resolvedDate{date: date, expires: time.Now().Add(resolvedTTL)}
+}
+
+// OFZ возвращает G-кривую на дату date с доходностями на сроки tenors, по умолчанию - DefaultTenors
+func (s *CurveService) OFZ(ctx context.Context, date time.Time, tenors []float64) (Curve, error) {
+	if len(tenors) == 0 {
+		tenors = DefaultTenors
+	}
+	for _, t := range tenors {
+		if t <= 0 || t > maxTenor {
+			return Curve{}, fmt.Errorf("%w: %v, expected (0, %v] years", ErrIncorrectTenor, t, maxTenor)
+		}
+	}
+
+	zcyc, err := s.ZCYC(ctx, date)
+	if err != nil {
+		return Curve{}, err
+	}
+
+	c := Curve{ZCYC: zcyc, Yields: make([]TenorYield, len(tenors))}
+	for i, t := range tenors {
+		c.Yields[i] = TenorYield{Tenor: t, Yield: roundFloat(Yield(zcyc, t), precision)}
+	}
+	return c, nil
+}
+
+// Refresh загружает с Мосбиржи и сохраняет последние рассчитанные параметры G-кривой
+func (s *CurveService) Refresh(ctx context.Context) error {
+	zcyc, err := s.src.ZCYC(ctx, time.Time{})
+	if err != nil {
+		return err
+	}
+	return s.repo.UpdateZCYC(zcyc)
+}
+
+// Rate возвращает непрерывно начисляемую бескупонную доходность на срок t лет, в долях
+func Rate(p models.ZCYC, t float64) float64 {
+	e := math.Exp(-t / p.T1)
+	g := p.B1 + (p.B2+p.B3)*p.T1/t*(1-e) - p.B3*e
+
+	a, b := 0.0, termA
+	for i, gi := range p.G {
+		g += gi * math.Exp(-(t-a)*(t-a)/(b*b))
+		a += termA * math.Pow(termK, float64(i))
+		b *= termK
+	}
+	return g / 10000
+}
+
+// Yield возвращает бескупонную доходность на срок t лет с ежегодным начислением, в долях
+func Yield(p models.ZCYC, t float64) float64 {
+	return math.Exp(Rate(p, t)) - 1
+}
+
+// Discount возвращает коэффициент дисконтирования для выплаты через t лет
+func Discount(p models.ZCYC, t float64) float64 {
+	return math.Exp(-Rate(p, t) * t)
+}
+
+func roundFloat(val float64, precision uint) float64 {
+	ratio := math.Pow(10, float64(precision))
+	return math.Round(val*ratio) / ratio
+}
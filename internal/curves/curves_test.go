@@ -0,0 +1,152 @@
+package curves
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"simple-invest/internal/models"
+	"simple-invest/internal/repository"
+	"simple-invest/internal/securities/moextest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memRepo хранит параметры G-кривой в памяти. Нереализованные методы Repository вызывают панику.
+type memRepo struct {
+	repository.Repository
+	zcyc map[string]models.ZCYC
+}
+
+func (r *memRepo) GetZCYC(date time.Time) (models.ZCYC, error) {
+	dates := make([]string, 0, len(r.zcyc))
+	for d := range r.zcyc {
+		dates = append(dates, d)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(dates)))
+	for _, d := range dates {
+		if d <= date.Format(time.DateOnly) {
+			return r.zcyc[d], nil
+		}
+	}
+	return models.ZCYC{}, repository.ErrNotFound
+}
+
+func (r *memRepo) UpdateZCYC(c models.ZCYC) error {
+	r.zcyc[c.Date] = c
+	return nil
+}
+
+// stubSource возвращает параметры кривой на запрошенную дату и считает обращения.
+// Для дат выходных возвращаются параметры на предшествующую пятницу.
+type stubSource struct {
+	calls int
+	err   error
+}
+
+func (s *stubSource) ZCYC(ctx context.Context, date time.Time) (models.ZCYC, error) {
+	s.calls++
+	if s.err != nil {
+		return models.ZCYC{}, s.err
+	}
+	c := moextest.ZCYC
+	if !date.IsZero() {
+		for date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+			date = date.AddDate(0, 0, -1)
+		}
+		c.Date = date.Format(time.DateOnly)
+	}
+	return c, nil
+}
+
+func TestYield(t *testing.T) {
+	tests := []struct {
+		tenor    float64
+		yield    float64
+		discount float64
+	}{
+		{0.25, 0.1656, 0.962413},
+		{1, 0.1568, 0.864443},
+		{5, 0.1468, 0.504071},
+		{30, 0.144, 0.017689},
+	}
+	for _, tt := range tests {
+		assert.InDelta(t, tt.yield, Yield(moextest.ZCYC, tt.tenor), 5e-5, tt.tenor)
+		assert.InDelta(t, tt.discount, Discount(moextest.ZCYC, tt.tenor), 1e-6, tt.tenor)
+		assert.InDelta(t, math.Log(1+Yield(moextest.ZCYC, tt.tenor)), Rate(moextest.ZCYC, tt.tenor), 1e-12, tt.tenor)
+	}
+}
+
+func TestCurveService_ZCYC(t *testing.T) {
+	repo := &memRepo{zcyc: map[string]models.ZCYC{}}
+	src := &stubSource{}
+	s := New(repo, src)
+	ctx := context.Background()
+	date := time.Date(2024, 6, 4, 0, 0, 0, 0, time.UTC)
+
+	// Параметры загружаются с Мосбиржи и сохраняются
+	got, err := s.ZCYC(ctx, date)
+	require.NoError(t, err)
+	assert.Equal(t, moextest.ZCYC, got)
+	assert.Contains(t, repo.zcyc, "2024-06-04")
+
+	// Повторный запрос обслуживается из хранилища
+	_, err = s.ZCYC(ctx, date)
+	require.NoError(t, err)
+	assert.Equal(t, 1, src.calls)
+
+	// На дату без торгов Мосбиржа возвращает последний расчёт, повторный запрос обслуживается из хранилища
+	saturday := time.Date(2024, 6, 8, 0, 0, 0, 0, time.UTC)
+	got, err = s.ZCYC(ctx, saturday)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-06-07", got.Date)
+	got, err = s.ZCYC(ctx, saturday)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-06-07", got.Date)
+	assert.Equal(t, 2, src.calls)
+
+	// По истечении времени хранения дата без торгов запрашивается повторно
+	s.resolved["2024-06-08"] = resolvedDate{date: "2024-06-07", expires: time.Now().Add(-time.Second)}
+	_, err = s.ZCYC(ctx, saturday)
+	require.NoError(t, err)
+	assert.Equal(t, 3, src.calls)
+
+	// При недоступности Мосбиржи используются параметры на предшествующую дату
+	src.err = errors.New("moex is down")
+	got, err = s.ZCYC(ctx, date.AddDate(0, 0, 2))
+	require.NoError(t, err)
+	assert.Equal(t, "2024-06-04", got.Date)
+
+	_, err = s.ZCYC(ctx, date.AddDate(0, 0, -1))
+	assert.ErrorIs(t, err, src.err)
+
+	// Последние параметры загружаются по расписанию
+	src.err = nil
+	require.NoError(t, s.Refresh(ctx))
+	assert.Equal(t, 6, src.calls)
+}
+
+func TestCurveService_OFZ(t *testing.T) {
+	s := New(&memRepo{zcyc: map[string]models.ZCYC{}}, &stubSource{})
+	ctx := context.Background()
+	date := time.Date(2024, 6, 4, 0, 0, 0, 0, time.UTC)
+
+	got, err := s.OFZ(ctx, date, nil)
+	require.NoError(t, err)
+	assert.Equal(t, moextest.ZCYC, got.ZCYC)
+	require.Len(t, got.Yields, len(DefaultTenors))
+	assert.Equal(t, TenorYield{Tenor: 1, Yield: 0.1568}, got.Yields[3])
+
+	got, err = s.OFZ(ctx, date, []float64{5, 10})
+	require.NoError(t, err)
+	assert.Equal(t, []TenorYield{{Tenor: 5, Yield: 0.1468}, {Tenor: 10, Yield: 0.1447}}, got.Yields)
+
+	for _, tenors := range [][]float64{{0}, {-1}, {31}} {
+		_, err = s.OFZ(ctx, date, tenors)
+		assert.ErrorIs(t, err, ErrIncorrectTenor)
+	}
+}
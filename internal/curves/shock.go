@@ -0,0 +1,117 @@
+package curves
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Виды сдвига кривой доходности
+const (
+	ShockParallel  = "parallel"  // Параллельный сдвиг
+	ShockSteepener = "steepener" // Рост наклона: короткие ставки снижаются, длинные растут
+	ShockFlattener = "flattener" // Снижение наклона: короткие ставки растут, длинные снижаются
+	ShockCustom    = "custom"    // Сдвиги по срокам с линейной интерполяцией
+)
+
+const (
+	defaultPivot = 5.0    // Срок, вокруг которого по умолчанию поворачивается кривая, лет
+	maxShift     = 5000.0 // Максимальная по модулю величина сдвига, б.п.
+)
+
+// ErrIncorrectShock возвращается при некорректных параметрах сдвига кривой
+var ErrIncorrectShock = errors.New("incorrect curve shock")
+
+// ShockPoint - сдвиг кривой на срок
+type ShockPoint struct {
+	Tenor float64 `json:"tenor"`    // Срок, лет
+	Shift float64 `json:"shift_bp"` // Сдвиг, б.п.
+}
+
+// Shock описывает сценарий сдвига кривой доходности.
+// Для steepener и flattener кривая поворачивается вокруг срока Pivot: ставка на нулевой срок сдвигается
+// на половину Shift, на срок 2*Pivot и более - на половину Shift в противоположную сторону, так что наклон
+// кривой меняется на Shift. Для custom сдвиг между точками Points интерполируется линейно,
+// за их пределами равен сдвигу в крайней точке.
+type Shock struct {
+	Name   string       `json:"name"`             // Название сценария
+	Type   string       `json:"type"`             // Вид сдвига
+	Shift  float64      `json:"shift_bp"`         // Величина сдвига или изменения наклона, б.п.
+	Pivot  float64      `json:"pivot,omitempty"`  // Срок поворота кривой, лет, по умолчанию 5
+	Points []ShockPoint `json:"points,omitempty"` // Сдвиги по срокам для custom
+}
+
+// Validate проверяет параметры сдвига, упорядочивает точки по сроку и задаёт срок поворота по умолчанию
+func (s *Shock) Validate() error {
+	if math.Abs(s.Shift) > maxShift {
+		return fmt.Errorf("%w: shift %v bp exceeds %v bp", ErrIncorrectShock, s.Shift, maxShift)
+	}
+	switch s.Type {
+	case ShockParallel:
+	case ShockSteepener, ShockFlattener:
+		if s.Pivot < 0 {
+			return fmt.Errorf("%w: pivot must be positive", ErrIncorrectShock)
+		}
+		if s.Pivot == 0 {
+			s.Pivot = defaultPivot
+		}
+	case ShockCustom:
+		if len(s.Points) == 0 {
+			return fmt.Errorf("%w: custom shock without points", ErrIncorrectShock)
+		}
+		sort.Slice(s.Points, func(i, j int) bool { return s.Points[i].Tenor < s.Points[j].Tenor })
+		for i, p := range s.Points {
+			if p.Tenor < 0 || (i > 0 && p.Tenor == s.Points[i-1].Tenor) {
+				return fmt.Errorf("%w: incorrect tenor %v", ErrIncorrectShock, p.Tenor)
+			}
+			if math.Abs(p.Shift) > maxShift {
+				return fmt.Errorf("%w: shift %v bp exceeds %v bp", ErrIncorrectShock, p.Shift, maxShift)
+			}
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrIncorrectShock, s.Type)
+	}
+	if s.Name == "" {
+		s.Name = s.Type
+	}
+	return nil
+}
+
+// At возвращает сдвиг ставки на срок t лет, в долях
+func (s Shock) At(t float64) float64 {
+	var bp float64
+	switch s.Type {
+	case ShockParallel:
+		bp = s.Shift
+	case ShockSteepener:
+		bp = s.Shift / 2 * twist(t, s.Pivot)
+	case ShockFlattener:
+		bp = -s.Shift / 2 * twist(t, s.Pivot)
+	case ShockCustom:
+		bp = interpolate(s.Points, t)
+	}
+	return bp / 10000
+}
+
+// twist возвращает долю поворота кривой на срок t: от -1 на нулевой срок до 1 на срок 2*pivot и более
+func twist(t, pivot float64) float64 {
+	return max(-1, min(1, (t-pivot)/pivot))
+}
+
+// interpolate линейно интерполирует сдвиг по упорядоченным по сроку точкам
+func interpolate(points []ShockPoint, t float64) float64 {
+	if len(points) == 0 {
+		return 0
+	}
+	if t <= points[0].Tenor {
+		return points[0].Shift
+	}
+	for i := 1; i < len(points); i++ {
+		if t <= points[i].Tenor {
+			left, right := points[i-1], points[i]
+			return left.Shift + (right.Shift-left.Shift)*(t-left.Tenor)/(right.Tenor-left.Tenor)
+		}
+	}
+	return points[len(points)-1].Shift
+}
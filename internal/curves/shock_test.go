@@ -0,0 +1,66 @@
+package curves
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShock_At(t *testing.T) {
+	tests := []struct {
+		name  string
+		shock Shock
+		want  map[float64]float64 // Сдвиг в б.п. по срокам
+	}{
+		{
+			"parallel",
+			Shock{Type: ShockParallel, Shift: 200},
+			map[float64]float64{0.5: 200, 10: 200},
+		},
+		{
+			"steepener",
+			Shock{Type: ShockSteepener, Shift: 100},
+			map[float64]float64{0: -50, 2.5: -25, 5: 0, 10: 50, 20: 50},
+		},
+		{
+			"flattener",
+			Shock{Type: ShockFlattener, Shift: 100, Pivot: 2},
+			map[float64]float64{0: 50, 2: 0, 3: -25, 10: -50},
+		},
+		{
+			"custom",
+			Shock{Type: ShockCustom, Points: []ShockPoint{{10, 50}, {1, 300}, {3, 100}}},
+			map[float64]float64{0.5: 300, 2: 200, 6.5: 75, 15: 50},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.shock.Validate())
+			assert.Equal(t, tt.name, tt.shock.Name)
+			for tenor, bp := range tt.want {
+				assert.InDelta(t, bp/10000, tt.shock.At(tenor), 1e-12, tenor)
+			}
+		})
+	}
+}
+
+func TestShock_Validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		shock Shock
+	}{
+		{"unknown type", Shock{Type: "twist", Shift: 100}},
+		{"negative pivot", Shock{Type: ShockSteepener, Shift: 100, Pivot: -1}},
+		{"too large shift", Shock{Type: ShockParallel, Shift: -6000}},
+		{"no points", Shock{Type: ShockCustom}},
+		{"duplicate tenor", Shock{Type: ShockCustom, Points: []ShockPoint{{1, 100}, {1, 200}}}},
+		{"negative tenor", Shock{Type: ShockCustom, Points: []ShockPoint{{-1, 100}}}},
+		{"too large point shift", Shock{Type: ShockCustom, Points: []ShockPoint{{1, 5001}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.shock.Validate(), ErrIncorrectShock)
+		})
+	}
+}
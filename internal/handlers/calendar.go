@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"simple-invest/internal/models"
+)
+
+const (
+	msgCalendarTarget = "Either isin or portfolio must be specified"
+	calendarProductID = "-//simple-invest//calendar//RU"
+	icsLineLength     = 75 // Максимальная длина строки iCalendar в байтах
+)
+
+// Заголовки событий календаря по видам
+var calendarTitles = map[string]string{
+	models.EventCoupon:       "Купон",
+	models.EventCouponRecord: "Фиксация реестра под купон",
+	models.EventAmortization: "Амортизация",
+	models.EventMaturity:     "Погашение",
+	models.EventOffer:        "Оферта",
+	models.EventDividend:     "Закрытие реестра под дивиденды",
+}
+
+func (h *Handler) Calendar(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	isins, portfolioID := query.Get("isin"), query.Get("portfolio")
+	if (isins == "") == (portfolioID == "") {
+		log.Print(msgCalendarTarget)
+		writeError(w, msgCalendarTarget, http.StatusBadRequest)
+		return
+	}
+	from, err := dateParam(req, "from", time.Now().Truncate(time.Hour*24))
+	if err != nil {
+		log.Print(err)
+		writeError(w, msgIncorrectDate, http.StatusBadRequest)
+		return
+	}
+
+	if portfolioID != "" {
+		id, err := strconv.ParseInt(portfolioID, 10, 64)
+		if err != nil || id <= 0 {
+			log.Printf("%s portfolio: %q", msgIncorrectID, portfolioID)
+			writeError(w, fmt.Sprintf("%s portfolio: %q", msgIncorrectID, portfolioID), http.StatusBadRequest)
+			return
+		}
+		p, err := h.portfolios.Portfolio(id)
+		if err != nil {
+			writePortfolioError(w, err)
+			return
+		}
+		events, err := h.portfolios.CalendarEvents(req.Context(), id, from)
+		if err != nil {
+			writePortfolioError(w, err)
+			return
+		}
+		writeCalendar(w, p.Name, events)
+		return
+	}
+
+	quantity, err := intParam(req, "quantity")
+	if err == nil && quantity != nil && *quantity <= 0 {
+		err = fmt.Errorf("%s quantity: %d", msgIncorrectParam, *quantity)
+	}
+	if err != nil {
+		log.Print(err)
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	n := int64(1)
+	if quantity != nil {
+		n = *quantity
+	}
+
+	events := []models.CalendarEvent{}
+	for _, isin := range strings.Split(isins, ",") {
+		e, err := h.service.CalendarEvents(req.Context(), strings.TrimSpace(isin), n, from)
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+		events = append(events, e...)
+	}
+	writeCalendar(w, isins, events)
+}
+
+// writeCalendar отправляет события календаря name в формате iCalendar (RFC 5545)
+func writeCalendar(w http.ResponseWriter, name string, events []models.CalendarEvent) {
+	w.Header().Set("content-type", "text/calendar; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(encodeCalendar(name, events, time.Now()))
+}
+
+// encodeCalendar формирует календарь name с событиями на весь день в формате iCalendar.
+// Суммы выплат указываются в описании событий.
+func encodeCalendar(name string, events []models.CalendarEvent, stamp time.Time) []byte {
+	var buf bytes.Buffer
+	line := func(s string) {
+		buf.WriteString(foldLine(s))
+		buf.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:" + calendarProductID)
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + escapeText(name))
+	for _, e := range events {
+		date, err := time.Parse(time.DateOnly, e.Date)
+		if err != nil {
+			log.Printf("calendar %s: %v", e.Isin, err)
+			continue
+		}
+		line("BEGIN:VEVENT")
+		line(fmt.Sprintf("UID:%s-%s-%s@simple-invest", e.Isin, e.Type, date.Format("20060102")))
+		line("DTSTAMP:" + stamp.UTC().Format("20060102T150405Z"))
+		line("DTSTART;VALUE=DATE:" + date.Format("20060102"))
+		line("DTEND;VALUE=DATE:" + date.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY:" + escapeText(calendarTitles[e.Type]+" "+e.Ticker))
+		line("DESCRIPTION:" + escapeText(eventDescription(e)))
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return buf.Bytes()
+}
+
+// eventDescription возвращает описание события с суммами на одну бумагу и по всем бумагам
+func eventDescription(e models.CalendarEvent) string {
+	lines := []string{"ISIN: " + e.Isin, fmt.Sprintf("Количество: %d", e.Quantity)}
+	if e.Value == 0 {
+		lines = append(lines, "Сумма не объявлена")
+	} else {
+		lines = append(lines,
+			fmt.Sprintf("На одну бумагу: %s %s", formatAmount(e.Value), e.Currency),
+			fmt.Sprintf("Всего: %s %s", formatAmount(e.Amount), e.Currency))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatAmount форматирует сумму без лишних нулей в дробной части
+func formatAmount(x float64) string {
+	return strconv.FormatFloat(x, 'f', -1, 64)
+}
+
+// escapeText экранирует специальные символы текстового значения iCalendar
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// foldLine переносит строку длиннее icsLineLength байт, не разрывая символы UTF-8.
+// Строки продолжения начинаются с пробела.
+func foldLine(s string) string {
+	if len(s) <= icsLineLength {
+		return s
+	}
+	var b strings.Builder
+	limit := icsLineLength
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = icsLineLength - 1
+	}
+	b.WriteString(s)
+	return b.String()
+}
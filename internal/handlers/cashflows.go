@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"simple-invest/internal/models"
+)
+
+const defaultCashFlowsPeriod = 365 // Период графика выплат портфеля по умолчанию, дней
+
+func (h *Handler) CashFlows(w http.ResponseWriter, req *http.Request) {
+	isin := req.URL.Query().Get("isin")
+	if isin == "" {
+		log.Print(msgEmptyID)
+		writeError(w, msgEmptyID, http.StatusBadRequest)
+		return
+	}
+	quantity, err := intParam(req, "quantity")
+	if err == nil && quantity != nil && *quantity <= 0 {
+		err = fmt.Errorf("%s quantity: %d", msgIncorrectParam, *quantity)
+	}
+	var cost *float64
+	if err == nil {
+		cost, err = floatParam(req, "cost")
+	}
+	if err == nil && cost != nil && *cost <= 0 {
+		err = fmt.Errorf("%s cost: %v", msgIncorrectParam, *cost)
+	}
+	if err != nil {
+		log.Print(err)
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tm, err := taxModelParam(req)
+	if err != nil {
+		log.Print(err)
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	n := int64(1)
+	if quantity != nil {
+		n = *quantity
+	}
+	c := 0.0
+	if cost != nil {
+		c = *cost
+	}
+	flows, err := h.service.CashFlows(req.Context(), isin, []models.Lot{{Quantity: n, Cost: c}}, tm)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, flows)
+}
+
+func (h *Handler) PortfolioCashFlows(w http.ResponseWriter, req *http.Request) {
+	id, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+	from, err := dateParam(req, "from", time.Now().Truncate(time.Hour*24))
+	var to time.Time
+	if err == nil {
+		to, err = dateParam(req, "to", from.AddDate(0, 0, defaultCashFlowsPeriod))
+	}
+	if err != nil {
+		log.Print(err)
+		writeError(w, msgIncorrectDate, http.StatusBadRequest)
+		return
+	}
+
+	flows, err := h.portfolios.CashFlows(req.Context(), id, from, to)
+	if err != nil {
+		writePortfolioError(w, err)
+		return
+	}
+	writeJSON(w, flows)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"simple-invest/internal/curves"
+)
+
+func (h *Handler) OFZCurve(w http.ResponseWriter, req *http.Request) {
+	date, err := dateParam(req, "date", time.Now().Truncate(time.Hour*24))
+	if err != nil {
+		log.Print(err)
+		writeError(w, msgIncorrectDate, http.StatusBadRequest)
+		return
+	}
+	tenors, err := tenorsParam(req)
+	if err != nil {
+		log.Print(err)
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	curve, err := h.curves.OFZ(req.Context(), date, tenors)
+	if errors.Is(err, curves.ErrIncorrectTenor) {
+		log.Print(err)
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Print(err)
+		writeError(w, msgMoexGettingDataFailed, http.StatusServiceUnavailable)
+		return
+	}
+
+	writeJSON(w, curve)
+}
+
+// tenorsParam возвращает сроки в годах из параметра запроса tenors в формате "0.5,1,5"
+func tenorsParam(req *http.Request) ([]float64, error) {
+	value := req.URL.Query().Get("tenors")
+	if value == "" {
+		return nil, nil
+	}
+
+	var tenors []float64
+	for _, s := range strings.Split(value, ",") {
+		t, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s tenors: %q", msgIncorrectParam, value)
+		}
+		tenors = append(tenors, t)
+	}
+	return tenors, nil
+}
@@ -4,25 +4,45 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"simple-invest/internal/curves"
+	"simple-invest/internal/portfolio"
+	"simple-invest/internal/scheduler"
 	"simple-invest/internal/securities"
+	"simple-invest/internal/tax"
+	"strconv"
+	"time"
 )
 
 const (
 	msgSerializationFailed   = "Serialization data failed"
 	msgMoexGettingDataFailed = "Cannot get data from MOEX"
 	msgEmptyID               = "Share ID cannot be empty"
+	msgIncorrectDate         = "Incorrect date, expected format YYYY-MM-DD"
+	msgGettingDataFailed     = "Cannot get stored data"
+	msgIncorrectRequest      = "Incorrect request body"
+	msgEmptyBatch            = "List of ISIN or board must be specified"
+	msgIncorrectParam        = "Incorrect parameter"
+	msgEmptyTargetYield      = "Target yield must be specified"
+	msgCalculationFailed     = "Calculation failed"
 )
 
+const defaultPaymentsPeriod = 30 // Период выплат по умолчанию, дней
+
 var errEmtyID = errors.New(msgEmptyID)
 
 type Handler struct {
-	service *securities.SecuritiesService
+	service    *securities.SecuritiesService
+	portfolios *portfolio.PortfolioService
+	curves     *curves.CurveService
+	jobs       *scheduler.Scheduler
 }
 
-func New(service *securities.SecuritiesService) *Handler {
-	return &Handler{service: service}
+func New(service *securities.SecuritiesService, portfolios *portfolio.PortfolioService, curves *curves.CurveService,
+	jobs *scheduler.Scheduler) *Handler {
+	return &Handler{service: service, portfolios: portfolios, curves: curves, jobs: jobs}
 }
 
 func (h *Handler) DefaultHandle(w http.ResponseWriter, req *http.Request) {
@@ -32,9 +52,10 @@ func (h *Handler) DefaultHandle(w http.ResponseWriter, req *http.Request) {
 func (h *Handler) Shares(w http.ResponseWriter, req *http.Request) {
 	update := req.URL.Query().Get("update")
 	if update == "yes" {
-		if err := h.service.DownloadShares(); err != nil {
+		if err := h.service.DownloadShares(req.Context()); err != nil {
 			log.Print(err.Error())
 			writeError(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 	}
 
@@ -65,8 +86,7 @@ func (h *Handler) Dividends(w http.ResponseWriter, req *http.Request) {
 
 	divs, err := h.service.Dividends(context.Background(), isin)
 	if err != nil {
-		log.Print(err)
-		writeError(w, msgMoexGettingDataFailed, http.StatusServiceUnavailable)
+		writeServiceError(w, err)
 		return
 	}
 
@@ -81,12 +101,49 @@ func (h *Handler) Dividends(w http.ResponseWriter, req *http.Request) {
 
 }
 
+func (h *Handler) DividendYield(w http.ResponseWriter, req *http.Request) {
+	ticker := req.URL.Query().Get("ticker")
+	if ticker == "" {
+		log.Print(msgEmptyID)
+		writeError(w, msgEmptyID, http.StatusBadRequest)
+		return
+	}
+	date, err := dateParam(req, "date", time.Now().Truncate(time.Hour*24))
+	if err != nil {
+		log.Print(err)
+		writeError(w, msgIncorrectDate, http.StatusBadRequest)
+		return
+	}
+	tm, err := taxModelParam(req)
+	if err != nil {
+		log.Print(err)
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dy, err := h.service.DividendYield(req.Context(), ticker, date, tm)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(dy)
+	if err != nil {
+		log.Print(err)
+		writeError(w, msgSerializationFailed, http.StatusInternalServerError)
+		return
+	}
+
+	writeResponse(w, resp)
+}
+
 func (h *Handler) Bonds(w http.ResponseWriter, req *http.Request) {
 	update := req.URL.Query().Get("update")
 	if update == "yes" {
-		if err := h.service.DownloadBonds(); err != nil {
+		if err := h.service.DownloadBonds(req.Context()); err != nil {
 			log.Print(err.Error())
 			writeError(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 	}
 
@@ -115,10 +172,10 @@ func (h *Handler) Coupons(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	coupons, err := h.service.Coupons(isin)
+	update := req.URL.Query().Get("update") == "yes"
+	coupons, err := h.service.Coupons(isin, update)
 	if err != nil {
-		log.Print(err)
-		writeError(w, msgMoexGettingDataFailed, http.StatusServiceUnavailable)
+		writeServiceError(w, err)
 		return
 	}
 
@@ -140,10 +197,10 @@ func (h *Handler) Amortizations(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	amortizations, err := h.service.Amortizations(isin)
+	update := req.URL.Query().Get("update") == "yes"
+	amortizations, err := h.service.Amortizations(isin, update)
 	if err != nil {
-		log.Print(err)
-		writeError(w, msgMoexGettingDataFailed, http.StatusServiceUnavailable)
+		writeServiceError(w, err)
 		return
 	}
 
@@ -165,10 +222,19 @@ func (h *Handler) BondIndicators(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	bondIndicators, err := h.service.BondIndicators(isin)
+	opts, err := indicatorOptionsParam(req)
+	if err == nil {
+		err = whatIfParam(req, &opts)
+	}
 	if err != nil {
 		log.Print(err)
-		writeError(w, msgMoexGettingDataFailed, http.StatusServiceUnavailable)
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	bondIndicators, err := h.service.BondIndicators(req.Context(), isin, opts)
+	if err != nil {
+		writeServiceError(w, err)
 		return
 	}
 
@@ -183,6 +249,365 @@ func (h *Handler) BondIndicators(w http.ResponseWriter, req *http.Request) {
 
 }
 
+func (h *Handler) BondPrice(w http.ResponseWriter, req *http.Request) {
+	isin := req.URL.Query().Get("isin")
+	if isin == "" {
+		log.Print(msgEmptyID)
+		writeError(w, msgEmptyID, http.StatusBadRequest)
+		return
+	}
+	target, err := floatParam(req, "target_yield")
+	if err != nil {
+		log.Print(err)
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if target == nil {
+		log.Print(msgEmptyTargetYield)
+		writeError(w, msgEmptyTargetYield, http.StatusBadRequest)
+		return
+	}
+	opts, err := indicatorOptionsParam(req)
+	if err != nil {
+		log.Print(err)
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if opts.SettleDate, err = dateParam(req, "settle_date", time.Time{}); err != nil {
+		log.Print(err)
+		writeError(w, msgIncorrectDate, http.StatusBadRequest)
+		return
+	}
+
+	price, err := h.service.PriceForYield(req.Context(), isin, *target, req.URL.Query().Get("yield_type"), opts)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(price)
+	if err != nil {
+		log.Print(err)
+		writeError(w, msgSerializationFailed, http.StatusInternalServerError)
+		return
+	}
+
+	writeResponse(w, resp)
+}
+
+// batchRequest - тело запроса пакетного расчёта показателей облигаций
+type batchRequest struct {
+	Isins []string `json:"isins"` // Коды облигаций
+	Board string   `json:"board"` // Режим торгов, все облигации которого необходимо рассчитать
+}
+
+func (h *Handler) BatchBondIndicators(w http.ResponseWriter, req *http.Request) {
+	var batch batchRequest
+	if err := json.NewDecoder(req.Body).Decode(&batch); err != nil {
+		log.Print(err)
+		writeError(w, msgIncorrectRequest, http.StatusBadRequest)
+		return
+	}
+	if len(batch.Isins) == 0 && batch.Board == "" {
+		log.Print(msgEmptyBatch)
+		writeError(w, msgEmptyBatch, http.StatusBadRequest)
+		return
+	}
+
+	opts, err := indicatorOptionsParam(req)
+	if err != nil {
+		log.Print(err)
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := h.service.BatchBondIndicators(req.Context(), batch.Isins, batch.Board, opts)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+
+	resp, err := json.Marshal(results)
+	if err != nil {
+		log.Print(err)
+		writeError(w, msgSerializationFailed, http.StatusInternalServerError)
+		return
+	}
+
+	writeResponse(w, resp)
+}
+
+func (h *Handler) ScreenBonds(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	f := securities.ScreenFilter{
+		YieldField: q.Get("yield"),
+		FaceUnit:   q.Get("face_unit"),
+		CouponType: q.Get("coupon_type"),
+		BondType:   q.Get("bond_type"),
+		SortBy:     q.Get("sort"),
+		Desc:       q.Get("order") == "desc",
+	}
+
+	var err error
+	if f.MinYield, err = floatParam(req, "min_yield"); err == nil {
+		f.MaxYield, err = floatParam(req, "max_yield")
+	}
+	if err == nil {
+		f.MinDays, err = intParam(req, "min_days")
+	}
+	if err == nil {
+		f.MaxDays, err = intParam(req, "max_days")
+	}
+	if err == nil {
+		f.Amortization, err = boolParam(req, "amortization")
+	}
+	if err == nil {
+		f.MinValToday, err = floatParam(req, "min_valtoday")
+	}
+	if err == nil {
+		f.MinNumTrades, err = intParam(req, "min_numtrades")
+	}
+	var limit *int64
+	if err == nil {
+		limit, err = intParam(req, "limit")
+	}
+	if err != nil {
+		log.Print(err)
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if limit != nil {
+		f.Limit = int(*limit)
+	}
+
+	bonds, err := h.service.ScreenBonds(f)
+	if err != nil {
+		log.Print(err)
+		if errors.Is(err, securities.ErrIncorrectFilter) {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeError(w, msgGettingDataFailed, http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := json.Marshal(bonds)
+	if err != nil {
+		log.Print(err)
+		writeError(w, msgSerializationFailed, http.StatusInternalServerError)
+		return
+	}
+
+	writeResponse(w, resp)
+}
+
+func (h *Handler) Payments(w http.ResponseWriter, req *http.Request) {
+	today := time.Now().Truncate(time.Hour * 24)
+	from, err := dateParam(req, "from", today)
+	if err != nil {
+		log.Print(err)
+		writeError(w, msgIncorrectDate, http.StatusBadRequest)
+		return
+	}
+	to, err := dateParam(req, "to", from.AddDate(0, 0, defaultPaymentsPeriod))
+	if err != nil {
+		log.Print(err)
+		writeError(w, msgIncorrectDate, http.StatusBadRequest)
+		return
+	}
+
+	payments, err := h.service.Payments(from, to)
+	if err != nil {
+		log.Print(err)
+		writeError(w, msgGettingDataFailed, http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := json.Marshal(payments)
+	if err != nil {
+		log.Print(err)
+		writeError(w, msgSerializationFailed, http.StatusInternalServerError)
+		return
+	}
+
+	writeResponse(w, resp)
+}
+
+func (h *Handler) Jobs(w http.ResponseWriter, req *http.Request) {
+	statuses := []scheduler.Status{}
+	if h.jobs != nil {
+		statuses = h.jobs.Status()
+	}
+
+	resp, err := json.Marshal(statuses)
+	if err != nil {
+		log.Print(err)
+		writeError(w, msgSerializationFailed, http.StatusInternalServerError)
+		return
+	}
+
+	writeResponse(w, resp)
+}
+
+// dateParam возвращает дату из параметра запроса name или def, если параметр не задан
+func dateParam(req *http.Request, name string, def time.Time) (time.Time, error) {
+	value := req.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// floatParam возвращает число из параметра запроса name или nil, если параметр не задан
+func floatParam(req *http.Request, name string) (*float64, error) {
+	value := req.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %q", msgIncorrectParam, name, value)
+	}
+	return &f, nil
+}
+
+// intParam возвращает целое число из параметра запроса name или nil, если параметр не задан
+func intParam(req *http.Request, name string) (*int64, error) {
+	value := req.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %q", msgIncorrectParam, name, value)
+	}
+	return &n, nil
+}
+
+// boolParam возвращает признак из параметра запроса name (yes/no) или nil, если параметр не задан
+func boolParam(req *http.Request, name string) (*bool, error) {
+	var b bool
+	switch value := req.URL.Query().Get(name); value {
+	case "":
+		return nil, nil
+	case "yes":
+		b = true
+	case "no":
+		b = false
+	default:
+		return nil, fmt.Errorf("%s %s: %q", msgIncorrectParam, name, value)
+	}
+	return &b, nil
+}
+
+// taxModelParam возвращает налоговую модель из параметров запроса tax_year, resident (yes/no) и income.
+// По умолчанию используется модель для резидента РФ в текущем году.
+func taxModelParam(req *http.Request) (tax.Model, error) {
+	tm := tax.Default()
+
+	year, err := intParam(req, "tax_year")
+	if err != nil {
+		return tm, err
+	}
+	if year != nil {
+		tm.Year = int(*year)
+	}
+	resident, err := boolParam(req, "resident")
+	if err != nil {
+		return tm, err
+	}
+	if resident != nil {
+		tm.Resident = *resident
+	}
+	income, err := floatParam(req, "income")
+	if err != nil {
+		return tm, err
+	}
+	if income != nil {
+		tm.Income = *income
+	}
+
+	return tm, nil
+}
+
+// indicatorOptionsParam возвращает параметры расчёта показателей облигации: налоговую модель (см. taxModelParam),
+// базовую ставку флоатера base (key_rate/ruonia), спред флоатера spread, ожидаемую инфляцию inflation
+// и ожидаемое изменение курса валюты номинала fx_change.
+func indicatorOptionsParam(req *http.Request) (securities.IndicatorOptions, error) {
+	var opts securities.IndicatorOptions
+	var err error
+
+	opts.Tax, err = taxModelParam(req)
+	if err != nil {
+		return opts, err
+	}
+	switch opts.Base = req.URL.Query().Get("base"); opts.Base {
+	case "", securities.BaseKeyRate, securities.BaseRUONIA:
+	default:
+		return opts, fmt.Errorf("%s base: %q", msgIncorrectParam, opts.Base)
+	}
+	if opts.Spread, err = floatParam(req, "spread"); err != nil {
+		return opts, err
+	}
+	if opts.Inflation, err = floatParam(req, "inflation"); err != nil {
+		return opts, err
+	}
+	fxChange, err := floatParam(req, "fx_change")
+	if err != nil {
+		return opts, err
+	}
+	if fxChange != nil {
+		opts.FXChange = *fxChange
+	}
+
+	return opts, nil
+}
+
+// whatIfParam дополняет opts заданными в запросе ценой, датой расчётов и количеством облигаций
+func whatIfParam(req *http.Request, opts *securities.IndicatorOptions) error {
+	price, err := floatParam(req, "price")
+	if err != nil {
+		return err
+	}
+	if price != nil {
+		if *price <= 0 {
+			return fmt.Errorf("%s price: %v", msgIncorrectParam, *price)
+		}
+		opts.Price = *price
+	}
+
+	if opts.SettleDate, err = dateParam(req, "settle_date", time.Time{}); err != nil {
+		return errors.New(msgIncorrectDate)
+	}
+
+	if value := req.URL.Query().Get("quantity"); value != "" {
+		opts.Quantity, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || opts.Quantity <= 0 {
+			return fmt.Errorf("%s quantity: %q", msgIncorrectParam, value)
+		}
+	}
+
+	return nil
+}
+
+// writeServiceError отправляет ошибку расчёта по бумаге: параметры запроса, при которых расчёт невозможен, -
+// 400, ошибки БД и расчёта - 500, остальные ошибки относятся к получению данных Мосбиржи
+func writeServiceError(w http.ResponseWriter, err error) {
+	log.Print(err)
+	switch {
+	case errors.Is(err, securities.ErrIncorrectOptions), errors.Is(err, securities.ErrNoCashFlows),
+		errors.Is(err, securities.ErrIncorrectYield), errors.Is(err, securities.ErrIncorrectBatch),
+		errors.Is(err, securities.ErrNotBond), errors.Is(err, curves.ErrIncorrectShock):
+		writeError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, securities.ErrStorage):
+		writeError(w, msgGettingDataFailed, http.StatusInternalServerError)
+	case errors.Is(err, securities.ErrCalculation):
+		writeError(w, msgCalculationFailed, http.StatusInternalServerError)
+	default:
+		writeError(w, msgMoexGettingDataFailed, http.StatusServiceUnavailable)
+	}
+}
+
 func writeResponse(w http.ResponseWriter, resp []byte) {
 	w.Header().Set("content-type", "application/json")
 	w.WriteHeader(http.StatusOK)
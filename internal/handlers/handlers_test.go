@@ -0,0 +1,504 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"simple-invest/internal/curves"
+	"simple-invest/internal/models"
+	"simple-invest/internal/portfolio"
+	"simple-invest/internal/repository"
+	"simple-invest/internal/securities"
+	"simple-invest/internal/securities/moextest"
+
+	"github.com/WLM1ke/gomoex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubRepo хранит данные в памяти. Нереализованные методы Repository вызывают панику.
+type stubRepo struct {
+	repository.Repository
+	mu            sync.Mutex
+	shares        []gomoex.Security
+	bonds         []gomoex.Security
+	coupons       map[string][]securities.Coupon
+	amortizations map[string][]securities.Amortization
+	offers        map[string][]securities.Offer
+	zcyc          []models.ZCYC
+	portfolios    map[int64]models.Portfolio
+	err           error // Ошибка чтения списков бумаг и графиков оферт
+}
+
+func newStubRepo() *stubRepo {
+	return &stubRepo{
+		coupons:       make(map[string][]securities.Coupon),
+		amortizations: make(map[string][]securities.Amortization),
+		offers:        make(map[string][]securities.Offer),
+		portfolios:    make(map[int64]models.Portfolio),
+	}
+}
+
+func (r *stubRepo) GetShares() ([]gomoex.Security, error) { return r.shares, r.err }
+func (r *stubRepo) GetBonds() ([]gomoex.Security, error)  { return r.bonds, r.err }
+
+func (r *stubRepo) UpdateShares(secs []gomoex.Security) (int, error) {
+	r.shares = secs
+	return len(secs), nil
+}
+
+func (r *stubRepo) UpdateBonds(secs []gomoex.Security) (int, error) {
+	r.bonds = secs
+	return len(secs), nil
+}
+
+func (r *stubRepo) GetSecurity(isin string) (gomoex.Security, string, error) {
+	return gomoex.Security{}, "", repository.ErrNotFound
+}
+
+func (r *stubRepo) GetCoupons(isin string) ([]securities.Coupon, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.coupons[isin], nil
+}
+
+func (r *stubRepo) GetAmortizations(isin string) ([]securities.Amortization, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.amortizations[isin], nil
+}
+
+func (r *stubRepo) GetOffers(isin string) ([]securities.Offer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.offers[isin], r.err
+}
+
+func (r *stubRepo) GetZCYC(date time.Time) (models.ZCYC, error) {
+	for i := len(r.zcyc) - 1; i >= 0; i-- {
+		if r.zcyc[i].Date <= date.Format(time.DateOnly) {
+			return r.zcyc[i], nil
+		}
+	}
+	return models.ZCYC{}, repository.ErrNotFound
+}
+
+func (r *stubRepo) UpdateZCYC(c models.ZCYC) error {
+	r.zcyc = append(r.zcyc, c)
+	return nil
+}
+
+func (r *stubRepo) BondizationUpdatedAt(isin string) (time.Time, error) { return time.Time{}, nil }
+
+func (r *stubRepo) UpdateBondization(isin string, coupons []securities.Coupon, amortizations []securities.Amortization, offers []securities.Offer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.coupons[isin] = coupons
+	r.amortizations[isin] = amortizations
+	r.offers[isin] = offers
+	return nil
+}
+
+func (r *stubRepo) GetPortfolio(id int64) (models.Portfolio, error) {
+	p, ok := r.portfolios[id]
+	if !ok {
+		return p, repository.ErrNotFound
+	}
+	return p, nil
+}
+
+func (r *stubRepo) CreatePortfolio(p models.Portfolio) (models.Portfolio, error) {
+	p.ID = int64(len(r.portfolios) + 1)
+	r.portfolios[p.ID] = p
+	return p, nil
+}
+
+func (r *stubRepo) UpdatePortfolio(p models.Portfolio) error {
+	if _, ok := r.portfolios[p.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	r.portfolios[p.ID] = p
+	return nil
+}
+
+func newTestHandler(t *testing.T) *Handler {
+	t.Helper()
+
+	srv := moextest.NewServer(t)
+	md, err := securities.NewMoexClient(srv.URL, http.DefaultClient)
+	require.NoError(t, err)
+
+	repo := newStubRepo()
+	service := securities.New(repo, md, securities.Options{})
+	return New(service, portfolio.New(repo, service), curves.New(repo, md), nil)
+}
+
+func serve(h http.HandlerFunc, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, target, http.NoBody))
+	return rec
+}
+
+func TestHandler_BondIndicators(t *testing.T) {
+	h := newTestHandler(t)
+
+	rec := serve(h.BondIndicators, "/bondindicators?isin="+moextest.ISINOfz)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, moextest.ISINOfz, got["isin"])
+	assert.Equal(t, 0.1402, got["effective_yield"])
+
+	rec = serve(h.BondIndicators, "/bondindicators")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(h.BondIndicators, "/bondindicators?resident=maybe&isin="+moextest.ISINOfz)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(h.BondIndicators, "/bondindicators?resident=no&tax_year=2024&isin="+moextest.ISINAmortizing)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, 0.3, got["tax_rate"])
+
+	rec = serve(h.BondIndicators, "/bondindicators?base=ruonia&spread=1&isin="+moextest.ISINFloater)
+	require.Equal(t, http.StatusOK, rec.Code)
+	got = nil
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, "floater", got["bond_type"])
+	assert.Equal(t, map[string]any{"base": "ruonia", "fixing": 15.95, "spread": 1.0, "spread_source": "request", "projected_coupons": 3.0}, got["assumptions"])
+
+	rec = serve(h.BondIndicators, "/bondindicators?fx_change=3&isin="+moextest.ISINCurrency)
+	require.Equal(t, http.StatusOK, rec.Code)
+	got = nil
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Contains(t, got, "fx")
+	assert.Equal(t, 3.0, got["fx"].(map[string]any)["rate_change"])
+
+	rec = serve(h.BondIndicators, "/bondindicators?base=libor&isin="+moextest.ISINFloater)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(h.BondIndicators, "/bondindicators?isin="+moextest.ISINNoTrades)
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	rec = serve(h.BondIndicators, "/bondindicators?isin="+moextest.ISINIlliquid)
+	require.Equal(t, http.StatusOK, rec.Code)
+	got = nil
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, "marketprice", got["price_source"])
+	assert.Equal(t, "2024-06-04 18:50:03", got["price_time"])
+	require.Contains(t, got, "quotes")
+	assert.Equal(t, 98.2, got["quotes"].(map[string]any)["offer"])
+
+	rec = serve(h.BondIndicators, "/bondindicators?price=98.5&settle_date=2024-03-08&quantity=2&isin="+moextest.ISINNoTrades)
+	require.Equal(t, http.StatusOK, rec.Code)
+	got = nil
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, 98.5, got["percent_price"])
+	assert.Equal(t, "2024-03-08", got["settledate"])
+	require.Contains(t, got, "position")
+	assert.Equal(t, 1970.0, got["position"].(map[string]any)["amount"])
+
+	for _, query := range []string{"price=0", "price=abc", "settle_date=08.03.2024", "quantity=-1", "quantity=1.5"} {
+		rec = serve(h.BondIndicators, "/bondindicators?"+query+"&isin="+moextest.ISINNoTrades)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+
+	// Дата расчётов после погашения и цена, при которой доходность не рассчитывается, - ошибки запроса
+	for _, query := range []string{"settle_date=2045-01-01", "price=0.01"} {
+		rec = serve(h.BondIndicators, "/bondindicators?"+query+"&isin="+moextest.ISINOfz)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+
+	// Ошибка хранилища не выдаётся за недоступность Мосбиржи
+	md, err := securities.NewMoexClient(moextest.NewServer(t).URL, http.DefaultClient)
+	require.NoError(t, err)
+	repo := newStubRepo()
+	repo.err = errors.New("connection refused")
+	h.service = securities.New(repo, md, securities.Options{})
+	rec = serve(h.BondIndicators, "/bondindicators?isin="+moextest.ISINOfz)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestHandler_Coupons(t *testing.T) {
+	h := newTestHandler(t)
+
+	rec := serve(h.Coupons, "/coupons?isin="+moextest.ISINAmortizing)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got []securities.Coupon
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Len(t, got, 21)
+}
+
+func TestHandler_Shares(t *testing.T) {
+	h := newTestHandler(t)
+
+	rec := serve(h.Shares, "/shares?update=yes")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got []gomoex.Security
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Len(t, got, 2)
+}
+
+func TestHandler_DividendYield(t *testing.T) {
+	h := newTestHandler(t)
+
+	rec := serve(h.DividendYield, "/dividendyield?ticker="+moextest.TickerShare+"&date=2024-06-04")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var got securities.DividendYield
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, moextest.TickerShare, got.Ticker)
+
+	// Ошибка хранилища не выдаётся за недоступность Мосбиржи
+	h.service = securities.New(&stubRepo{err: errors.New("connection refused")}, nil, securities.Options{})
+	rec = serve(h.DividendYield, "/dividendyield?ticker="+moextest.TickerShare)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
+
+func TestHandler_BatchBondIndicators(t *testing.T) {
+	h := newTestHandler(t)
+
+	body := `{"isins": ["` + moextest.ISINOfz + `", "` + moextest.ISINNoTrades + `", "` + moextest.ISINOfz + `"]}`
+	rec := httptest.NewRecorder()
+	h.BatchBondIndicators(rec, httptest.NewRequest(http.MethodPost, "/bondindicators/batch", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got []securities.BondIndicatorsResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Len(t, got, 2)
+	assert.Equal(t, moextest.ISINOfz, got[0].Isin)
+	assert.NotNil(t, got[0].Indicators)
+	assert.Empty(t, got[0].Error)
+	assert.Equal(t, moextest.ISINNoTrades, got[1].Isin)
+	assert.Nil(t, got[1].Indicators)
+	assert.NotEmpty(t, got[1].Error)
+
+	post := func(body string) int {
+		rec := httptest.NewRecorder()
+		h.BatchBondIndicators(rec, httptest.NewRequest(http.MethodPost, "/bondindicators/batch", strings.NewReader(body)))
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusBadRequest, post(`{}`))
+	assert.Equal(t, http.StatusBadRequest, post(`{"board": "TQCB"}`))
+	isins := make([]string, 3001)
+	for i := range isins {
+		isins[i] = fmt.Sprintf(`"RU%010d"`, i)
+	}
+	assert.Equal(t, http.StatusBadRequest, post(`{"isins": [`+strings.Join(isins, ",")+`]}`))
+
+	// Ошибки хранилища не считаются ошибками запроса
+	h.service = securities.New(&stubRepo{err: errors.New("connection refused")}, nil, securities.Options{})
+	assert.Equal(t, http.StatusInternalServerError, post(`{"board": "TQCB"}`))
+}
+
+func TestHandler_BondPrice(t *testing.T) {
+	h := newTestHandler(t)
+
+	rec := serve(h.BondPrice, "/bondindicators/price?target_yield=0.18&yield_type=net_effective&isin="+moextest.ISINOfz)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var got securities.BondPrice
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, securities.YieldNetEffective, got.YieldType)
+	assert.Positive(t, got.PercentPrice)
+	assert.InDelta(t, got.Price, got.CleanPrice+got.AccruedInt, 0.01)
+
+	rec = serve(h.BondPrice, "/bondindicators/price?isin="+moextest.ISINOfz)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(h.BondPrice, "/bondindicators/price?target_yield=0.18&yield_type=current&isin="+moextest.ISINOfz)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandler_OFZCurve(t *testing.T) {
+	h := newTestHandler(t)
+
+	rec := serve(h.OFZCurve, "/curves/ofz?date=2024-06-04&tenors=1,5")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got curves.Curve
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, moextest.ZCYC, got.ZCYC)
+	assert.Equal(t, []curves.TenorYield{{Tenor: 1, Yield: 0.1568}, {Tenor: 5, Yield: 0.1468}}, got.Yields)
+
+	for _, query := range []string{"date=04.06.2024", "tenors=1,x", "tenors=50"} {
+		rec = serve(h.OFZCurve, "/curves/ofz?"+query)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestHandler_Scenarios(t *testing.T) {
+	h := newTestHandler(t)
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.Scenarios(rec, httptest.NewRequest(http.MethodPost, "/scenarios", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := post(`{"isin": "` + moextest.ISINOfz + `", "quantity": 10, "scenarios": [
+		{"name": "+200", "type": "parallel", "shift_bp": 200},
+		{"type": "custom", "points": [{"tenor": 1, "shift_bp": 100}, {"tenor": 10, "shift_bp": -50}]}]}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got portfolio.ScenarioAnalysis
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Len(t, got.Positions, 1)
+	position := got.Positions[0]
+	assert.Equal(t, int64(10), position.Quantity)
+	require.Len(t, position.Scenarios, 2)
+	assert.Equal(t, "+200", position.Scenarios[0].Name)
+	assert.Negative(t, position.Scenarios[0].PnL)
+	assert.Equal(t, curves.ShockCustom, position.Scenarios[1].Name)
+	require.Len(t, got.Totals, 1)
+	assert.Equal(t, position.Scenarios, got.Totals[0].Scenarios)
+
+	tests := []struct {
+		body string
+		code int
+	}{
+		{`{"scenarios": [{"type": "parallel", "shift_bp": 200}]}`, http.StatusBadRequest},
+		{`{"isin": "` + moextest.ISINOfz + `", "portfolio_id": 1, "scenarios": [{"type": "parallel"}]}`, http.StatusBadRequest},
+		{`{"isin": "` + moextest.ISINOfz + `", "scenarios": [{"type": "twist"}]}`, http.StatusBadRequest},
+		{`{"isin": "` + moextest.ISINOfz + `"}`, http.StatusBadRequest},
+		{`{"isin": "` + moextest.ISINNoTrades + `", "scenarios": [{"type": "parallel", "shift_bp": 200}]}`, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.code, post(tt.body).Code, tt.body)
+	}
+}
+
+func TestHandler_CashFlows(t *testing.T) {
+	h := newTestHandler(t)
+
+	rec := serve(h.CashFlows, "/cashflows?isin="+moextest.ISINOffer+"&quantity=2&cost=950&tax_year=2024")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var got []models.CashFlow
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Len(t, got, 10)
+	assert.Equal(t, models.CashFlowCoupon, got[0].Type)
+	assert.Equal(t, models.CashFlowMaturity, got[9].Type)
+	assert.Equal(t, models.CashFlow{
+		Date: "2025-12-02", Isin: moextest.ISINOffer, Type: models.CashFlowOffer, Quantity: 2,
+		Amount: 2000, Tax: 13, Net: 1987, Currency: "RUB",
+	}, got[3])
+
+	for _, target := range []string{
+		"/cashflows",
+		"/cashflows?isin=" + moextest.ISINOffer + "&quantity=0",
+		"/cashflows?isin=" + moextest.ISINOffer + "&quantity=x",
+		"/cashflows?isin=" + moextest.ISINOffer + "&cost=x",
+		"/cashflows?isin=" + moextest.ISINOffer + "&cost=0",
+		"/cashflows?isin=" + moextest.ISINOffer + "&cost=-1",
+	} {
+		rec = serve(h.CashFlows, target)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+	}
+
+	for _, target := range []string{"/portfolios/x/cashflows", "/portfolios/1/cashflows?from=2024-13-01"} {
+		req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+		req.SetPathValue("id", strings.Split(target, "/")[2])
+		rec = httptest.NewRecorder()
+		h.PortfolioCashFlows(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+	}
+}
+
+func TestHandler_Calendar(t *testing.T) {
+	h := newTestHandler(t)
+
+	rec := serve(h.Calendar, "/calendar.ics?isin="+moextest.ISINOffer+"&quantity=2&from=2026-11-01")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/calendar; charset=utf-8", rec.Header().Get("content-type"))
+
+	body := rec.Body.String()
+	assert.True(t, strings.HasPrefix(body, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(body, "END:VEVENT\r\nEND:VCALENDAR\r\n"))
+	assert.NotContains(t, strings.ReplaceAll(body, "\r\n", ""), "\n")
+	for _, line := range strings.Split(body, "\r\n") {
+		assert.LessOrEqual(t, len(line), icsLineLength, line)
+	}
+	unfolded := strings.ReplaceAll(body, "\r\n ", "")
+	assert.Contains(t, unfolded, "UID:"+moextest.ISINOffer+"-offer-20261201@simple-invest\r\n")
+	assert.Contains(t, unfolded, "DTSTART;VALUE=DATE:20261201\r\nDTEND;VALUE=DATE:20261202\r\n")
+	assert.Contains(t, unfolded, "SUMMARY:Оферта "+moextest.ISINOffer+"\r\n")
+	assert.Contains(t, unfolded, `DESCRIPTION:ISIN: `+moextest.ISINOffer+`\nКоличество: 2\nНа одну бумагу: 1010 RUB\nВсего: 2020 RUB`)
+	// События до даты from не включаются
+	assert.NotContains(t, unfolded, "DTSTART;VALUE=DATE:2025")
+	assert.Equal(t, 10, strings.Count(body, "BEGIN:VEVENT"))
+
+	for _, target := range []string{
+		"/calendar.ics",
+		"/calendar.ics?isin=" + moextest.ISINOffer + "&portfolio=1",
+		"/calendar.ics?portfolio=x",
+		"/calendar.ics?isin=" + moextest.ISINOffer + "&from=2024-13-01",
+		"/calendar.ics?isin=" + moextest.ISINOffer + "&quantity=0",
+	} {
+		rec = serve(h.Calendar, target)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+	}
+}
+
+func Test_escapeText(t *testing.T) {
+	assert.Equal(t, `Купон\, НКД\; C:\\tmp\nстрока`, escapeText("Купон, НКД; C:\\tmp\nстрока"))
+}
+
+func Test_foldLine(t *testing.T) {
+	short := "SUMMARY:Купон"
+	assert.Equal(t, short, foldLine(short))
+
+	long := "DESCRIPTION:" + strings.Repeat("Выплата ", 20)
+	folded := foldLine(long)
+	lines := strings.Split(folded, "\r\n")
+	require.Greater(t, len(lines), 1)
+	for i, line := range lines {
+		assert.LessOrEqual(t, len(line), icsLineLength)
+		assert.True(t, utf8.ValidString(line), line)
+		if i > 0 {
+			assert.True(t, strings.HasPrefix(line, " "))
+		}
+	}
+	assert.Equal(t, long, strings.ReplaceAll(folded, "\r\n ", ""))
+}
+
+func TestHandler_UpdatePortfolio(t *testing.T) {
+	h := newTestHandler(t)
+
+	p, err := h.portfolios.CreatePortfolio(models.Portfolio{Name: "Основной", NonResident: true})
+	require.NoError(t, err)
+
+	put := func(id, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/portfolios/"+id, strings.NewReader(body))
+		req.SetPathValue("id", id)
+		h.UpdatePortfolio(rec, req)
+		return rec
+	}
+	id := fmt.Sprint(p.ID)
+
+	// Поля, не переданные в запросе, не изменяются
+	rec := put(id, `{"name": "ИИС"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var got models.Portfolio
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, "ИИС", got.Name)
+	assert.True(t, got.NonResident)
+
+	rec = put(id, `{"non_resident": false}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, "ИИС", got.Name)
+	assert.False(t, got.NonResident)
+
+	assert.Equal(t, http.StatusBadRequest, put(id, `{"name": " "}`).Code)
+	assert.Equal(t, http.StatusNotFound, put("100", `{"name": "ИИС"}`).Code)
+}
@@ -0,0 +1,367 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"simple-invest/internal/models"
+	"simple-invest/internal/portfolio"
+	"strconv"
+)
+
+const (
+	msgNotFound    = "Not found"
+	msgIncorrectID = "Incorrect identifier"
+	msgStorage     = "Storage operation failed"
+)
+
+// portfolioUpdate содержит изменяемые поля портфеля. Не переданные поля сохраняют текущие значения.
+type portfolioUpdate struct {
+	Name        *string `json:"name"`
+	NonResident *bool   `json:"non_resident"`
+}
+
+func (h *Handler) Portfolios(w http.ResponseWriter, req *http.Request) {
+	portfolios, err := h.portfolios.Portfolios()
+	if err != nil {
+		writePortfolioError(w, err)
+		return
+	}
+	writeJSON(w, portfolios)
+}
+
+func (h *Handler) Portfolio(w http.ResponseWriter, req *http.Request) {
+	id, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	p, err := h.portfolios.Portfolio(id)
+	if err != nil {
+		writePortfolioError(w, err)
+		return
+	}
+	writeJSON(w, p)
+}
+
+func (h *Handler) CreatePortfolio(w http.ResponseWriter, req *http.Request) {
+	var p models.Portfolio
+	if !decodeBody(w, req, &p) {
+		return
+	}
+
+	p, err := h.portfolios.CreatePortfolio(p)
+	if err != nil {
+		writePortfolioError(w, err)
+		return
+	}
+	writeJSON(w, p)
+}
+
+func (h *Handler) UpdatePortfolio(w http.ResponseWriter, req *http.Request) {
+	id, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+	var u portfolioUpdate
+	if !decodeBody(w, req, &u) {
+		return
+	}
+	p, err := h.portfolios.Portfolio(id)
+	if err != nil {
+		writePortfolioError(w, err)
+		return
+	}
+	if u.Name != nil {
+		p.Name = *u.Name
+	}
+	if u.NonResident != nil {
+		p.NonResident = *u.NonResident
+	}
+
+	p, err = h.portfolios.UpdatePortfolio(p)
+	if err != nil {
+		writePortfolioError(w, err)
+		return
+	}
+	writeJSON(w, p)
+}
+
+func (h *Handler) DeletePortfolio(w http.ResponseWriter, req *http.Request) {
+	id, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.portfolios.DeletePortfolio(id); err != nil {
+		writePortfolioError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) Accounts(w http.ResponseWriter, req *http.Request) {
+	id, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	accounts, err := h.portfolios.Accounts(id)
+	if err != nil {
+		writePortfolioError(w, err)
+		return
+	}
+	writeJSON(w, accounts)
+}
+
+func (h *Handler) CreateAccount(w http.ResponseWriter, req *http.Request) {
+	id, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+	var a models.Account
+	if !decodeBody(w, req, &a) {
+		return
+	}
+	a.PortfolioID = id
+
+	a, err := h.portfolios.CreateAccount(a)
+	if err != nil {
+		writePortfolioError(w, err)
+		return
+	}
+	writeJSON(w, a)
+}
+
+func (h *Handler) UpdateAccount(w http.ResponseWriter, req *http.Request) {
+	id, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+	accountID, ok := pathID(w, req, "account")
+	if !ok {
+		return
+	}
+	var a models.Account
+	if !decodeBody(w, req, &a) {
+		return
+	}
+	a.ID = accountID
+	a.PortfolioID = id
+
+	a, err := h.portfolios.UpdateAccount(a)
+	if err != nil {
+		writePortfolioError(w, err)
+		return
+	}
+	writeJSON(w, a)
+}
+
+func (h *Handler) DeleteAccount(w http.ResponseWriter, req *http.Request) {
+	id, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+	accountID, ok := pathID(w, req, "account")
+	if !ok {
+		return
+	}
+
+	if err := h.portfolios.DeleteAccount(id, accountID); err != nil {
+		writePortfolioError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) Trades(w http.ResponseWriter, req *http.Request) {
+	id, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+	accountID, err := intParam(req, "account")
+	if err != nil {
+		log.Print(err)
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if accountID == nil {
+		accountID = new(int64)
+	}
+
+	trades, err := h.portfolios.Trades(id, *accountID)
+	if err != nil {
+		writePortfolioError(w, err)
+		return
+	}
+	writeJSON(w, trades)
+}
+
+func (h *Handler) CreateTrade(w http.ResponseWriter, req *http.Request) {
+	id, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+	var t models.Trade
+	if !decodeBody(w, req, &t) {
+		return
+	}
+
+	t, err := h.portfolios.CreateTrade(id, t)
+	if err != nil {
+		writePortfolioError(w, err)
+		return
+	}
+	writeJSON(w, t)
+}
+
+func (h *Handler) UpdateTrade(w http.ResponseWriter, req *http.Request) {
+	id, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+	tradeID, ok := pathID(w, req, "trade")
+	if !ok {
+		return
+	}
+	var t models.Trade
+	if !decodeBody(w, req, &t) {
+		return
+	}
+	t.ID = tradeID
+
+	t, err := h.portfolios.UpdateTrade(id, t)
+	if err != nil {
+		writePortfolioError(w, err)
+		return
+	}
+	writeJSON(w, t)
+}
+
+func (h *Handler) DeleteTrade(w http.ResponseWriter, req *http.Request) {
+	id, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+	tradeID, ok := pathID(w, req, "trade")
+	if !ok {
+		return
+	}
+
+	if err := h.portfolios.DeleteTrade(id, tradeID); err != nil {
+		writePortfolioError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) Positions(w http.ResponseWriter, req *http.Request) {
+	id, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	positions, err := h.portfolios.Positions(id)
+	if err != nil {
+		writePortfolioError(w, err)
+		return
+	}
+	writeJSON(w, positions)
+}
+
+func (h *Handler) Valuation(w http.ResponseWriter, req *http.Request) {
+	id, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	valuation, err := h.portfolios.Valuation(req.Context(), id)
+	if err != nil {
+		writePortfolioError(w, err)
+		return
+	}
+	writeJSON(w, valuation)
+}
+
+func (h *Handler) Taxes(w http.ResponseWriter, req *http.Request) {
+	id, ok := pathID(w, req, "id")
+	if !ok {
+		return
+	}
+
+	var opts portfolio.TaxOptions
+	year, err := intParam(req, "year")
+	if err == nil {
+		opts.Resident, err = boolParam(req, "resident")
+	}
+	var income *float64
+	if err == nil {
+		income, err = floatParam(req, "income")
+	}
+	if err != nil {
+		log.Print(err)
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if year != nil {
+		opts.Year = int(*year)
+	}
+	if income != nil {
+		opts.Income = *income
+	}
+
+	report, err := h.portfolios.Taxes(id, opts)
+	if err != nil {
+		writePortfolioError(w, err)
+		return
+	}
+	writeJSON(w, report)
+}
+
+// pathID возвращает идентификатор из сегмента пути name. При ошибке отправляет ответ и возвращает false.
+func pathID(w http.ResponseWriter, req *http.Request, name string) (int64, bool) {
+	value := req.PathValue(name)
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		log.Printf("%s %s: %q", msgIncorrectID, name, value)
+		writeError(w, fmt.Sprintf("%s %s: %q", msgIncorrectID, name, value), http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// decodeBody разбирает тело запроса в v. При ошибке отправляет ответ и возвращает false.
+func decodeBody(w http.ResponseWriter, req *http.Request, v any) bool {
+	if err := json.NewDecoder(req.Body).Decode(v); err != nil {
+		log.Print(err)
+		writeError(w, msgIncorrectRequest, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// writePortfolioError отправляет ответ с ошибкой сервиса портфелей
+func writePortfolioError(w http.ResponseWriter, err error) {
+	log.Print(err)
+	switch {
+	case errors.Is(err, portfolio.ErrNotFound):
+		writeError(w, msgNotFound, http.StatusNotFound)
+	case errors.Is(err, portfolio.ErrIncorrectData):
+		writeError(w, err.Error(), http.StatusBadRequest)
+	default:
+		writeError(w, msgStorage, http.StatusInternalServerError)
+	}
+}
+
+// writeJSON отправляет v в формате JSON
+func writeJSON(w http.ResponseWriter, v any) {
+	resp, err := json.Marshal(v)
+	if err != nil {
+		log.Print(err)
+		writeError(w, msgSerializationFailed, http.StatusInternalServerError)
+		return
+	}
+	writeResponse(w, resp)
+}
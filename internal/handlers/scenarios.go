@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"simple-invest/internal/curves"
+	"simple-invest/internal/portfolio"
+)
+
+const msgScenarioTarget = "Either isin or portfolio_id must be specified"
+
+// scenarioRequest - тело запроса сценарного анализа облигации или портфеля
+type scenarioRequest struct {
+	Isin        string         `json:"isin"`         // Облигация
+	Quantity    int64          `json:"quantity"`     // Количество облигаций, по умолчанию 1
+	PortfolioID int64          `json:"portfolio_id"` // Портфель
+	Scenarios   []curves.Shock `json:"scenarios"`    // Сценарии сдвига кривой доходности
+}
+
+func (h *Handler) Scenarios(w http.ResponseWriter, req *http.Request) {
+	var r scenarioRequest
+	if !decodeBody(w, req, &r) {
+		return
+	}
+	if (r.Isin == "") == (r.PortfolioID == 0) {
+		log.Print(msgScenarioTarget)
+		writeError(w, msgScenarioTarget, http.StatusBadRequest)
+		return
+	}
+
+	if r.PortfolioID != 0 {
+		analysis, err := h.portfolios.Scenarios(req.Context(), r.PortfolioID, r.Scenarios)
+		if err != nil {
+			writePortfolioError(w, err)
+			return
+		}
+		writeJSON(w, analysis)
+		return
+	}
+
+	if r.Quantity == 0 {
+		r.Quantity = 1
+	}
+	analysis, err := h.portfolios.BondScenarios(req.Context(), r.Isin, r.Quantity, r.Scenarios)
+	if errors.Is(err, portfolio.ErrIncorrectData) {
+		log.Print(err)
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, analysis)
+}
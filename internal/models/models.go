@@ -0,0 +1,175 @@
+// Пакет models содержит структуры данных, общие для хранилища и сервисов.
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Параметры конкретного купона
+type Coupon struct {
+	Isin             string  `json:"isin"`             // ISIN код
+	Coupondate       string  `json:"coupondate"`       // Дата выплаты купона
+	Recorddate       string  `json:"recorddate"`       // Дата фиксации списка держателей
+	Initialfacevalue float64 `json:"initialfacevalue"` // Первоначальная номинальная стоимость
+	Facevalue        float64 `json:"facevalue"`        // Номинальная стоимость
+	Faceunit         string  `json:"faceunit"`         // Валюта
+	Value            float64 `json:"value"`            // Сумма купона, в валюте номинала
+	Valueprc         float64 `json:"valueprc"`         // Ставка купона, %
+	ValueRub         float64 `json:"value_rub"`        // Сумма купона, руб
+}
+
+// Параметры конкретной амортизационной выплаты
+type Amortization struct {
+	Isin             string    `json:"isin"`             // ISIN код
+	Amortdate        string    `json:"amortdate"`        // Дата амортизации
+	Facevalue        float64   `json:"facevalue"`        // Номинальная стоимость
+	Initialfacevalue float64   `json:"initialfacevalue"` // Первоначальная номинальная стоимость
+	Faceunit         string    `json:"faceunit"`         // Валюта
+	Value            float64   `json:"value"`            // Сумма амортизации, в валюте номинала
+	ValueRub         float64   `json:"value_rub"`        // Сумма амортизации, руб
+	Date             time.Time // Дата амортизации в формате time.Time
+}
+
+// Параметры оферты по облигации
+type Offer struct {
+	Isin           string  `json:"isin"`           // ISIN код
+	Offerdate      string  `json:"offerdate"`      // Дата исполнения оферты
+	OfferdateStart string  `json:"offerdatestart"` // Дата начала приёма заявок
+	OfferdateEnd   string  `json:"offerdateend"`   // Дата окончания приёма заявок
+	Facevalue      float64 `json:"facevalue"`      // Номинальная стоимость
+	Faceunit       string  `json:"faceunit"`       // Валюта
+	Price          float64 `json:"price"`          // Цена выкупа, % от номинала
+	Value          float64 `json:"value"`          // Сумма выкупа, в валюте номинала
+	Agent          string  `json:"agent"`          // Агент по оферте
+	Offertype      string  `json:"offertype"`      // Вид оферты по данным Мосбиржи
+}
+
+// Сохранённый результат расчёта показателей облигации
+type IndicatorSnapshot struct {
+	Isin       string          // ISIN код
+	ComputedAt time.Time       // Время расчёта
+	Data       json.RawMessage // Показатели в формате JSON
+}
+
+// Инвестиционный портфель
+type Portfolio struct {
+	ID          int64     `json:"id"`           // Идентификатор
+	Name        string    `json:"name"`         // Наименование
+	NonResident bool      `json:"non_resident"` // Владелец не является налоговым резидентом РФ
+	CreatedAt   time.Time `json:"created_at"`   // Дата создания
+}
+
+// Брокерский счёт портфеля
+type Account struct {
+	ID          int64  `json:"id"`           // Идентификатор
+	PortfolioID int64  `json:"portfolio_id"` // Портфель
+	Name        string `json:"name"`         // Наименование
+	Broker      string `json:"broker"`       // Брокер
+}
+
+// Типы операций по счёту
+const (
+	TradeBuy          = "buy"          // Покупка
+	TradeSell         = "sell"         // Продажа
+	TradeCoupon       = "coupon"       // Получение купона
+	TradeDividend     = "dividend"     // Получение дивидендов
+	TradeAmortization = "amortization" // Амортизация (частичное погашение номинала), погашение
+	TradeFee          = "fee"          // Комиссия, не связанная со сделкой
+)
+
+// Операция по счёту: сделка или денежное событие по ценной бумаге
+type Trade struct {
+	ID         int64   `json:"id"`         // Идентификатор
+	AccountID  int64   `json:"account_id"` // Счёт
+	Isin       string  `json:"isin"`       // ISIN код, для комиссии может быть пустым
+	Type       string  `json:"type"`       // Тип операции
+	Date       string  `json:"date"`       // Дата операции
+	Quantity   int64   `json:"quantity"`   // Количество бумаг
+	Price      float64 `json:"price"`      // Цена одной бумаги без НКД, в валюте операции
+	AccruedInt float64 `json:"accruedint"` // НКД по сделке, всего
+	Amount     float64 `json:"amount"`     // Сумма операции без комиссии
+	Fee        float64 `json:"fee"`        // Комиссия
+	Currency   string  `json:"currency"`   // Валюта операции
+	Comment    string  `json:"comment"`    // Комментарий
+}
+
+// Текущая цена ценной бумаги
+type Quote struct {
+	Isin       string  `json:"isin"`        // ISIN код
+	CleanPrice float64 `json:"clean_price"` // Цена одной бумаги без НКД
+	AccruedInt float64 `json:"accruedint"`  // НКД на одну бумагу
+	Price      float64 `json:"price"`       // Цена одной бумаги с учётом НКД
+	Currency   string  `json:"currency"`    // Валюта цены
+}
+
+// Виды выплат в графике денежных потоков
+const (
+	CashFlowCoupon       = "coupon"       // Купон
+	CashFlowAmortization = "amortization" // Амортизация (частичное погашение номинала)
+	CashFlowOffer        = "offer"        // Выкуп по оферте
+	CashFlowMaturity     = "maturity"     // Погашение
+)
+
+// Партия облигаций для расчёта налога при погашении
+type Lot struct {
+	Quantity int64     // Количество облигаций
+	Cost     float64   // Стоимость приобретения одной облигации, 0 - текущая цена с НКД
+	Date     time.Time // Дата приобретения, нулевая - дата расчётов
+}
+
+// Будущая выплата по облигации
+type CashFlow struct {
+	Date      string  `json:"date"`                // Дата выплаты
+	Isin      string  `json:"isin"`                // ISIN код
+	Type      string  `json:"type"`                // Вид выплаты
+	Quantity  int64   `json:"quantity"`            // Количество бумаг
+	Amount    float64 `json:"amount"`              // Сумма выплаты до налогов
+	Tax       float64 `json:"tax"`                 // Удерживаемый НДФЛ
+	Net       float64 `json:"net"`                 // Сумма выплаты за вычетом налога
+	Currency  string  `json:"currency"`            // Валюта выплаты
+	Estimated bool    `json:"estimated,omitempty"` // Сумма рассчитана по прогнозу
+}
+
+// Виды событий календаря выплат
+const (
+	EventCoupon       = "coupon"          // Выплата купона
+	EventCouponRecord = "coupon_record"   // Дата фиксации держателей для выплаты купона
+	EventAmortization = "amortization"    // Амортизация
+	EventMaturity     = "maturity"        // Погашение
+	EventOffer        = "offer"           // Оферта
+	EventDividend     = "dividend_record" // Дата закрытия реестра под дивиденды
+)
+
+// Событие календаря выплат по ценной бумаге
+type CalendarEvent struct {
+	Date     string  `json:"date"`     // Дата события
+	Isin     string  `json:"isin"`     // ISIN код
+	Ticker   string  `json:"ticker"`   // Тикер
+	Type     string  `json:"type"`     // Вид события
+	Quantity int64   `json:"quantity"` // Количество бумаг
+	Value    float64 `json:"value"`    // Сумма на одну бумагу, 0 - не объявлена
+	Amount   float64 `json:"amount"`   // Сумма по всем бумагам
+	Currency string  `json:"currency"` // Валюта
+}
+
+// Цены облигации при сценариях сдвига кривой доходности
+type ScenarioQuote struct {
+	Isin     string    `json:"isin"`     // ISIN код
+	Price    float64   `json:"price"`    // Текущая цена одной бумаги с учётом НКД
+	Yield    float64   `json:"yield"`    // Эффективная доходность при текущей цене
+	Currency string    `json:"currency"` // Валюта цены
+	Prices   []float64 `json:"prices"`   // Цены одной бумаги с учётом НКД по сценариям в порядке их задания
+}
+
+// Параметры кривой бескупонной доходности ОФЗ (G-кривой), публикуемые Мосбиржей.
+// Параметры модели Нельсона-Сигеля-Свенссона заданы в базисных пунктах, T1 - в годах.
+type ZCYC struct {
+	Date string     `json:"date"` // Дата расчёта
+	Time string     `json:"time"` // Время расчёта
+	B1   float64    `json:"b1"`   // Долгосрочный уровень
+	B2   float64    `json:"b2"`   // Краткосрочная составляющая
+	B3   float64    `json:"b3"`   // Среднесрочная составляющая
+	T1   float64    `json:"t1"`   // Параметр формы
+	G    [9]float64 `json:"g"`    // Коэффициенты поправочных слагаемых
+}
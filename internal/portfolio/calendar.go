@@ -0,0 +1,39 @@
+package portfolio
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"simple-invest/internal/models"
+)
+
+// CalendarEvents возвращает события по открытым позициям портфеля с датой не ранее from, упорядоченные
+// по дате и ISIN. Бумаги, события по которым не удалось получить, пропускаются с записью в журнал.
+func (s *PortfolioService) CalendarEvents(ctx context.Context, portfolioID int64, from time.Time) ([]models.CalendarEvent, error) {
+	positions, err := s.Positions(portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []models.CalendarEvent{}
+	for _, p := range positions {
+		if p.Quantity <= 0 {
+			continue
+		}
+		e, err := s.prices.CalendarEvents(ctx, p.Isin, p.Quantity, from)
+		if err != nil {
+			log.Printf("calendar %s: %v", p.Isin, err)
+			continue
+		}
+		events = append(events, e...)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Date != events[j].Date {
+			return events[i].Date < events[j].Date
+		}
+		return events[i].Isin < events[j].Isin
+	})
+	return events, nil
+}
@@ -0,0 +1,56 @@
+package portfolio
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"simple-invest/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPortfolioService_CalendarEvents(t *testing.T) {
+	repo := newMemRepo()
+	prices := stubPricer{
+		"B": {Isin: "B", CleanPrice: 950, AccruedInt: 12.5, Price: 962.5, Currency: "RUB"},
+		"S": {Isin: "S", CleanPrice: 300, Price: 300, Currency: "RUB"},
+	}
+	s := New(repo, prices)
+	ctx := context.Background()
+
+	p, err := s.CreatePortfolio(models.Portfolio{Name: "Основной"})
+	require.NoError(t, err)
+	acc, err := s.CreateAccount(models.Account{PortfolioID: p.ID, Name: "Брокерский"})
+	require.NoError(t, err)
+	trades := []models.Trade{
+		{AccountID: acc.ID, Isin: "B", Type: models.TradeBuy, Date: "2024-01-10", Quantity: 10, Price: 1000},
+		{AccountID: acc.ID, Isin: "S", Type: models.TradeBuy, Date: "2024-02-01", Quantity: 20, Price: 250},
+		{AccountID: acc.ID, Isin: "X", Type: models.TradeBuy, Date: "2024-02-01", Quantity: 1, Price: 100},
+		{AccountID: acc.ID, Isin: "C", Type: models.TradeBuy, Date: "2024-02-01", Quantity: 1, Price: 100},
+		{AccountID: acc.ID, Isin: "C", Type: models.TradeSell, Date: "2024-03-01", Quantity: 1, Price: 110},
+	}
+	for _, tr := range trades {
+		_, err := s.CreateTrade(p.ID, tr)
+		require.NoError(t, err)
+	}
+
+	// Бумага без данных пропускается, закрытая позиция не учитывается
+	events, err := s.CalendarEvents(ctx, p.ID, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, models.EventDividend, events[0].Type)
+	assert.Equal(t, 200.0, events[0].Amount)
+	assert.Equal(t, models.EventCoupon, events[1].Type)
+	assert.Equal(t, 500.0, events[1].Amount)
+	assert.Equal(t, models.EventMaturity, events[2].Type)
+
+	events, err = s.CalendarEvents(ctx, p.ID, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "2025-07-15", events[0].Date)
+
+	_, err = s.CalendarEvents(ctx, p.ID+100, time.Time{})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,125 @@
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"simple-invest/internal/models"
+	"simple-invest/internal/tax"
+
+	"github.com/WLM1ke/gomoex"
+)
+
+// CashFlowTotal содержит итоги графика выплат в одной валюте. Выкуп по оферте не входит в итоги: они
+// соответствуют владению облигациями до погашения.
+type CashFlowTotal struct {
+	Currency string  `json:"currency"` // Валюта
+	Amount   float64 `json:"amount"`   // Сумма выплат
+	Tax      float64 `json:"tax"`      // НДФЛ
+	Net      float64 `json:"net"`      // Сумма выплат за вычетом НДФЛ
+}
+
+// CashFlowError содержит ошибку построения графика выплат по бумаге
+type CashFlowError struct {
+	Isin  string `json:"isin"`  // ISIN код
+	Error string `json:"error"` // Ошибка
+}
+
+// CashFlows содержит график будущих выплат по облигациям портфеля за период
+type CashFlows struct {
+	PortfolioID int64             `json:"portfolio_id"`     // Портфель
+	From        string            `json:"from"`             // Начало периода
+	To          string            `json:"to"`               // Окончание периода
+	Flows       []models.CashFlow `json:"flows"`            // Выплаты по датам
+	Totals      []CashFlowTotal   `json:"totals"`           // Итоги по валютам
+	Errors      []CashFlowError   `json:"errors,omitempty"` // Бумаги, по которым график не построен
+}
+
+// CashFlows возвращает график будущих выплат по открытым позициям в облигациях портфеля с датами в периоде
+// [from, to]. Выплаты после оферт соответствуют владению облигациями до погашения, выкуп по оферте
+// возвращается отдельной выплатой. Налог при погашении рассчитывается по налоговой модели портфеля для каждой
+// открытой партии, сопоставленной методом FIFO, от её стоимости и с ЛДВ по дате её покупки.
+// Ошибки по отдельным бумагам возвращаются в графике и не прерывают построение остальных.
+func (s *PortfolioService) CashFlows(ctx context.Context, portfolioID int64, from, to time.Time) (CashFlows, error) {
+	cf := CashFlows{PortfolioID: portfolioID, From: from.Format(time.DateOnly), To: to.Format(time.DateOnly),
+		Flows: []models.CashFlow{}, Totals: []CashFlowTotal{}}
+	if to.Before(from) {
+		return cf, fmt.Errorf("%w: period end %s before start %s", ErrIncorrectData, cf.To, cf.From)
+	}
+
+	p, err := s.repo.GetPortfolio(portfolioID)
+	if err != nil {
+		return cf, err
+	}
+	positions, err := s.Positions(portfolioID)
+	if err != nil {
+		return cf, err
+	}
+	trades, err := s.repo.GetTrades(portfolioID)
+	if err != nil {
+		return cf, err
+	}
+	_, openLots, err := matchLots(trades)
+	if err != nil {
+		return cf, err
+	}
+	lots := make(map[string][]models.Lot)
+	for _, lot := range openLots {
+		date, _ := time.Parse(time.DateOnly, lot.Date)
+		lots[lot.Isin] = append(lots[lot.Isin], models.Lot{
+			Quantity: lot.Quantity,
+			Cost:     lot.Cost / float64(lot.Quantity),
+			Date:     date,
+		})
+	}
+	tm := tax.Model{Year: time.Now().Year(), Resident: !p.NonResident}
+
+	totals := make(map[string]*CashFlowTotal)
+	for _, pos := range positions {
+		if pos.Quantity <= 0 || pos.Market == gomoex.MarketShares {
+			continue
+		}
+
+		flows, err := s.prices.CashFlows(ctx, pos.Isin, lots[pos.Isin], tm)
+		if err != nil {
+			cf.Errors = append(cf.Errors, CashFlowError{Isin: pos.Isin, Error: err.Error()})
+			continue
+		}
+		for _, f := range flows {
+			if f.Date < cf.From || f.Date > cf.To {
+				continue
+			}
+			cf.Flows = append(cf.Flows, f)
+			if f.Type == models.CashFlowOffer {
+				continue
+			}
+
+			t, ok := totals[f.Currency]
+			if !ok {
+				t = &CashFlowTotal{Currency: f.Currency}
+				totals[f.Currency] = t
+			}
+			t.Amount += f.Amount
+			t.Tax += f.Tax
+			t.Net += f.Net
+		}
+	}
+	sort.SliceStable(cf.Flows, func(i, j int) bool {
+		if cf.Flows[i].Date != cf.Flows[j].Date {
+			return cf.Flows[i].Date < cf.Flows[j].Date
+		}
+		return cf.Flows[i].Isin < cf.Flows[j].Isin
+	})
+
+	for _, t := range totals {
+		t.Amount = round(t.Amount)
+		t.Tax = round(t.Tax)
+		t.Net = round(t.Net)
+		cf.Totals = append(cf.Totals, *t)
+	}
+	sort.Slice(cf.Totals, func(i, j int) bool { return cf.Totals[i].Currency < cf.Totals[j].Currency })
+
+	return cf, nil
+}
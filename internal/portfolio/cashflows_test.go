@@ -0,0 +1,77 @@
+package portfolio
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"simple-invest/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPortfolioService_CashFlows(t *testing.T) {
+	repo := newMemRepo()
+	prices := stubPricer{
+		"B1": {Isin: "B1", CleanPrice: 950, AccruedInt: 12.5, Price: 962.5, Currency: "RUB"},
+		"B2": {Isin: "B2", CleanPrice: 1010, AccruedInt: 3, Price: 1013, Currency: "RUB"},
+		"S":  {Isin: "S", CleanPrice: 300, Price: 300, Currency: "RUB"},
+	}
+	s := New(repo, prices)
+	ctx := context.Background()
+
+	p, err := s.CreatePortfolio(models.Portfolio{Name: "Основной"})
+	require.NoError(t, err)
+	acc, err := s.CreateAccount(models.Account{PortfolioID: p.ID, Name: "Брокерский"})
+	require.NoError(t, err)
+	trades := []models.Trade{
+		{AccountID: acc.ID, Isin: "B2", Type: models.TradeBuy, Date: "2024-01-10", Quantity: 2, Price: 1020},
+		{AccountID: acc.ID, Isin: "B1", Type: models.TradeBuy, Date: "2021-03-01", Quantity: 4, Price: 800},
+		{AccountID: acc.ID, Isin: "B1", Type: models.TradeBuy, Date: "2024-01-10", Quantity: 10, Price: 900, AccruedInt: 40},
+		{AccountID: acc.ID, Isin: "B1", Type: models.TradeSell, Date: "2024-02-01", Quantity: 4, Price: 950},
+		{AccountID: acc.ID, Isin: "B1", Type: models.TradeBuy, Date: "2022-01-10", Quantity: 2, Price: 700},
+		{AccountID: acc.ID, Isin: "S", Type: models.TradeBuy, Date: "2024-02-01", Quantity: 20, Price: 250},
+	}
+	for _, tr := range trades {
+		_, err := s.CreateTrade(p.ID, tr)
+		require.NoError(t, err)
+	}
+
+	from := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	cf, err := s.CashFlows(ctx, p.ID, from, from.AddDate(1, 0, 0))
+	require.NoError(t, err)
+	assert.Equal(t, "2024-06-01", cf.From)
+	assert.Equal(t, "2025-06-01", cf.To)
+
+	// Погашение после окончания периода не включается, выплаты упорядочены по дате и ISIN.
+	// Выкуп по оферте возвращается отдельной выплатой и не входит в итоги.
+	require.Len(t, cf.Flows, 3)
+	assert.Equal(t, "B1", cf.Flows[0].Isin)
+	assert.Equal(t, 600.0, cf.Flows[0].Amount)
+	assert.Equal(t, "B2", cf.Flows[1].Isin)
+	assert.Equal(t, models.CashFlowOffer, cf.Flows[2].Type)
+	assert.Equal(t, 2000.0, cf.Flows[2].Amount)
+	assert.Equal(t, []CashFlowTotal{{Currency: "RUB", Amount: 700, Tax: 91, Net: 609}}, cf.Totals)
+
+	// Период после оферты включает выплаты до погашения.
+	// Налог при погашении рассчитывается по открытым партиям FIFO: партия 2022 года освобождена по ЛДВ.
+	cf, err = s.CashFlows(ctx, p.ID, from, from.AddDate(2, 0, 0))
+	require.NoError(t, err)
+	require.Len(t, cf.Flows, 5)
+	assert.Equal(t, models.CashFlowMaturity, cf.Flows[3].Type)
+	assert.Equal(t, 130.0, cf.Flows[3].Tax)
+	assert.Equal(t, models.CashFlowMaturity, cf.Flows[4].Type)
+	assert.Equal(t, "B2", cf.Flows[4].Isin)
+	assert.Zero(t, cf.Flows[4].Tax)
+	assert.Equal(t, []CashFlowTotal{{Currency: "RUB", Amount: 14700, Tax: 221, Net: 14479}}, cf.Totals)
+
+	// Ошибка по бумаге возвращается в графике
+	require.Len(t, cf.Errors, 1)
+	assert.Equal(t, "S", cf.Errors[0].Isin)
+
+	_, err = s.CashFlows(ctx, p.ID, from, from.AddDate(0, 0, -1))
+	assert.ErrorIs(t, err, ErrIncorrectData)
+	_, err = s.CashFlows(ctx, p.ID+100, from, from)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
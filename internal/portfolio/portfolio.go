@@ -0,0 +1,230 @@
+package portfolio
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"simple-invest/internal/models"
+	"simple-invest/internal/repository"
+)
+
+const (
+	defaultCurrency = "RUB" // Валюта операции по умолчанию
+	moneyPrecision  = 2     // Точность денежных сумм
+)
+
+var (
+	// ErrNotFound возвращается, если портфель, счёт или операция не найдены
+	ErrNotFound = repository.ErrNotFound
+	// ErrIncorrectData возвращается при некорректных данных портфеля, счёта или операции
+	ErrIncorrectData = errors.New("incorrect portfolio data")
+)
+
+type PortfolioService struct {
+	repo   repository.Repository
+	prices Pricer
+}
+
+func New(repo repository.Repository, prices Pricer) *PortfolioService {
+	return &PortfolioService{repo: repo, prices: prices}
+}
+
+// Portfolios возвращает все портфели
+func (s *PortfolioService) Portfolios() ([]models.Portfolio, error) {
+	return s.repo.GetPortfolios()
+}
+
+// Portfolio возвращает портфель по идентификатору
+func (s *PortfolioService) Portfolio(id int64) (models.Portfolio, error) {
+	return s.repo.GetPortfolio(id)
+}
+
+// CreatePortfolio создаёт портфель
+func (s *PortfolioService) CreatePortfolio(p models.Portfolio) (models.Portfolio, error) {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return p, fmt.Errorf("%w: empty portfolio name", ErrIncorrectData)
+	}
+	return s.repo.CreatePortfolio(p)
+}
+
+// UpdatePortfolio изменяет портфель
+func (s *PortfolioService) UpdatePortfolio(p models.Portfolio) (models.Portfolio, error) {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return p, fmt.Errorf("%w: empty portfolio name", ErrIncorrectData)
+	}
+	if err := s.repo.UpdatePortfolio(p); err != nil {
+		return p, err
+	}
+	return s.repo.GetPortfolio(p.ID)
+}
+
+// DeletePortfolio удаляет портфель вместе со счетами и операциями
+func (s *PortfolioService) DeletePortfolio(id int64) error {
+	return s.repo.DeletePortfolio(id)
+}
+
+// Accounts возвращает счета портфеля
+func (s *PortfolioService) Accounts(portfolioID int64) ([]models.Account, error) {
+	if _, err := s.repo.GetPortfolio(portfolioID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetAccounts(portfolioID)
+}
+
+// CreateAccount создаёт счёт портфеля
+func (s *PortfolioService) CreateAccount(a models.Account) (models.Account, error) {
+	a.Name = strings.TrimSpace(a.Name)
+	if a.Name == "" {
+		return a, fmt.Errorf("%w: empty account name", ErrIncorrectData)
+	}
+	if _, err := s.repo.GetPortfolio(a.PortfolioID); err != nil {
+		return a, err
+	}
+	return s.repo.CreateAccount(a)
+}
+
+// UpdateAccount изменяет счёт портфеля
+func (s *PortfolioService) UpdateAccount(a models.Account) (models.Account, error) {
+	a.Name = strings.TrimSpace(a.Name)
+	if a.Name == "" {
+		return a, fmt.Errorf("%w: empty account name", ErrIncorrectData)
+	}
+	return a, s.repo.UpdateAccount(a)
+}
+
+// DeleteAccount удаляет счёт портфеля вместе с операциями.
+// Удаление не выполняется, если без операций счёта продажи превысят количество бумаг в портфеле.
+func (s *PortfolioService) DeleteAccount(portfolioID, id int64) error {
+	return s.repo.DeleteAccount(portfolioID, id, checkLedger)
+}
+
+// Trades возвращает операции портфеля. Если accountID не равен нулю, возвращаются операции только этого счёта.
+func (s *PortfolioService) Trades(portfolioID, accountID int64) ([]models.Trade, error) {
+	if _, err := s.repo.GetPortfolio(portfolioID); err != nil {
+		return nil, err
+	}
+	trades, err := s.repo.GetTrades(portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	if accountID == 0 {
+		return trades, nil
+	}
+
+	filtered := []models.Trade{}
+	for _, t := range trades {
+		if t.AccountID == accountID {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered, nil
+}
+
+// CreateTrade добавляет операцию на счёт портфеля.
+// Проверка операций портфеля и запись выполняются в одной транзакции.
+func (s *PortfolioService) CreateTrade(portfolioID int64, t models.Trade) (models.Trade, error) {
+	t, err := s.checkTrade(portfolioID, t)
+	if err != nil {
+		return t, err
+	}
+	return s.repo.CreateTrade(portfolioID, t, checkLedger)
+}
+
+// UpdateTrade изменяет операцию по счёту портфеля
+func (s *PortfolioService) UpdateTrade(portfolioID int64, t models.Trade) (models.Trade, error) {
+	t, err := s.checkTrade(portfolioID, t)
+	if err != nil {
+		return t, err
+	}
+	return t, s.repo.UpdateTrade(portfolioID, t, checkLedger)
+}
+
+// DeleteTrade удаляет операцию по счёту портфеля
+func (s *PortfolioService) DeleteTrade(portfolioID, id int64) error {
+	return s.repo.DeleteTrade(portfolioID, id, checkLedger)
+}
+
+// checkTrade проверяет операцию, принадлежность счёта портфелю и заполняет значения по умолчанию
+func (s *PortfolioService) checkTrade(portfolioID int64, t models.Trade) (models.Trade, error) {
+	t, err := normalizeTrade(t)
+	if err != nil {
+		return t, err
+	}
+
+	accounts, err := s.Accounts(portfolioID)
+	if err != nil {
+		return t, err
+	}
+	for _, a := range accounts {
+		if a.ID == t.AccountID {
+			return t, nil
+		}
+	}
+	return t, fmt.Errorf("%w: account %d not found in portfolio %d", ErrIncorrectData, t.AccountID, portfolioID)
+}
+
+// normalizeTrade проверяет поля операции. Для покупки и продажи без суммы она рассчитывается по цене и НКД.
+func normalizeTrade(t models.Trade) (models.Trade, error) {
+	t.Isin = strings.ToUpper(strings.TrimSpace(t.Isin))
+	t.Currency = strings.ToUpper(strings.TrimSpace(t.Currency))
+	if t.Currency == "" {
+		t.Currency = defaultCurrency
+	}
+
+	if _, err := time.Parse(time.DateOnly, t.Date); err != nil {
+		return t, fmt.Errorf("%w: incorrect date %q, expected format YYYY-MM-DD", ErrIncorrectData, t.Date)
+	}
+	if t.Quantity < 0 || t.Price < 0 || t.AccruedInt < 0 || t.Amount < 0 || t.Fee < 0 {
+		return t, fmt.Errorf("%w: quantity, price and amounts cannot be negative", ErrIncorrectData)
+	}
+
+	switch t.Type {
+	case models.TradeBuy, models.TradeSell:
+		if t.Isin == "" || t.Quantity == 0 {
+			return t, fmt.Errorf("%w: %s requires isin and quantity", ErrIncorrectData, t.Type)
+		}
+		if t.Amount == 0 {
+			t.Amount = round(t.Price*float64(t.Quantity) + t.AccruedInt)
+		}
+	case models.TradeCoupon, models.TradeDividend, models.TradeAmortization:
+		if t.Isin == "" || t.Amount == 0 {
+			return t, fmt.Errorf("%w: %s requires isin and amount", ErrIncorrectData, t.Type)
+		}
+	case models.TradeFee:
+		if t.Amount == 0 && t.Fee == 0 {
+			return t, fmt.Errorf("%w: fee requires amount", ErrIncorrectData)
+		}
+	default:
+		return t, fmt.Errorf("%w: unknown trade type %q", ErrIncorrectData, t.Type)
+	}
+
+	return t, nil
+}
+
+// checkLedger проверяет, что продажи и амортизации на каждом счёте не превышают количество купленных бумаг,
+// а операции по каждой бумаге совершены в одной валюте
+func checkLedger(trades []models.Trade) error {
+	if _, _, err := matchLots(trades); err != nil {
+		return err
+	}
+	_, err := aggregate(trades)
+	return err
+}
+
+// sortTrades упорядочивает операции по дате, операции одной даты - в порядке добавления
+func sortTrades(trades []models.Trade) {
+	sort.SliceStable(trades, func(i, j int) bool {
+		return trades[i].Date < trades[j].Date
+	})
+}
+
+func round(x float64) float64 {
+	ratio := math.Pow(10, moneyPrecision)
+	return math.Round(x*ratio) / ratio
+}
@@ -0,0 +1,275 @@
+package portfolio
+
+import (
+	"testing"
+
+	"simple-invest/internal/models"
+	"simple-invest/internal/repository"
+
+	"github.com/WLM1ke/gomoex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memRepo хранит портфели в памяти. Нереализованные методы Repository вызывают панику.
+type memRepo struct {
+	repository.Repository
+	portfolios map[int64]models.Portfolio
+	accounts   []models.Account
+	trades     []models.Trade
+	securities map[string]gomoex.Security
+	nextID     int64
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{
+		portfolios: map[int64]models.Portfolio{},
+		securities: map[string]gomoex.Security{},
+	}
+}
+
+func (r *memRepo) id() int64 {
+	r.nextID++
+	return r.nextID
+}
+
+func (r *memRepo) GetSecurity(isin string) (gomoex.Security, string, error) {
+	sec, ok := r.securities[isin]
+	if !ok {
+		return sec, "", repository.ErrNotFound
+	}
+	return sec, gomoex.MarketBonds, nil
+}
+
+func (r *memRepo) GetPortfolio(id int64) (models.Portfolio, error) {
+	p, ok := r.portfolios[id]
+	if !ok {
+		return p, repository.ErrNotFound
+	}
+	return p, nil
+}
+
+func (r *memRepo) CreatePortfolio(p models.Portfolio) (models.Portfolio, error) {
+	p.ID = r.id()
+	r.portfolios[p.ID] = p
+	return p, nil
+}
+
+func (r *memRepo) GetAccounts(portfolioID int64) ([]models.Account, error) {
+	accounts := []models.Account{}
+	for _, a := range r.accounts {
+		if a.PortfolioID == portfolioID {
+			accounts = append(accounts, a)
+		}
+	}
+	return accounts, nil
+}
+
+func (r *memRepo) CreateAccount(a models.Account) (models.Account, error) {
+	a.ID = r.id()
+	r.accounts = append(r.accounts, a)
+	return a, nil
+}
+
+func (r *memRepo) GetTrades(portfolioID int64) ([]models.Trade, error) {
+	trades := append([]models.Trade{}, r.trades...)
+	sortTrades(trades)
+	return trades, nil
+}
+
+func (r *memRepo) CreateTrade(portfolioID int64, t models.Trade, check repository.LedgerCheck) (models.Trade, error) {
+	t.ID = r.id()
+	return t, r.changeLedger(check, append(append([]models.Trade{}, r.trades...), t))
+}
+
+func (r *memRepo) UpdateTrade(portfolioID int64, t models.Trade, check repository.LedgerCheck) error {
+	trades := append([]models.Trade{}, r.trades...)
+	for i := range trades {
+		if trades[i].ID == t.ID {
+			trades[i] = t
+			return r.changeLedger(check, trades)
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (r *memRepo) DeleteTrade(portfolioID, id int64, check repository.LedgerCheck) error {
+	for i, t := range r.trades {
+		if t.ID == id {
+			rest := append(append([]models.Trade{}, r.trades[:i]...), r.trades[i+1:]...)
+			return r.changeLedger(check, rest)
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (r *memRepo) DeleteAccount(portfolioID, id int64, check repository.LedgerCheck) error {
+	for i, a := range r.accounts {
+		if a.ID != id {
+			continue
+		}
+		rest := []models.Trade{}
+		for _, t := range r.trades {
+			if t.AccountID != id {
+				rest = append(rest, t)
+			}
+		}
+		if err := r.changeLedger(check, rest); err != nil {
+			return err
+		}
+		r.accounts = append(r.accounts[:i], r.accounts[i+1:]...)
+		return nil
+	}
+	return repository.ErrNotFound
+}
+
+// changeLedger сохраняет операции trades, если они проходят проверку check
+func (r *memRepo) changeLedger(check repository.LedgerCheck, trades []models.Trade) error {
+	sorted := append([]models.Trade{}, trades...)
+	sortTrades(sorted)
+	if err := check(sorted); err != nil {
+		return err
+	}
+	r.trades = trades
+	return nil
+}
+
+func Test_aggregate(t *testing.T) {
+	trades := []models.Trade{
+		{Isin: "B", Type: models.TradeBuy, Date: "2024-01-10", Quantity: 10, Price: 990, AccruedInt: 50, Amount: 9950, Fee: 5},
+		{Isin: "B", Type: models.TradeCoupon, Date: "2024-03-01", Amount: 300},
+		{Isin: "B", Type: models.TradeSell, Date: "2024-05-01", Quantity: 4, Price: 1010, AccruedInt: 20, Amount: 4060, Fee: 2},
+		{Isin: "B", Type: models.TradeAmortization, Date: "2024-06-01", Amount: 1200},
+		{Isin: "S", Type: models.TradeBuy, Date: "2024-02-01", Quantity: 20, Price: 250, Amount: 5000},
+		{Isin: "S", Type: models.TradeSell, Date: "2024-04-01", Quantity: 20, Price: 300, Amount: 6000},
+		{Isin: "S", Type: models.TradeDividend, Date: "2024-07-20", Amount: 600},
+		{Type: models.TradeFee, Date: "2024-12-31", Amount: 100},
+	}
+
+	positions, err := aggregate(trades)
+	require.NoError(t, err)
+	require.Len(t, positions, 2)
+
+	bond := positions[0]
+	assert.Equal(t, "B", bond.Isin)
+	assert.Equal(t, int64(6), bond.Quantity)
+	// Стоимость 9905 за 10 бумаг, продано 4 на 3962, амортизация вернула 1200
+	assert.Equal(t, 4743.0, bond.Cost)
+	assert.Equal(t, 790.5, bond.AvgPrice)
+	assert.Equal(t, 76.0, bond.Realized)
+	assert.Equal(t, 270.0, bond.Income)
+	assert.Equal(t, 1200.0, bond.Amortized)
+	assert.Equal(t, 7.0, bond.Fees)
+
+	share := positions[1]
+	assert.Equal(t, int64(0), share.Quantity)
+	assert.Equal(t, 0.0, share.Cost)
+	assert.Equal(t, 1000.0, share.Realized)
+	assert.Equal(t, 600.0, share.Income)
+
+	// Продажа, превышающая позицию
+	_, err = aggregate(append(trades, models.Trade{Isin: "S", Type: models.TradeSell, Date: "2024-08-01", Quantity: 1, Amount: 300}))
+	assert.ErrorIs(t, err, ErrIncorrectData)
+
+	// Операции по бумаге в разных валютах
+	_, err = aggregate(append(trades, models.Trade{Isin: "B", Type: models.TradeCoupon, Date: "2024-08-01", Amount: 10, Currency: "USD"}))
+	assert.ErrorIs(t, err, ErrIncorrectData)
+}
+
+func TestPortfolioService_Trades(t *testing.T) {
+	repo := newMemRepo()
+	repo.securities["B"] = gomoex.Security{ISIN: "B", Ticker: "BOND", Board: "TQCB", LotSize: 1}
+	repo.securities["S"] = gomoex.Security{ISIN: "S", Ticker: "SHARE", Board: "TQBR", LotSize: 10}
+	s := New(repo, nil)
+
+	p, err := s.CreatePortfolio(models.Portfolio{Name: " Основной "})
+	require.NoError(t, err)
+	assert.Equal(t, "Основной", p.Name)
+
+	_, err = s.CreatePortfolio(models.Portfolio{})
+	assert.ErrorIs(t, err, ErrIncorrectData)
+
+	acc, err := s.CreateAccount(models.Account{PortfolioID: p.ID, Name: "ИИС"})
+	require.NoError(t, err)
+
+	_, err = s.CreateAccount(models.Account{PortfolioID: p.ID + 100, Name: "ИИС"})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	tests := []struct {
+		name  string
+		trade models.Trade
+		err   error
+	}{
+		{"buy", models.Trade{AccountID: acc.ID, Isin: "s", Type: models.TradeBuy, Date: "2024-02-01", Quantity: 30, Price: 250}, nil},
+		{"unknown type", models.Trade{AccountID: acc.ID, Isin: "S", Type: "gift", Date: "2024-02-01"}, ErrIncorrectData},
+		{"incorrect date", models.Trade{AccountID: acc.ID, Isin: "S", Type: models.TradeBuy, Date: "01.02.2024", Quantity: 1}, ErrIncorrectData},
+		{"foreign account", models.Trade{AccountID: acc.ID + 100, Isin: "S", Type: models.TradeBuy, Date: "2024-02-01", Quantity: 1}, ErrIncorrectData},
+		{"oversell", models.Trade{AccountID: acc.ID, Isin: "S", Type: models.TradeSell, Date: "2024-03-01", Quantity: 40, Price: 260}, ErrIncorrectData},
+		{"other currency", models.Trade{AccountID: acc.ID, Isin: "S", Type: models.TradeBuy, Date: "2024-02-01", Quantity: 1, Price: 3, Currency: "USD"}, ErrIncorrectData},
+		{"sell before buy", models.Trade{AccountID: acc.ID, Isin: "S", Type: models.TradeSell, Date: "2024-01-01", Quantity: 10, Price: 260}, ErrIncorrectData},
+		{"sell", models.Trade{AccountID: acc.ID, Isin: "S", Type: models.TradeSell, Date: "2024-03-01", Quantity: 10, Price: 260}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.CreateTrade(p.ID, tt.trade)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	positions, err := s.Positions(p.ID)
+	require.NoError(t, err)
+	require.Len(t, positions, 1)
+	assert.Equal(t, "SHARE", positions[0].Ticker)
+	assert.Equal(t, "TQBR", positions[0].Board)
+	assert.Equal(t, int64(20), positions[0].Quantity)
+	assert.Equal(t, 2.0, positions[0].Lots)
+	assert.Equal(t, 5000.0, positions[0].Cost)
+	assert.Equal(t, 100.0, positions[0].Realized)
+
+	// Удаление покупки привело бы к продаже отсутствующих бумаг
+	trades, err := s.Trades(p.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, trades, 2)
+	assert.Equal(t, 7500.0, trades[0].Amount)
+
+	buy := trades[0]
+	buy.Quantity, buy.Amount = 5, 0
+	_, err = s.UpdateTrade(p.ID, buy)
+	assert.ErrorIs(t, err, ErrIncorrectData)
+	buy.ID += 100
+	_, err = s.UpdateTrade(p.ID, buy)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.ErrorIs(t, s.DeleteTrade(p.ID, trades[0].ID), ErrIncorrectData)
+	assert.NoError(t, s.DeleteTrade(p.ID, trades[1].ID))
+}
+
+func TestPortfolioService_DeleteAccount(t *testing.T) {
+	repo := newMemRepo()
+	s := New(repo, nil)
+
+	p, err := s.CreatePortfolio(models.Portfolio{Name: "Основной"})
+	require.NoError(t, err)
+	broker, err := s.CreateAccount(models.Account{PortfolioID: p.ID, Name: "Брокерский"})
+	require.NoError(t, err)
+	iis, err := s.CreateAccount(models.Account{PortfolioID: p.ID, Name: "ИИС"})
+	require.NoError(t, err)
+
+	// Операции, сохранённые до проверки продаж по счетам: бумаги куплены на одном счёте, а проданы с другого
+	repo.trades = []models.Trade{
+		{ID: 100, AccountID: broker.ID, Isin: "S", Type: models.TradeBuy, Date: "2024-02-01", Quantity: 10, Amount: 2500},
+		{ID: 101, AccountID: iis.ID, Isin: "S", Type: models.TradeSell, Date: "2024-03-01", Quantity: 10, Amount: 2600},
+	}
+
+	assert.ErrorIs(t, s.DeleteAccount(p.ID, broker.ID), ErrIncorrectData)
+	assert.Len(t, repo.trades, 2)
+
+	require.NoError(t, s.DeleteAccount(p.ID, iis.ID))
+	assert.Len(t, repo.trades, 1)
+	require.NoError(t, s.DeleteAccount(p.ID, broker.ID))
+	assert.Empty(t, repo.trades)
+}
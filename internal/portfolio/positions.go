@@ -0,0 +1,138 @@
+package portfolio
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"simple-invest/internal/models"
+	"simple-invest/internal/repository"
+)
+
+// Position содержит позицию портфеля по ценной бумаге.
+// Стоимость приобретения рассчитывается по средней цене, НКД учитывается в доходе.
+type Position struct {
+	Isin       string  `json:"isin"`       // ISIN код
+	Ticker     string  `json:"ticker"`     // Тикер
+	Board      string  `json:"board"`      // Режим торгов
+	Market     string  `json:"market"`     // Рынок: shares или bonds
+	LotSize    int     `json:"lotsize"`    // Размер лота
+	Currency   string  `json:"currency"`   // Валюта операций
+	Quantity   int64   `json:"quantity"`   // Количество бумаг
+	Lots       float64 `json:"lots"`       // Количество лотов
+	Cost       float64 `json:"cost"`       // Стоимость приобретения бумаг в портфеле с учётом комиссий
+	AvgPrice   float64 `json:"avg_price"`  // Средняя цена приобретения одной бумаги
+	Realized   float64 `json:"realized"`   // Финансовый результат от продаж
+	Income     float64 `json:"income"`     // Купоны и дивиденды за вычетом уплаченного НКД
+	Amortized  float64 `json:"amortized"`  // Полученные амортизационные выплаты
+	Fees       float64 `json:"fees"`       // Комиссии
+	TradeCount int     `json:"tradecount"` // Количество операций
+}
+
+// Positions возвращает позиции портфеля по всем бумагам, включая закрытые.
+// Комиссии без указания ISIN в позиции не включаются.
+func (s *PortfolioService) Positions(portfolioID int64) ([]Position, error) {
+	if _, err := s.repo.GetPortfolio(portfolioID); err != nil {
+		return nil, err
+	}
+	trades, err := s.repo.GetTrades(portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	positions, err := aggregate(trades)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range positions {
+		p := &positions[i]
+		sec, market, err := s.repo.GetSecurity(p.Isin)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.Ticker = sec.Ticker
+		p.Board = sec.Board
+		p.Market = market
+		p.LotSize = sec.LotSize
+		if sec.LotSize > 0 {
+			p.Lots = float64(p.Quantity) / float64(sec.LotSize)
+		}
+	}
+
+	return positions, nil
+}
+
+// aggregate рассчитывает позиции по операциям. Возвращает ошибку, если продажа превышает количество бумаг
+// или операции по бумаге совершены в разных валютах.
+func aggregate(trades []models.Trade) ([]Position, error) {
+	trades = append([]models.Trade(nil), trades...)
+	sortTrades(trades)
+
+	byIsin := make(map[string]*Position)
+	for _, t := range trades {
+		if t.Isin == "" {
+			continue
+		}
+		p, ok := byIsin[t.Isin]
+		if !ok {
+			p = &Position{Isin: t.Isin, Currency: t.Currency}
+			byIsin[t.Isin] = p
+		}
+		if t.Currency != p.Currency {
+			return nil, fmt.Errorf("%w: %s %s on %s in %s, position currency %s",
+				ErrIncorrectData, t.Isin, t.Type, t.Date, t.Currency, p.Currency)
+		}
+		p.TradeCount++
+		p.Fees += t.Fee
+
+		switch t.Type {
+		case models.TradeBuy:
+			p.Quantity += t.Quantity
+			p.Cost += t.Amount - t.AccruedInt + t.Fee
+			p.Income -= t.AccruedInt
+		case models.TradeSell:
+			if t.Quantity > p.Quantity {
+				return nil, fmt.Errorf("%w: %s sell of %d on %s exceeds position %d",
+					ErrIncorrectData, t.Isin, t.Quantity, t.Date, p.Quantity)
+			}
+			sold := p.Cost * float64(t.Quantity) / float64(p.Quantity)
+			p.Quantity -= t.Quantity
+			p.Cost -= sold
+			p.Realized += t.Amount - t.AccruedInt - t.Fee - sold
+			p.Income += t.AccruedInt
+		case models.TradeCoupon, models.TradeDividend:
+			p.Income += t.Amount - t.Fee
+		case models.TradeAmortization:
+			// Амортизация возвращает часть вложений, превышение над стоимостью приобретения - доход от погашения
+			p.Amortized += t.Amount
+			returned := min(t.Amount, p.Cost)
+			p.Cost -= returned
+			p.Realized += t.Amount - returned - t.Fee
+		case models.TradeFee:
+			p.Fees += t.Amount
+			p.Realized -= t.Amount + t.Fee
+		}
+	}
+
+	positions := make([]Position, 0, len(byIsin))
+	for _, p := range byIsin {
+		if p.Quantity == 0 {
+			p.Cost = 0
+		} else {
+			p.AvgPrice = round(p.Cost / float64(p.Quantity))
+		}
+		p.Cost = round(p.Cost)
+		p.Realized = round(p.Realized)
+		p.Income = round(p.Income)
+		p.Amortized = round(p.Amortized)
+		p.Fees = round(p.Fees)
+		positions = append(positions, *p)
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Isin < positions[j].Isin })
+
+	return positions, nil
+}
@@ -0,0 +1,173 @@
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"simple-invest/internal/curves"
+)
+
+const maxScenarios = 20 // Максимальное количество сценариев в одном запросе
+
+// ScenarioResult содержит стоимость при сценарии сдвига кривой доходности
+type ScenarioResult struct {
+	Name       string  `json:"name"`        // Название сценария
+	Value      float64 `json:"value"`       // Стоимость с учётом НКД
+	PnL        float64 `json:"pnl"`         // Изменение стоимости
+	PnLPercent float64 `json:"pnl_percent"` // Изменение стоимости в долях от текущей
+}
+
+// ScenarioPosition содержит переоценку позиции по сценариям
+type ScenarioPosition struct {
+	Isin      string           `json:"isin"`            // ISIN код
+	Quantity  int64            `json:"quantity"`        // Количество бумаг
+	Currency  string           `json:"currency"`        // Валюта
+	Price     float64          `json:"price"`           // Текущая цена одной бумаги с учётом НКД
+	Yield     float64          `json:"yield"`           // Эффективная доходность при текущей цене
+	Value     float64          `json:"value"`           // Текущая стоимость с учётом НКД
+	Scenarios []ScenarioResult `json:"scenarios"`       // Стоимость по сценариям
+	Error     string           `json:"error,omitempty"` // Ошибка переоценки, позиция не учитывается в итогах
+}
+
+// ScenarioTotal содержит итоги переоценки в одной валюте
+type ScenarioTotal struct {
+	Currency  string           `json:"currency"`  // Валюта
+	Value     float64          `json:"value"`     // Текущая стоимость с учётом НКД
+	Scenarios []ScenarioResult `json:"scenarios"` // Стоимость по сценариям
+}
+
+// ScenarioAnalysis содержит переоценку облигаций при сдвигах кривой доходности
+type ScenarioAnalysis struct {
+	PortfolioID int64              `json:"portfolio_id,omitempty"` // Портфель
+	ValuedAt    time.Time          `json:"valued_at"`              // Время оценки
+	Shocks      []curves.Shock     `json:"shocks"`                 // Сценарии
+	Positions   []ScenarioPosition `json:"positions"`              // Позиции
+	Totals      []ScenarioTotal    `json:"totals"`                 // Итоги по валютам
+}
+
+// Scenarios переоценивает открытые позиции портфеля при сдвигах кривой доходности shocks.
+// Позиции в акциях и ошибки переоценки отдельных бумаг возвращаются в позициях и не учитываются в итогах.
+func (s *PortfolioService) Scenarios(ctx context.Context, portfolioID int64, shocks []curves.Shock) (ScenarioAnalysis, error) {
+	if err := validateShocks(shocks); err != nil {
+		return ScenarioAnalysis{}, err
+	}
+
+	positions, err := s.Positions(portfolioID)
+	if err != nil {
+		return ScenarioAnalysis{}, err
+	}
+
+	quantities := make(map[string]int64)
+	var isins []string
+	for _, p := range positions {
+		if p.Quantity == 0 {
+			continue
+		}
+		if _, ok := quantities[p.Isin]; !ok {
+			isins = append(isins, p.Isin)
+		}
+		quantities[p.Isin] += p.Quantity
+	}
+
+	a, _ := s.scenarios(ctx, isins, quantities, shocks)
+	a.PortfolioID = portfolioID
+	return a, nil
+}
+
+// BondScenarios переоценивает quantity облигаций isin при сдвигах кривой доходности shocks
+func (s *PortfolioService) BondScenarios(ctx context.Context, isin string, quantity int64, shocks []curves.Shock) (ScenarioAnalysis, error) {
+	isin = strings.TrimSpace(isin)
+	if isin == "" {
+		return ScenarioAnalysis{}, fmt.Errorf("%w: empty isin", ErrIncorrectData)
+	}
+	if quantity <= 0 {
+		return ScenarioAnalysis{}, fmt.Errorf("%w: quantity must be positive", ErrIncorrectData)
+	}
+	if err := validateShocks(shocks); err != nil {
+		return ScenarioAnalysis{}, err
+	}
+
+	a, errs := s.scenarios(ctx, []string{isin}, map[string]int64{isin: quantity}, shocks)
+	if errs[0] != nil {
+		return a, fmt.Errorf("%s: %w", isin, errs[0])
+	}
+	return a, nil
+}
+
+// validateShocks проверяет сценарии и дополняет их значениями по умолчанию
+func validateShocks(shocks []curves.Shock) error {
+	if len(shocks) == 0 {
+		return fmt.Errorf("%w: no scenarios", ErrIncorrectData)
+	}
+	if len(shocks) > maxScenarios {
+		return fmt.Errorf("%w: too many scenarios: %d, maximum %d", ErrIncorrectData, len(shocks), maxScenarios)
+	}
+	for i := range shocks {
+		if err := shocks[i].Validate(); err != nil {
+			return fmt.Errorf("%w: %v", ErrIncorrectData, err)
+		}
+	}
+	return nil
+}
+
+// scenarios переоценивает позиции quantities в порядке isins и рассчитывает итоги по валютам.
+// Ошибки переоценки возвращаются в том же порядке.
+func (s *PortfolioService) scenarios(ctx context.Context, isins []string, quantities map[string]int64,
+	shocks []curves.Shock) (ScenarioAnalysis, []error) {
+	a := ScenarioAnalysis{ValuedAt: time.Now(), Shocks: shocks, Positions: []ScenarioPosition{}, Totals: []ScenarioTotal{}}
+	errs := make([]error, len(isins))
+
+	totals := make(map[string]*ScenarioTotal)
+	for j, isin := range isins {
+		p := ScenarioPosition{Isin: isin, Quantity: quantities[isin], Scenarios: []ScenarioResult{}}
+
+		q, err := s.prices.Reprice(ctx, isin, shocks)
+		if err != nil {
+			errs[j] = err
+			p.Error = err.Error()
+			a.Positions = append(a.Positions, p)
+			continue
+		}
+
+		quantity := float64(p.Quantity)
+		p.Currency, p.Price, p.Yield = q.Currency, q.Price, q.Yield
+		p.Value = round(q.Price * quantity)
+		for i, shock := range shocks {
+			p.Scenarios = append(p.Scenarios, scenarioResult(shock.Name, p.Value, round(q.Prices[i]*quantity)))
+		}
+		a.Positions = append(a.Positions, p)
+
+		t, ok := totals[p.Currency]
+		if !ok {
+			t = &ScenarioTotal{Currency: p.Currency, Scenarios: make([]ScenarioResult, len(shocks))}
+			totals[p.Currency] = t
+		}
+		t.Value += p.Value
+		for i := range shocks {
+			t.Scenarios[i].Value += p.Scenarios[i].Value
+		}
+	}
+
+	for _, t := range totals {
+		t.Value = round(t.Value)
+		for i, shock := range shocks {
+			t.Scenarios[i] = scenarioResult(shock.Name, t.Value, round(t.Scenarios[i].Value))
+		}
+		a.Totals = append(a.Totals, *t)
+	}
+	sort.Slice(a.Totals, func(i, j int) bool { return a.Totals[i].Currency < a.Totals[j].Currency })
+
+	return a, errs
+}
+
+// scenarioResult рассчитывает изменение стоимости value до стоимости по сценарию shocked
+func scenarioResult(name string, value, shocked float64) ScenarioResult {
+	r := ScenarioResult{Name: name, Value: shocked, PnL: round(shocked - value)}
+	if value != 0 {
+		r.PnLPercent = roundWeight(r.PnL / value)
+	}
+	return r
+}
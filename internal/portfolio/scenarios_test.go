@@ -0,0 +1,98 @@
+package portfolio
+
+import (
+	"context"
+	"testing"
+
+	"simple-invest/internal/curves"
+	"simple-invest/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPortfolioService_Scenarios(t *testing.T) {
+	repo := newMemRepo()
+	prices := stubPricer{
+		"B": {Isin: "B", CleanPrice: 950, AccruedInt: 12.5, Price: 962.5, Currency: "RUB"},
+		"S": {Isin: "S", CleanPrice: 300, Price: 300, Currency: "RUB"},
+	}
+	s := New(repo, prices)
+	ctx := context.Background()
+
+	p, err := s.CreatePortfolio(models.Portfolio{Name: "Основной"})
+	require.NoError(t, err)
+	acc, err := s.CreateAccount(models.Account{PortfolioID: p.ID, Name: "Брокерский"})
+	require.NoError(t, err)
+	trades := []models.Trade{
+		{AccountID: acc.ID, Isin: "B", Type: models.TradeBuy, Date: "2024-01-10", Quantity: 10, Price: 1000, AccruedInt: 40},
+		{AccountID: acc.ID, Isin: "S", Type: models.TradeBuy, Date: "2024-02-01", Quantity: 20, Price: 250},
+	}
+	for _, tr := range trades {
+		_, err := s.CreateTrade(p.ID, tr)
+		require.NoError(t, err)
+	}
+
+	shocks := []curves.Shock{
+		{Name: "+200", Type: curves.ShockParallel, Shift: 200},
+		{Type: curves.ShockParallel, Shift: -100},
+	}
+	a, err := s.Scenarios(ctx, p.ID, shocks)
+	require.NoError(t, err)
+	assert.Equal(t, p.ID, a.PortfolioID)
+	require.Len(t, a.Positions, 2)
+
+	bond := a.Positions[0]
+	assert.Equal(t, 9625.0, bond.Value)
+	assert.Equal(t, []ScenarioResult{
+		{Name: "+200", Value: 8662.5, PnL: -962.5, PnLPercent: -0.1},
+		{Name: curves.ShockParallel, Value: 10106.25, PnL: 481.25, PnLPercent: 0.05},
+	}, bond.Scenarios)
+
+	// Акции не переоцениваются и не учитываются в итогах
+	assert.NotEmpty(t, a.Positions[1].Error)
+	require.Len(t, a.Totals, 1)
+	assert.Equal(t, 9625.0, a.Totals[0].Value)
+	assert.Equal(t, bond.Scenarios, a.Totals[0].Scenarios)
+
+	_, err = s.Scenarios(ctx, p.ID+100, shocks)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestPortfolioService_BondScenarios(t *testing.T) {
+	prices := stubPricer{
+		"B": {Isin: "B", CleanPrice: 950, AccruedInt: 12.5, Price: 962.5, Currency: "RUB"},
+	}
+	s := New(newMemRepo(), prices)
+	ctx := context.Background()
+	parallel := []curves.Shock{{Type: curves.ShockParallel, Shift: 200}}
+
+	a, err := s.BondScenarios(ctx, "B", 2, parallel)
+	require.NoError(t, err)
+	require.Len(t, a.Positions, 1)
+	assert.Equal(t, 1925.0, a.Positions[0].Value)
+	assert.Equal(t, -192.5, a.Positions[0].Scenarios[0].PnL)
+
+	_, err = s.BondScenarios(ctx, "X", 1, parallel)
+	assert.Error(t, err)
+
+	tests := []struct {
+		name     string
+		isin     string
+		quantity int64
+		shocks   []curves.Shock
+	}{
+		{"empty isin", " ", 1, parallel},
+		{"zero quantity", "B", 0, parallel},
+		{"no scenarios", "B", 1, nil},
+		{"unknown type", "B", 1, []curves.Shock{{Type: "twist"}}},
+		{"custom without points", "B", 1, []curves.Shock{{Type: curves.ShockCustom}}},
+		{"too large shift", "B", 1, []curves.Shock{{Type: curves.ShockParallel, Shift: 10000}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.BondScenarios(ctx, tt.isin, tt.quantity, tt.shocks)
+			assert.ErrorIs(t, err, ErrIncorrectData)
+		})
+	}
+}
@@ -0,0 +1,363 @@
+package portfolio
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"simple-invest/internal/models"
+	"simple-invest/internal/repository"
+	"simple-invest/internal/tax"
+)
+
+const ldvAnnualCap = 3_000_000 // Предельный размер вычета ЛДВ за каждый полный год владения, руб.
+
+// TaxLot - партия бумаг, приобретённая одной операцией покупки
+type TaxLot struct {
+	AccountID int64   `json:"account_id"` // Счёт
+	Isin      string  `json:"isin"`       // ISIN код
+	Date      string  `json:"date"`       // Дата покупки
+	Quantity  int64   `json:"quantity"`   // Количество бумаг в партии
+	Cost      float64 `json:"cost"`       // Стоимость приобретения с учётом комиссии, без НКД
+}
+
+// LotSale - выбытие части партии бумаг при продаже или амортизации
+type LotSale struct {
+	AccountID int64   `json:"account_id"` // Счёт
+	Isin      string  `json:"isin"`       // ISIN код
+	Type      string  `json:"type"`       // Тип операции: sell или amortization
+	Date      string  `json:"date"`       // Дата выбытия
+	BuyDate   string  `json:"buy_date"`   // Дата покупки партии
+	Quantity  int64   `json:"quantity"`   // Количество проданных бумаг, для амортизации - 0
+	Proceeds  float64 `json:"proceeds"`   // Доход от выбытия за вычетом комиссии, без НКД
+	Cost      float64 `json:"cost"`       // Стоимость приобретения выбывших бумаг
+	Gain      float64 `json:"gain"`       // Финансовый результат
+	HeldYears int     `json:"held_years"` // Полных лет владения
+	LDV       bool    `json:"ldv"`        // Применима ЛДВ
+}
+
+// TaxYear содержит налоговые итоги года
+type TaxYear struct {
+	Year           int       `json:"year"`              // Год
+	TaxModel       tax.Model `json:"tax_model"`         // Налоговая модель
+	Sales          []LotSale `json:"sales"`             // Выбытия партий
+	Gains          float64   `json:"gains"`             // Прибыль по выбытиям
+	Losses         float64   `json:"losses"`            // Убыток по выбытиям
+	LDVResult      float64   `json:"ldv_result"`        // Финансовый результат по выбытиям с ЛДВ
+	LDVCap         float64   `json:"ldv_cap"`           // Предельный размер вычета ЛДВ
+	LDVDeduction   float64   `json:"ldv_deduction"`     // Вычет ЛДВ
+	Fees           float64   `json:"fees"`              // Комиссии, не относящиеся к сделкам
+	SecuritiesBase float64   `json:"securities_base"`   // Налоговая база по операциям с ценными бумагами
+	Coupons        float64   `json:"coupons"`           // Полученные купоны
+	ExemptCoupons  float64   `json:"exempt_coupons"`    // Купоны, освобождённые от налога
+	AccruedIntPaid float64   `json:"accruedint_paid"`   // НКД, уплаченный при покупке
+	AccruedIntRcvd float64   `json:"accruedint_rcvd"`   // НКД, полученный при продаже
+	CouponsBase    float64   `json:"coupons_base"`      // Налоговая база по купонам
+	Dividends      float64   `json:"dividends"`         // Полученные дивиденды российских эмитентов
+	ForeignDivs    float64   `json:"foreign_dividends"` // Полученные дивиденды иностранных эмитентов
+	TaxBase        float64   `json:"tax_base"`          // Общая налоговая база
+	Tax            float64   `json:"tax"`               // Налог
+}
+
+// TaxReport содержит налоговые итоги портфеля по годам и открытые партии бумаг
+type TaxReport struct {
+	PortfolioID int64     `json:"portfolio_id"` // Портфель
+	Years       []TaxYear `json:"years"`        // Итоги по годам
+	OpenLots    []TaxLot  `json:"open_lots"`    // Открытые партии
+}
+
+// TaxOptions задаёт налоговую модель отчёта
+type TaxOptions struct {
+	Year     int     // Год отчёта, 0 - все годы
+	Resident *bool   // Налоговое резидентство, по умолчанию - из настроек портфеля
+	Income   float64 // Прочие доходы с начала каждого года, учитываемые при определении ставки
+}
+
+// Taxes рассчитывает налоговые итоги портфеля. Продажи сопоставляются с покупками методом FIFO отдельно по каждому счёту.
+func (s *PortfolioService) Taxes(portfolioID int64, opts TaxOptions) (TaxReport, error) {
+	report := TaxReport{PortfolioID: portfolioID, Years: []TaxYear{}, OpenLots: []TaxLot{}}
+
+	p, err := s.repo.GetPortfolio(portfolioID)
+	if err != nil {
+		return report, err
+	}
+	trades, err := s.repo.GetTrades(portfolioID)
+	if err != nil {
+		return report, err
+	}
+
+	sales, lots, err := matchLots(trades)
+	if err != nil {
+		return report, err
+	}
+	report.OpenLots = lots
+
+	kinds, err := s.incomeKinds(trades)
+	if err != nil {
+		return report, err
+	}
+	tm := tax.Model{Resident: !p.NonResident, Income: opts.Income}
+	if opts.Resident != nil {
+		tm.Resident = *opts.Resident
+	}
+
+	for _, ty := range taxYears(trades, sales, kinds, tm) {
+		if opts.Year == 0 || ty.Year == opts.Year {
+			report.Years = append(report.Years, ty)
+		}
+	}
+	return report, nil
+}
+
+// incomeKinds определяет вид дохода по купонам и дивидендам каждой бумаги
+func (s *PortfolioService) incomeKinds(trades []models.Trade) (map[string]tax.Income, error) {
+	kinds := make(map[string]tax.Income)
+	for _, t := range trades {
+		if _, ok := kinds[t.Isin]; ok {
+			continue
+		}
+		switch t.Type {
+		case models.TradeCoupon:
+			sec, _, err := s.repo.GetSecurity(t.Isin)
+			if err != nil && !errors.Is(err, repository.ErrNotFound) {
+				return nil, err
+			}
+			kinds[t.Isin] = tax.CouponKind(sec.Type, t.Isin)
+		case models.TradeDividend:
+			kinds[t.Isin] = tax.DividendKind(t.Isin)
+		}
+	}
+	return kinds, nil
+}
+
+// lotKey - ключ партий бумаги на счёте
+type lotKey struct {
+	accountID int64
+	isin      string
+}
+
+// matchLots сопоставляет продажи и амортизации с партиями бумаг методом FIFO.
+// Амортизация распределяется между открытыми партиями пропорционально количеству бумаг.
+func matchLots(trades []models.Trade) ([]LotSale, []TaxLot, error) {
+	trades = append([]models.Trade(nil), trades...)
+	sortTrades(trades)
+
+	open := make(map[lotKey][]TaxLot)
+	sales := []LotSale{}
+	for _, t := range trades {
+		key := lotKey{t.AccountID, t.Isin}
+
+		switch t.Type {
+		case models.TradeBuy:
+			open[key] = append(open[key], TaxLot{
+				AccountID: t.AccountID,
+				Isin:      t.Isin,
+				Date:      t.Date,
+				Quantity:  t.Quantity,
+				Cost:      t.Amount - t.AccruedInt + t.Fee,
+			})
+		case models.TradeSell:
+			lots := open[key]
+			proceeds := t.Amount - t.AccruedInt - t.Fee
+			rest := t.Quantity
+			for rest > 0 {
+				if len(lots) == 0 {
+					return nil, nil, fmt.Errorf("%w: %s sell of %d on %s exceeds position on account %d",
+						ErrIncorrectData, t.Isin, t.Quantity, t.Date, t.AccountID)
+				}
+				lot := &lots[0]
+				q := min(rest, lot.Quantity)
+				cost := lot.Cost * float64(q) / float64(lot.Quantity)
+				sales = append(sales, newLotSale(t, *lot, q, proceeds*float64(q)/float64(t.Quantity), cost))
+
+				lot.Quantity -= q
+				lot.Cost -= cost
+				rest -= q
+				if lot.Quantity == 0 {
+					lots = lots[1:]
+				}
+			}
+			open[key] = lots
+		case models.TradeAmortization:
+			lots := open[key]
+			var total int64
+			for _, lot := range lots {
+				total += lot.Quantity
+			}
+			if total == 0 {
+				return nil, nil, fmt.Errorf("%w: %s amortization on %s without position on account %d",
+					ErrIncorrectData, t.Isin, t.Date, t.AccountID)
+			}
+			for i := range lots {
+				lot := &lots[i]
+				share := (t.Amount - t.Fee) * float64(lot.Quantity) / float64(total)
+				cost := min(share, lot.Cost)
+				sales = append(sales, newLotSale(t, *lot, 0, share, cost))
+				lot.Cost -= cost
+			}
+		}
+	}
+
+	lots := []TaxLot{}
+	for _, l := range open {
+		for _, lot := range l {
+			lot.Cost = round(lot.Cost)
+			lots = append(lots, lot)
+		}
+	}
+	sort.Slice(lots, func(i, j int) bool {
+		if lots[i].Date != lots[j].Date {
+			return lots[i].Date < lots[j].Date
+		}
+		if lots[i].Isin != lots[j].Isin {
+			return lots[i].Isin < lots[j].Isin
+		}
+		return lots[i].AccountID < lots[j].AccountID
+	})
+
+	return sales, lots, nil
+}
+
+// newLotSale создаёт выбытие части партии lot операцией t
+func newLotSale(t models.Trade, lot TaxLot, quantity int64, proceeds, cost float64) LotSale {
+	buyDate, _ := time.Parse(time.DateOnly, lot.Date)
+	saleDate, _ := time.Parse(time.DateOnly, t.Date)
+
+	years := fullYears(buyDate, saleDate)
+	return LotSale{
+		AccountID: t.AccountID,
+		Isin:      t.Isin,
+		Type:      t.Type,
+		Date:      t.Date,
+		BuyDate:   lot.Date,
+		Quantity:  quantity,
+		Proceeds:  round(proceeds),
+		Cost:      round(cost),
+		Gain:      round(proceeds - cost),
+		HeldYears: years,
+		LDV:       tax.LongTermHolding(buyDate, saleDate),
+	}
+}
+
+// fullYears возвращает количество полных лет между датами from и to
+func fullYears(from, to time.Time) int {
+	years := to.Year() - from.Year()
+	if from.AddDate(years, 0, 0).After(to) {
+		years--
+	}
+	return max(years, 0)
+}
+
+// taxYears рассчитывает налоговые итоги по годам по модели tm, вид дохода по купонам и дивидендам задаётся kinds.
+// Убыток по операциям с ценными бумагами не уменьшает налоговую базу по купонам и дивидендам.
+// Вычет ЛДВ применяется только для налоговых резидентов.
+func taxYears(trades []models.Trade, sales []LotSale, kinds map[string]tax.Income, tm tax.Model) []TaxYear {
+	byYear := make(map[int]*TaxYear)
+	get := func(date string) *TaxYear {
+		d, _ := time.Parse(time.DateOnly, date)
+		ty, ok := byYear[d.Year()]
+		if !ok {
+			ty = &TaxYear{Year: d.Year(), Sales: []LotSale{}}
+			ty.TaxModel = tm
+			ty.TaxModel.Year = d.Year()
+			byYear[d.Year()] = ty
+		}
+		return ty
+	}
+
+	for _, t := range trades {
+		ty := get(t.Date)
+		switch t.Type {
+		case models.TradeBuy:
+			ty.AccruedIntPaid += t.AccruedInt
+		case models.TradeSell:
+			ty.AccruedIntRcvd += t.AccruedInt
+		case models.TradeCoupon:
+			ty.Coupons += t.Amount
+			if ty.TaxModel.Rate(kinds[t.Isin]) == 0 {
+				ty.ExemptCoupons += t.Amount
+			}
+		case models.TradeDividend:
+			if kinds[t.Isin] == tax.ForeignDividend {
+				ty.ForeignDivs += t.Amount
+			} else {
+				ty.Dividends += t.Amount
+			}
+		case models.TradeFee:
+			ty.Fees += t.Amount + t.Fee
+		}
+	}
+
+	// Вычет ЛДВ ограничен суммой 3 млн руб., умноженной на коэффициент Кцб -
+	// среднее количество полных лет владения, взвешенное по доходам от выбытия
+	ldvProceeds := make(map[int]float64)
+	ldvWeighted := make(map[int]float64)
+	for _, sale := range sales {
+		ty := get(sale.Date)
+		sale.LDV = sale.LDV && tm.Resident
+		ty.Sales = append(ty.Sales, sale)
+		if sale.Gain > 0 {
+			ty.Gains += sale.Gain
+		} else {
+			ty.Losses -= sale.Gain
+		}
+		if sale.LDV {
+			ty.LDVResult += sale.Gain
+			ldvProceeds[ty.Year] += sale.Proceeds
+			ldvWeighted[ty.Year] += sale.Proceeds * float64(sale.HeldYears)
+		}
+	}
+
+	years := make([]TaxYear, 0, len(byYear))
+	for _, ty := range byYear {
+		if ldvProceeds[ty.Year] > 0 {
+			ty.LDVCap = round(ldvAnnualCap * ldvWeighted[ty.Year] / ldvProceeds[ty.Year])
+		}
+		if ty.LDVResult > 0 {
+			ty.LDVDeduction = min(ty.LDVResult, ty.LDVCap)
+		}
+
+		ty.SecuritiesBase = max(ty.Gains-ty.Losses-ty.LDVDeduction-ty.Fees, 0)
+		ty.CouponsBase = max(ty.Coupons-ty.ExemptCoupons+ty.AccruedIntRcvd-ty.AccruedIntPaid, 0)
+
+		// Доходы учитываются последовательно: ставка прогрессивной шкалы зависит от суммы доходов с начала года
+		m := ty.TaxModel
+		for _, income := range []struct {
+			kind tax.Income
+			base float64
+		}{
+			{tax.CapitalGain, ty.SecuritiesBase},
+			{tax.Coupon, ty.CouponsBase},
+			{tax.Dividend, ty.Dividends},
+			{tax.ForeignDividend, ty.ForeignDivs},
+		} {
+			if m.Rate(income.kind) == 0 {
+				continue
+			}
+			ty.TaxBase += income.base
+			ty.Tax += m.Tax(income.kind, income.base)
+			m = m.Add(income.base)
+		}
+		ty.Tax = round(ty.Tax)
+
+		ty.Gains = round(ty.Gains)
+		ty.Losses = round(ty.Losses)
+		ty.LDVResult = round(ty.LDVResult)
+		ty.LDVDeduction = round(ty.LDVDeduction)
+		ty.Fees = round(ty.Fees)
+		ty.SecuritiesBase = round(ty.SecuritiesBase)
+		ty.Coupons = round(ty.Coupons)
+		ty.ExemptCoupons = round(ty.ExemptCoupons)
+		ty.AccruedIntPaid = round(ty.AccruedIntPaid)
+		ty.AccruedIntRcvd = round(ty.AccruedIntRcvd)
+		ty.CouponsBase = round(ty.CouponsBase)
+		ty.Dividends = round(ty.Dividends)
+		ty.ForeignDivs = round(ty.ForeignDivs)
+		ty.TaxBase = round(ty.TaxBase)
+		years = append(years, *ty)
+	}
+	sort.Slice(years, func(i, j int) bool { return years[i].Year < years[j].Year })
+
+	return years
+}
@@ -0,0 +1,185 @@
+package portfolio
+
+import (
+	"testing"
+	"time"
+
+	"simple-invest/internal/models"
+	"simple-invest/internal/tax"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPortfolioService_Taxes(t *testing.T) {
+	repo := newMemRepo()
+	s := New(repo, nil)
+
+	p, err := s.CreatePortfolio(models.Portfolio{Name: "Основной"})
+	require.NoError(t, err)
+	acc1, err := s.CreateAccount(models.Account{PortfolioID: p.ID, Name: "Брокерский"})
+	require.NoError(t, err)
+	acc2, err := s.CreateAccount(models.Account{PortfolioID: p.ID, Name: "ИИС"})
+	require.NoError(t, err)
+
+	trades := []models.Trade{
+		{AccountID: acc1.ID, Isin: "A", Type: models.TradeBuy, Date: "2019-03-01", Quantity: 100, Price: 1000, Fee: 10},
+		{AccountID: acc1.ID, Isin: "A", Type: models.TradeBuy, Date: "2022-05-10", Quantity: 50, Price: 1200},
+		{AccountID: acc2.ID, Isin: "A", Type: models.TradeBuy, Date: "2023-01-01", Quantity: 10, Price: 1400},
+		{AccountID: acc2.ID, Isin: "A", Type: models.TradeSell, Date: "2023-02-01", Quantity: 5, Price: 1300},
+		{AccountID: acc1.ID, Isin: "B", Type: models.TradeBuy, Date: "2023-01-10", Quantity: 10, Price: 1000, AccruedInt: 20},
+		{AccountID: acc1.ID, Isin: "A", Type: models.TradeSell, Date: "2023-06-01", Quantity: 120, Price: 1500},
+		{AccountID: acc1.ID, Isin: "B", Type: models.TradeCoupon, Date: "2023-07-10", Amount: 500},
+		{AccountID: acc1.ID, Isin: "S", Type: models.TradeDividend, Date: "2023-07-20", Amount: 300},
+		{AccountID: acc1.ID, Isin: "B", Type: models.TradeSell, Date: "2023-09-01", Quantity: 10, Price: 900, AccruedInt: 5},
+		{AccountID: acc1.ID, Type: models.TradeFee, Date: "2023-12-31", Amount: 100},
+	}
+	for _, tr := range trades {
+		_, err := s.CreateTrade(p.ID, tr)
+		require.NoError(t, err)
+	}
+
+	report, err := s.Taxes(p.ID, TaxOptions{Year: 2023})
+	require.NoError(t, err)
+	require.Len(t, report.Years, 1)
+
+	ty := report.Years[0]
+	require.Len(t, ty.Sales, 4)
+
+	// Продажа на втором счёте сопоставляется только с покупкой на этом счёте
+	assert.Equal(t, LotSale{AccountID: acc2.ID, Isin: "A", Type: models.TradeSell, Date: "2023-02-01", BuyDate: "2023-01-01",
+		Quantity: 5, Proceeds: 6500, Cost: 7000, Gain: -500}, ty.Sales[0])
+	// Продажа 120 бумаг закрывает партию 2019 года целиком и частично партию 2022 года
+	assert.Equal(t, LotSale{AccountID: acc1.ID, Isin: "A", Type: models.TradeSell, Date: "2023-06-01", BuyDate: "2019-03-01",
+		Quantity: 100, Proceeds: 150000, Cost: 100010, Gain: 49990, HeldYears: 4, LDV: true}, ty.Sales[1])
+	assert.Equal(t, LotSale{AccountID: acc1.ID, Isin: "A", Type: models.TradeSell, Date: "2023-06-01", BuyDate: "2022-05-10",
+		Quantity: 20, Proceeds: 30000, Cost: 24000, Gain: 6000, HeldYears: 1}, ty.Sales[2])
+
+	assert.Equal(t, 55990.0, ty.Gains)
+	assert.Equal(t, 1500.0, ty.Losses)
+	assert.Equal(t, 12_000_000.0, ty.LDVCap)
+	assert.Equal(t, 49990.0, ty.LDVDeduction)
+	assert.Equal(t, 4400.0, ty.SecuritiesBase)
+	assert.Equal(t, 485.0, ty.CouponsBase)
+	assert.Equal(t, 5185.0, ty.TaxBase)
+	assert.Equal(t, 674.05, ty.Tax)
+
+	assert.Equal(t, []TaxLot{
+		{AccountID: acc1.ID, Isin: "A", Date: "2022-05-10", Quantity: 30, Cost: 36000},
+		{AccountID: acc2.ID, Isin: "A", Date: "2023-01-01", Quantity: 5, Cost: 7000},
+	}, report.OpenLots)
+
+	report, err = s.Taxes(p.ID, TaxOptions{})
+	require.NoError(t, err)
+	assert.Len(t, report.Years, 3)
+}
+
+func Test_taxYears(t *testing.T) {
+	tests := []struct {
+		name        string
+		sales       []LotSale
+		nonResident bool
+		cap         float64
+		deduction   float64
+		base        float64
+	}{
+		{
+			name:      "cap",
+			sales:     []LotSale{{Date: "2024-03-01", Proceeds: 20_000_000, Gain: 10_000_000, HeldYears: 3, LDV: true}},
+			cap:       9_000_000,
+			deduction: 9_000_000,
+			base:      1_000_000,
+		},
+		{
+			name: "weighted years",
+			sales: []LotSale{
+				{Date: "2024-03-01", Proceeds: 10_000_000, Gain: 9_000_000, HeldYears: 3, LDV: true},
+				{Date: "2024-03-01", Proceeds: 30_000_000, Gain: 29_000_000, HeldYears: 7, LDV: true},
+			},
+			cap:       18_000_000,
+			deduction: 18_000_000,
+			base:      20_000_000,
+		},
+		{
+			name: "ldv loss",
+			sales: []LotSale{
+				{Date: "2024-03-01", Proceeds: 1000, Gain: -200, HeldYears: 4, LDV: true},
+				{Date: "2024-03-01", Proceeds: 1000, Gain: 500, HeldYears: 1},
+			},
+			cap:  12_000_000,
+			base: 300,
+		},
+		{
+			name:        "non-resident",
+			sales:       []LotSale{{Date: "2024-03-01", Proceeds: 20_000_000, Gain: 10_000_000, HeldYears: 3, LDV: true}},
+			nonResident: true,
+			base:        10_000_000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			years := taxYears(nil, tt.sales, nil, tax.Model{Resident: !tt.nonResident})
+			require.Len(t, years, 1)
+			assert.Equal(t, !tt.nonResident, years[0].Sales[0].LDV)
+			assert.Equal(t, tt.cap, years[0].LDVCap)
+			assert.Equal(t, tt.deduction, years[0].LDVDeduction)
+			assert.Equal(t, tt.base, years[0].SecuritiesBase)
+		})
+	}
+}
+
+func Test_fullYears(t *testing.T) {
+	date := func(s string) time.Time {
+		d, _ := time.Parse(time.DateOnly, s)
+		return d
+	}
+	assert.Equal(t, 2, fullYears(date("2021-06-02"), date("2024-06-01")))
+	assert.Equal(t, 3, fullYears(date("2021-06-01"), date("2024-06-01")))
+	assert.Equal(t, 0, fullYears(date("2024-06-01"), date("2024-01-01")))
+}
+
+func TestPortfolioService_TaxesModel(t *testing.T) {
+	repo := newMemRepo()
+	s := New(repo, nil)
+
+	p, err := s.CreatePortfolio(models.Portfolio{Name: "Основной"})
+	require.NoError(t, err)
+	acc, err := s.CreateAccount(models.Account{PortfolioID: p.ID, Name: "Брокерский"})
+	require.NoError(t, err)
+
+	trades := []models.Trade{
+		{AccountID: acc.ID, Isin: "SU26238RMFS4", Type: models.TradeCoupon, Date: "2020-06-01", Amount: 400},
+		{AccountID: acc.ID, Isin: "RU000A100YL8", Type: models.TradeCoupon, Date: "2020-06-01", Amount: 300},
+		{AccountID: acc.ID, Isin: "RU0009029540", Type: models.TradeDividend, Date: "2020-07-01", Amount: 200},
+		{AccountID: acc.ID, Isin: "US88160R1014", Type: models.TradeDividend, Date: "2020-07-01", Amount: 100},
+		{AccountID: acc.ID, Isin: "RU000A100YL8", Type: models.TradeCoupon, Date: "2024-06-01", Amount: 2000},
+	}
+	for _, tr := range trades {
+		_, err := s.CreateTrade(p.ID, tr)
+		require.NoError(t, err)
+	}
+
+	nonResident := false
+	tests := []struct {
+		name   string
+		opts   TaxOptions
+		exempt float64
+		base   float64
+		tax    float64
+	}{
+		{"resident 2020", TaxOptions{Year: 2020}, 400, 600, 78},
+		{"non-resident 2020", TaxOptions{Year: 2020, Resident: &nonResident}, 400, 500, 120},
+		{"progressive 2024", TaxOptions{Year: 2024, Income: 4_999_000}, 0, 2000, 280},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := s.Taxes(p.ID, tt.opts)
+			require.NoError(t, err)
+			require.Len(t, report.Years, 1)
+			assert.Equal(t, tt.exempt, report.Years[0].ExemptCoupons)
+			assert.Equal(t, tt.base, report.Years[0].TaxBase)
+			assert.Equal(t, tt.tax, report.Years[0].Tax)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"simple-invest/internal/curves"
+	"simple-invest/internal/models"
+	"simple-invest/internal/tax"
+)
+
+// Pricer возвращает текущие цены ценных бумаг, цены облигаций при сдвигах кривой доходности, графики выплат
+// и календарь событий по бумагам
+type Pricer interface {
+	Quote(ctx context.Context, isin string) (models.Quote, error)
+	Reprice(ctx context.Context, isin string, shocks []curves.Shock) (models.ScenarioQuote, error)
+	CashFlows(ctx context.Context, isin string, lots []models.Lot, tm tax.Model) ([]models.CashFlow, error)
+	CalendarEvents(ctx context.Context, isin string, quantity int64, from time.Time) ([]models.CalendarEvent, error)
+}
+
+// PositionValue содержит оценку открытой позиции по текущей цене
+type PositionValue struct {
+	Position
+	CleanPrice  float64 `json:"clean_price"`     // Цена одной бумаги без НКД
+	AccruedInt  float64 `json:"accruedint"`      // НКД по всем бумагам позиции
+	MarketValue float64 `json:"market_value"`    // Рыночная стоимость с учётом НКД
+	Unrealized  float64 `json:"unrealized"`      // Нереализованный результат: стоимость без НКД за вычетом стоимости приобретения
+	Weight      float64 `json:"weight"`          // Доля в рыночной стоимости портфеля в той же валюте
+	Error       string  `json:"error,omitempty"` // Ошибка оценки, позиция не учитывается в итогах
+}
+
+// ValuationTotal содержит итоги оценки портфеля в одной валюте
+type ValuationTotal struct {
+	Currency    string  `json:"currency"`     // Валюта
+	MarketValue float64 `json:"market_value"` // Рыночная стоимость с учётом НКД
+	Cost        float64 `json:"cost"`         // Стоимость приобретения без НКД
+	AccruedInt  float64 `json:"accruedint"`   // НКД
+	Unrealized  float64 `json:"unrealized"`   // Нереализованный результат без НКД
+}
+
+// Valuation содержит оценку портфеля
+type Valuation struct {
+	PortfolioID int64            `json:"portfolio_id"` // Портфель
+	ValuedAt    time.Time        `json:"valued_at"`    // Время оценки
+	Positions   []PositionValue  `json:"positions"`    // Открытые позиции
+	Totals      []ValuationTotal `json:"totals"`       // Итоги по валютам
+}
+
+// Valuation оценивает открытые позиции портфеля по текущим ценам.
+// Нереализованный результат сравнивает стоимость без НКД со стоимостью приобретения, которая также не включает НКД.
+// Ошибка получения цены отдельной бумаги или несовпадение валюты цены с валютой операций возвращается в её позиции
+// и не прерывает оценку остальных.
+func (s *PortfolioService) Valuation(ctx context.Context, portfolioID int64) (Valuation, error) {
+	v := Valuation{PortfolioID: portfolioID, ValuedAt: time.Now(), Positions: []PositionValue{}, Totals: []ValuationTotal{}}
+
+	positions, err := s.Positions(portfolioID)
+	if err != nil {
+		return v, err
+	}
+
+	totals := make(map[string]*ValuationTotal)
+	for _, p := range positions {
+		if p.Quantity == 0 {
+			continue
+		}
+		pv := PositionValue{Position: p}
+
+		q, err := s.prices.Quote(ctx, p.Isin)
+		if err != nil {
+			pv.Error = err.Error()
+			v.Positions = append(v.Positions, pv)
+			continue
+		}
+
+		if q.Currency != p.Currency {
+			pv.Error = fmt.Sprintf("price currency %s differs from trade currency %s", q.Currency, p.Currency)
+			v.Positions = append(v.Positions, pv)
+			continue
+		}
+
+		quantity := float64(p.Quantity)
+		pv.CleanPrice = q.CleanPrice
+		pv.AccruedInt = round(q.AccruedInt * quantity)
+		pv.MarketValue = round(q.Price * quantity)
+		pv.Unrealized = round(q.CleanPrice*quantity - p.Cost)
+		v.Positions = append(v.Positions, pv)
+
+		t, ok := totals[pv.Currency]
+		if !ok {
+			t = &ValuationTotal{Currency: pv.Currency}
+			totals[pv.Currency] = t
+		}
+		t.MarketValue += pv.MarketValue
+		t.Cost += pv.Cost
+		t.AccruedInt += pv.AccruedInt
+		t.Unrealized += pv.Unrealized
+	}
+
+	for i := range v.Positions {
+		pv := &v.Positions[i]
+		if t, ok := totals[pv.Currency]; ok && pv.Error == "" && t.MarketValue != 0 {
+			pv.Weight = roundWeight(pv.MarketValue / t.MarketValue)
+		}
+	}
+	for _, t := range totals {
+		t.MarketValue = round(t.MarketValue)
+		t.Cost = round(t.Cost)
+		t.AccruedInt = round(t.AccruedInt)
+		t.Unrealized = round(t.Unrealized)
+		v.Totals = append(v.Totals, *t)
+	}
+	sort.Slice(v.Totals, func(i, j int) bool { return v.Totals[i].Currency < v.Totals[j].Currency })
+
+	return v, nil
+}
+
+// roundWeight округляет долю позиции до сотых долей процента
+func roundWeight(w float64) float64 {
+	return round(w*100) / 100
+}
@@ -0,0 +1,176 @@
+package portfolio
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"simple-invest/internal/curves"
+	"simple-invest/internal/models"
+	"simple-invest/internal/tax"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubPricer возвращает заданные цены, для остальных бумаг - ошибку
+type stubPricer map[string]models.Quote
+
+func (p stubPricer) Quote(ctx context.Context, isin string) (models.Quote, error) {
+	q, ok := p[isin]
+	if !ok {
+		return q, errors.New("no moex data provided")
+	}
+	return q, nil
+}
+
+// Reprice возвращает цены облигаций с модифицированной дюрацией 5 лет. Бумаги без НКД считаются акциями.
+func (p stubPricer) Reprice(ctx context.Context, isin string, shocks []curves.Shock) (models.ScenarioQuote, error) {
+	q, err := p.Quote(ctx, isin)
+	if err != nil {
+		return models.ScenarioQuote{}, err
+	}
+	if q.AccruedInt == 0 {
+		return models.ScenarioQuote{}, errors.New("not a bond")
+	}
+	sq := models.ScenarioQuote{Isin: isin, Price: q.Price, Currency: q.Currency}
+	for _, shock := range shocks {
+		sq.Prices = append(sq.Prices, q.Price*(1-5*shock.At(5)))
+	}
+	return sq, nil
+}
+
+// CashFlows возвращает по облигации купон 50 на 2025-01-15 и погашение номинала 1000 на 2025-07-15
+// с налогом 13% на купон и доход от погашения с учётом ЛДВ по партиям, а по облигации с ценой выше номинала -
+// также выкуп по номиналу на оферте 2025-01-15. Бумаги без НКД считаются акциями.
+func (p stubPricer) CashFlows(ctx context.Context, isin string, lots []models.Lot, tm tax.Model) ([]models.CashFlow, error) {
+	q, err := p.Quote(ctx, isin)
+	if err != nil {
+		return nil, err
+	}
+	if q.AccruedInt == 0 {
+		return nil, errors.New("not a bond")
+	}
+	maturity := time.Date(2025, time.July, 15, 0, 0, 0, 0, time.UTC)
+	var quantity int64
+	gainTax := 0.0
+	for _, lot := range lots {
+		quantity += lot.Quantity
+		if lot.Cost < 1000 && !tm.LongTermExempt(lot.Date, maturity) {
+			gainTax += round((1000 - lot.Cost) * 0.13 * float64(lot.Quantity))
+		}
+	}
+	n := float64(quantity)
+	flows := []models.CashFlow{
+		{Date: "2025-01-15", Isin: isin, Type: models.CashFlowCoupon, Quantity: quantity, Amount: 50 * n,
+			Tax: 6.5 * n, Net: 43.5 * n, Currency: q.Currency},
+		{Date: "2025-07-15", Isin: isin, Type: models.CashFlowMaturity, Quantity: quantity, Amount: 1000 * n,
+			Tax: gainTax, Net: 1000*n - gainTax, Currency: q.Currency},
+	}
+	if q.CleanPrice > 1000 {
+		offer := flows[1]
+		offer.Date, offer.Type = "2025-01-15", models.CashFlowOffer
+		flows = []models.CashFlow{flows[0], offer, flows[1]}
+	}
+	return flows, nil
+}
+
+// CalendarEvents возвращает по облигации купон 50 на 2025-01-15 и погашение номинала 1000 на 2025-07-15,
+// по акции - дивиденд 10 на 2024-07-11. События ранее from не возвращаются.
+func (p stubPricer) CalendarEvents(ctx context.Context, isin string, quantity int64, from time.Time) ([]models.CalendarEvent, error) {
+	q, err := p.Quote(ctx, isin)
+	if err != nil {
+		return nil, err
+	}
+	n := float64(quantity)
+	all := []models.CalendarEvent{
+		{Date: "2024-07-11", Isin: isin, Type: models.EventDividend, Quantity: quantity, Value: 10, Amount: 10 * n, Currency: q.Currency},
+	}
+	if q.AccruedInt != 0 {
+		all = []models.CalendarEvent{
+			{Date: "2025-01-15", Isin: isin, Type: models.EventCoupon, Quantity: quantity, Value: 50, Amount: 50 * n, Currency: q.Currency},
+			{Date: "2025-07-15", Isin: isin, Type: models.EventMaturity, Quantity: quantity, Value: 1000, Amount: 1000 * n, Currency: q.Currency},
+		}
+	}
+	var events []models.CalendarEvent
+	for _, e := range all {
+		if e.Date >= from.Format(time.DateOnly) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func TestPortfolioService_Valuation(t *testing.T) {
+	repo := newMemRepo()
+	prices := stubPricer{
+		"B": {Isin: "B", CleanPrice: 950, AccruedInt: 12.5, Price: 962.5, Currency: "RUB"},
+		"E": {Isin: "E", CleanPrice: 9000, AccruedInt: 90, Price: 9090, Currency: "RUB"},
+		"S": {Isin: "S", CleanPrice: 300, Price: 300, Currency: "RUB"},
+		"U": {Isin: "U", CleanPrice: 98, AccruedInt: 1, Price: 99, Currency: "USD"},
+	}
+	s := New(repo, prices)
+
+	p, err := s.CreatePortfolio(models.Portfolio{Name: "Основной"})
+	require.NoError(t, err)
+	acc, err := s.CreateAccount(models.Account{PortfolioID: p.ID, Name: "Брокерский"})
+	require.NoError(t, err)
+
+	trades := []models.Trade{
+		{AccountID: acc.ID, Isin: "B", Type: models.TradeBuy, Date: "2024-01-10", Quantity: 10, Price: 1000, AccruedInt: 40},
+		{AccountID: acc.ID, Isin: "E", Type: models.TradeBuy, Date: "2024-01-10", Quantity: 1, Price: 100, Currency: "USD"},
+		{AccountID: acc.ID, Isin: "S", Type: models.TradeBuy, Date: "2024-02-01", Quantity: 20, Price: 250},
+		{AccountID: acc.ID, Isin: "U", Type: models.TradeBuy, Date: "2024-02-01", Quantity: 5, Price: 100, AccruedInt: 2, Currency: "USD"},
+		{AccountID: acc.ID, Isin: "X", Type: models.TradeBuy, Date: "2024-02-01", Quantity: 1, Price: 100},
+		{AccountID: acc.ID, Isin: "C", Type: models.TradeBuy, Date: "2024-02-01", Quantity: 1, Price: 100},
+		{AccountID: acc.ID, Isin: "C", Type: models.TradeSell, Date: "2024-03-01", Quantity: 1, Price: 110},
+	}
+	for _, tr := range trades {
+		_, err := s.CreateTrade(p.ID, tr)
+		require.NoError(t, err)
+	}
+
+	v, err := s.Valuation(context.Background(), p.ID)
+	require.NoError(t, err)
+
+	// Закрытая позиция C не оценивается
+	require.Len(t, v.Positions, 5)
+
+	// Результат считается по цене без НКД: уплаченный НКД не входит в стоимость приобретения
+	bond := v.Positions[0]
+	assert.Equal(t, "B", bond.Isin)
+	assert.Equal(t, 9625.0, bond.MarketValue)
+	assert.Equal(t, 125.0, bond.AccruedInt)
+	assert.Equal(t, -500.0, bond.Unrealized)
+	assert.Equal(t, 0.6160, bond.Weight)
+
+	// Цена в рублях не сравнивается со стоимостью покупки в долларах
+	foreign := v.Positions[1]
+	assert.Equal(t, "USD", foreign.Currency)
+	assert.NotEmpty(t, foreign.Error)
+	assert.Zero(t, foreign.MarketValue)
+	assert.Zero(t, foreign.Weight)
+
+	share := v.Positions[2]
+	assert.Equal(t, 6000.0, share.MarketValue)
+	assert.Equal(t, 1000.0, share.Unrealized)
+	assert.Equal(t, 0.3840, share.Weight)
+
+	usd := v.Positions[3]
+	assert.Equal(t, "USD", usd.Currency)
+	assert.Equal(t, 495.0, usd.MarketValue)
+	assert.Equal(t, -10.0, usd.Unrealized)
+	assert.Equal(t, 1.0, usd.Weight)
+
+	unknown := v.Positions[4]
+	assert.NotEmpty(t, unknown.Error)
+	assert.Zero(t, unknown.Weight)
+
+	require.Len(t, v.Totals, 2)
+	assert.Equal(t, ValuationTotal{Currency: "RUB", MarketValue: 15625, Cost: 15000, AccruedInt: 125, Unrealized: 500}, v.Totals[0])
+	assert.Equal(t, ValuationTotal{Currency: "USD", MarketValue: 495, Cost: 500, AccruedInt: 5, Unrealized: -10}, v.Totals[1])
+
+	_, err = s.Valuation(context.Background(), p.ID+100)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"simple-invest/internal/models"
+)
+
+func (r *PostgresRepo) GetCoupons(isin string) ([]models.Coupon, error) {
+	return queryCoupons(r, `
+		SELECT isin, coupondate, recorddate, initialfacevalue, facevalue, faceunit, value, valueprc, value_rub
+		FROM coupons
+		WHERE isin = $1
+		ORDER BY coupondate`, isin)
+}
+
+func (r *PostgresRepo) GetAmortizations(isin string) ([]models.Amortization, error) {
+	return queryAmortizations(r, `
+		SELECT isin, amortdate, facevalue, initialfacevalue, faceunit, value, value_rub
+		FROM amortizations
+		WHERE isin = $1
+		ORDER BY amortdate`, isin)
+}
+
+// GetOffers возвращает сохранённый график оферт облигации
+func (r *PostgresRepo) GetOffers(isin string) ([]models.Offer, error) {
+	rows, err := r.db.Query(`
+		SELECT isin, offerdate, offerdatestart, offerdateend, facevalue, faceunit, price, value, agent, offertype
+		FROM offers
+		WHERE isin = $1
+		ORDER BY offerdate`, isin)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	offers := []models.Offer{}
+	for rows.Next() {
+		var o models.Offer
+		var offerDate time.Time
+		var start, end sql.NullTime
+		err := rows.Scan(&o.Isin, &offerDate, &start, &end, &o.Facevalue, &o.Faceunit, &o.Price, &o.Value, &o.Agent, &o.Offertype)
+		if err != nil {
+			return nil, err
+		}
+		o.Offerdate = offerDate.Format(time.DateOnly)
+		if start.Valid {
+			o.OfferdateStart = start.Time.Format(time.DateOnly)
+		}
+		if end.Valid {
+			o.OfferdateEnd = end.Time.Format(time.DateOnly)
+		}
+		offers = append(offers, o)
+	}
+
+	return offers, rows.Err()
+}
+
+// CouponsBetween возвращает купоны всех облигаций с датой выплаты в интервале [from, to]
+func (r *PostgresRepo) CouponsBetween(from, to time.Time) ([]models.Coupon, error) {
+	return queryCoupons(r, `
+		SELECT isin, coupondate, recorddate, initialfacevalue, facevalue, faceunit, value, valueprc, value_rub
+		FROM coupons
+		WHERE coupondate BETWEEN $1 AND $2
+		ORDER BY coupondate, isin`, from, to)
+}
+
+// AmortizationsBetween возвращает амортизационные выплаты всех облигаций с датой в интервале [from, to]
+func (r *PostgresRepo) AmortizationsBetween(from, to time.Time) ([]models.Amortization, error) {
+	return queryAmortizations(r, `
+		SELECT isin, amortdate, facevalue, initialfacevalue, faceunit, value, value_rub
+		FROM amortizations
+		WHERE amortdate BETWEEN $1 AND $2
+		ORDER BY amortdate, isin`, from, to)
+}
+
+// BondizationUpdatedAt возвращает время последнего обновления графика выплат облигации.
+// Если график не загружался, возвращается нулевое время.
+func (r *PostgresRepo) BondizationUpdatedAt(isin string) (time.Time, error) {
+	var updatedAt time.Time
+	err := r.db.QueryRow("SELECT updated_at FROM bondization_updates WHERE isin = $1", isin).Scan(&updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return updatedAt, err
+}
+
+// UpdateBondization заменяет сохранённый график купонов, амортизаций и оферт облигации
+func (r *PostgresRepo) UpdateBondization(isin string, coupons []models.Coupon, amortizations []models.Amortization, offers []models.Offer) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM coupons WHERE isin = $1", isin); err != nil {
+		return err
+	}
+	for _, c := range coupons {
+		_, err := tx.Exec(`
+			INSERT INTO coupons (isin, coupondate, recorddate, initialfacevalue, facevalue, faceunit, value, valueprc, value_rub)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			isin, c.Coupondate, nullString(c.Recorddate), c.Initialfacevalue, c.Facevalue, c.Faceunit, c.Value, c.Valueprc, c.ValueRub)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM amortizations WHERE isin = $1", isin); err != nil {
+		return err
+	}
+	for _, a := range amortizations {
+		_, err := tx.Exec(`
+			INSERT INTO amortizations (isin, amortdate, facevalue, initialfacevalue, faceunit, value, value_rub)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			isin, a.Amortdate, a.Facevalue, a.Initialfacevalue, a.Faceunit, a.Value, a.ValueRub)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM offers WHERE isin = $1", isin); err != nil {
+		return err
+	}
+	for _, o := range offers {
+		_, err := tx.Exec(`
+			INSERT INTO offers (isin, offerdate, offerdatestart, offerdateend, facevalue, faceunit, price, value, agent, offertype)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			isin, o.Offerdate, nullString(o.OfferdateStart), nullString(o.OfferdateEnd), o.Facevalue, o.Faceunit, o.Price, o.Value,
+			o.Agent, o.Offertype)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO bondization_updates (isin, updated_at)
+		VALUES ($1, now())
+		ON CONFLICT (isin) DO UPDATE SET updated_at = EXCLUDED.updated_at`, isin)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func queryCoupons(r *PostgresRepo, query string, args ...any) ([]models.Coupon, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	coupons := []models.Coupon{}
+	for rows.Next() {
+		var c models.Coupon
+		var couponDate time.Time
+		var recordDate sql.NullTime
+		err := rows.Scan(&c.Isin, &couponDate, &recordDate, &c.Initialfacevalue, &c.Facevalue, &c.Faceunit, &c.Value, &c.Valueprc, &c.ValueRub)
+		if err != nil {
+			return nil, err
+		}
+		c.Coupondate = couponDate.Format(time.DateOnly)
+		if recordDate.Valid {
+			c.Recorddate = recordDate.Time.Format(time.DateOnly)
+		}
+		coupons = append(coupons, c)
+	}
+
+	return coupons, rows.Err()
+}
+
+func queryAmortizations(r *PostgresRepo, query string, args ...any) ([]models.Amortization, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	amortizations := []models.Amortization{}
+	for rows.Next() {
+		var a models.Amortization
+		err := rows.Scan(&a.Isin, &a.Date, &a.Facevalue, &a.Initialfacevalue, &a.Faceunit, &a.Value, &a.ValueRub)
+		if err != nil {
+			return nil, err
+		}
+		a.Amortdate = a.Date.Format(time.DateOnly)
+		amortizations = append(amortizations, a)
+	}
+
+	return amortizations, rows.Err()
+}
+
+// nullString преобразует пустую строку в NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
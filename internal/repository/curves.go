@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"simple-invest/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// GetZCYC возвращает параметры G-кривой на дату date, а при их отсутствии - на ближайшую предшествующую дату
+func (r *PostgresRepo) GetZCYC(date time.Time) (models.ZCYC, error) {
+	var c models.ZCYC
+	g := make([]float64, 0, len(c.G))
+	err := r.db.QueryRow(`
+		SELECT to_char(date, 'YYYY-MM-DD'), time, b1, b2, b3, t1, g
+		FROM zcyc
+		WHERE date <= $1
+		ORDER BY date DESC
+		LIMIT 1`, date).Scan(&c.Date, &c.Time, &c.B1, &c.B2, &c.B3, &c.T1, pq.Array(&g))
+	if errors.Is(err, sql.ErrNoRows) {
+		return c, ErrNotFound
+	}
+	copy(c.G[:], g)
+	return c, err
+}
+
+// UpdateZCYC сохраняет параметры G-кривой, заменяя ранее сохранённые на ту же дату
+func (r *PostgresRepo) UpdateZCYC(c models.ZCYC) error {
+	_, err := r.db.Exec(`
+		INSERT INTO zcyc (date, time, b1, b2, b3, t1, g)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (date) DO UPDATE
+		SET time = EXCLUDED.time, b1 = EXCLUDED.b1, b2 = EXCLUDED.b2, b3 = EXCLUDED.b3, t1 = EXCLUDED.t1, g = EXCLUDED.g`,
+		c.Date, c.Time, c.B1, c.B2, c.B3, c.T1, pq.Array(c.G[:]))
+	return err
+}
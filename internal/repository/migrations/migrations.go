@@ -0,0 +1,183 @@
+// Пакет migrations реализует версионные миграции схемы БД.
+//
+// Миграции хранятся в каталоге sql в виде файлов <версия>_<описание>.sql и встраиваются в исполняемый файл.
+// Применённые версии фиксируются в таблице schema_migrations.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const lockID = 7540 // Идентификатор advisory-блокировки на время применения миграций
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// ErrSchemaTooNew возвращается, если версия схемы БД новее, чем известная приложению
+var ErrSchemaTooNew = errors.New("database schema is newer than the application")
+
+type migration struct {
+	version int
+	name    string
+	query   string
+}
+
+// Latest возвращает последнюю известную приложению версию схемы
+func Latest() (int, error) {
+	migrations, err := load()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].version, nil
+}
+
+// Up применяет все неприменённые миграции и возвращает их количество.
+// Если схема БД новее, чем известная приложению, возвращается ErrSchemaTooNew.
+func Up(ctx context.Context, db *sql.DB) (int, error) {
+	migrations, err := load()
+	if err != nil {
+		return 0, err
+	}
+
+	// Блокировка исключает одновременное применение миграций несколькими экземплярами сервиса
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return 0, err
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID)
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations
+		(
+			version integer PRIMARY KEY,
+			name character varying(255) NOT NULL,
+			applied_at timestamp with time zone NOT NULL DEFAULT now()
+		)`); err != nil {
+		return 0, err
+	}
+
+	current, err := version(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	latest := 0
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].version
+	}
+	if current > latest {
+		return 0, fmt.Errorf("%w: schema version %d, application version %d", ErrSchemaTooNew, current, latest)
+	}
+
+	applied := 0
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := apply(ctx, conn, m); err != nil {
+			return applied, fmt.Errorf("migration %s: %w", m.name, err)
+		}
+		applied++
+	}
+
+	return applied, nil
+}
+
+// Version возвращает текущую версию схемы БД
+func Version(ctx context.Context, db *sql.DB) (int, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	var exists bool
+	err = conn.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists)
+	if err != nil || !exists {
+		return 0, err
+	}
+	return version(ctx, conn)
+}
+
+func version(ctx context.Context, conn *sql.Conn) (int, error) {
+	var v int
+	err := conn.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&v)
+	return v, err
+}
+
+func apply(ctx context.Context, conn *sql.Conn, m migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, m.query); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", m.version, m.name); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// load читает встроенные миграции, упорядоченные по версии
+func load() ([]migration, error) {
+	entries, err := files.ReadDir("sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]migration, 0, len(entries))
+	seen := make(map[int]string)
+	for _, e := range entries {
+		v, err := parseVersion(e.Name())
+		if err != nil {
+			return nil, err
+		}
+		if prev, ok := seen[v]; ok {
+			return nil, fmt.Errorf("duplicate migration version %d: %s, %s", v, prev, e.Name())
+		}
+		seen[v] = e.Name()
+
+		query, err := files.ReadFile(path.Join("sql", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, migration{version: v, name: e.Name(), query: string(query)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	return migrations, nil
+}
+
+// parseVersion извлекает версию из имени файла вида 0001_description.sql
+func parseVersion(name string) (int, error) {
+	prefix, _, ok := strings.Cut(name, "_")
+	if !ok || !strings.HasSuffix(name, ".sql") {
+		return 0, fmt.Errorf("incorrect migration file name %q", name)
+	}
+	v, err := strconv.Atoi(prefix)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("incorrect migration version in %q", name)
+	}
+	return v, nil
+}
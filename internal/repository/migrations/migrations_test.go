@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		want    int
+		wantErr bool
+	}{
+		{name: "correct name", file: "0002_securities_market.sql", want: 2},
+		{name: "no description", file: "0002.sql", wantErr: true},
+		{name: "not sql", file: "0002_securities.txt", wantErr: true},
+		{name: "not a number", file: "v2_securities.sql", wantErr: true},
+		{name: "zero version", file: "0000_securities.sql", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseVersion(tt.file)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_load(t *testing.T) {
+	migrations, err := load()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	// Версии встроенных миграций идут подряд начиная с 1
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.version, m.name)
+		assert.NotEmpty(t, m.query, m.name)
+	}
+}
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS securities
+(
+    id int PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    isin character(12) NOT NULL,
+    ticker character varying(12) NOT NULL,
+    lotsize integer NOT NULL,
+    board character varying(6) NOT NULL,
+    sectype character varying(2) NOT NULL,
+    instrument character varying(6) NOT NULL
+);
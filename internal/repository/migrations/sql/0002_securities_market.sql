@@ -0,0 +1,9 @@
+ALTER TABLE securities ADD COLUMN IF NOT EXISTS market character varying(12);
+
+UPDATE securities
+SET market = CASE WHEN board = 'TQBR' THEN 'shares' ELSE 'bonds' END
+WHERE market IS NULL;
+
+ALTER TABLE securities ALTER COLUMN market SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS securities_market_idx ON securities (market);
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS coupons
+(
+    isin character varying(12) NOT NULL,
+    coupondate date NOT NULL,
+    recorddate date,
+    initialfacevalue double precision NOT NULL,
+    facevalue double precision NOT NULL,
+    faceunit character varying(4) NOT NULL,
+    value double precision NOT NULL,
+    valueprc double precision NOT NULL,
+    value_rub double precision NOT NULL,
+    PRIMARY KEY (isin, coupondate)
+);
+
+CREATE INDEX IF NOT EXISTS coupons_coupondate_idx ON coupons (coupondate);
+
+CREATE TABLE IF NOT EXISTS amortizations
+(
+    isin character varying(12) NOT NULL,
+    amortdate date NOT NULL,
+    facevalue double precision NOT NULL,
+    initialfacevalue double precision NOT NULL,
+    faceunit character varying(4) NOT NULL,
+    value double precision NOT NULL,
+    value_rub double precision NOT NULL,
+    PRIMARY KEY (isin, amortdate)
+);
+
+CREATE INDEX IF NOT EXISTS amortizations_amortdate_idx ON amortizations (amortdate);
+
+CREATE TABLE IF NOT EXISTS bondization_updates
+(
+    isin character varying(12) PRIMARY KEY,
+    updated_at timestamp with time zone NOT NULL
+);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS indicator_snapshots
+(
+    isin character varying(12) PRIMARY KEY,
+    computed_at timestamp with time zone NOT NULL,
+    data jsonb NOT NULL
+);
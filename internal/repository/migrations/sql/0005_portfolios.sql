@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS portfolios
+(
+    id bigint PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    name character varying(100) NOT NULL,
+    created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS accounts
+(
+    id bigint PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    portfolio_id bigint NOT NULL REFERENCES portfolios (id) ON DELETE CASCADE,
+    name character varying(100) NOT NULL,
+    broker character varying(100) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS accounts_portfolio_id_idx ON accounts (portfolio_id);
+
+CREATE TABLE IF NOT EXISTS trades
+(
+    id bigint PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    account_id bigint NOT NULL REFERENCES accounts (id) ON DELETE CASCADE,
+    isin character varying(12) NOT NULL DEFAULT '',
+    type character varying(12) NOT NULL
+        CHECK (type IN ('buy', 'sell', 'coupon', 'dividend', 'amortization', 'fee')),
+    date date NOT NULL,
+    quantity bigint NOT NULL DEFAULT 0,
+    price double precision NOT NULL DEFAULT 0,
+    accruedint double precision NOT NULL DEFAULT 0,
+    amount double precision NOT NULL DEFAULT 0,
+    fee double precision NOT NULL DEFAULT 0,
+    currency character varying(4) NOT NULL DEFAULT 'RUB',
+    comment text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS trades_account_id_idx ON trades (account_id, date);
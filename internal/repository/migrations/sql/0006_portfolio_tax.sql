@@ -0,0 +1 @@
+ALTER TABLE portfolios ADD COLUMN IF NOT EXISTS non_resident boolean NOT NULL DEFAULT false;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS zcyc
+(
+    date date PRIMARY KEY,
+    time character varying(8) NOT NULL DEFAULT '',
+    b1 double precision NOT NULL,
+    b2 double precision NOT NULL,
+    b3 double precision NOT NULL,
+    t1 double precision NOT NULL,
+    g double precision[] NOT NULL
+);
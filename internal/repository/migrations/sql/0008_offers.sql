@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS offers
+(
+    isin character varying(12) NOT NULL,
+    offerdate date NOT NULL,
+    offerdatestart date,
+    offerdateend date,
+    facevalue double precision NOT NULL,
+    faceunit character varying(4) NOT NULL,
+    price double precision NOT NULL,
+    value double precision NOT NULL,
+    agent text NOT NULL DEFAULT '',
+    offertype text NOT NULL DEFAULT '',
+    PRIMARY KEY (isin, offerdate)
+);
+
+-- Графики выплат загружаются повторно вместе с офертами
+DELETE FROM bondization_updates;
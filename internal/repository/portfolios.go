@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"simple-invest/internal/models"
+)
+
+// GetPortfolios возвращает все портфели
+func (r *PostgresRepo) GetPortfolios() ([]models.Portfolio, error) {
+	rows, err := r.db.Query("SELECT id, name, non_resident, created_at FROM portfolios ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	portfolios := []models.Portfolio{}
+	for rows.Next() {
+		var p models.Portfolio
+		if err := rows.Scan(&p.ID, &p.Name, &p.NonResident, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		portfolios = append(portfolios, p)
+	}
+
+	return portfolios, rows.Err()
+}
+
+// GetPortfolio возвращает портфель по идентификатору
+func (r *PostgresRepo) GetPortfolio(id int64) (models.Portfolio, error) {
+	var p models.Portfolio
+	err := r.db.QueryRow("SELECT id, name, non_resident, created_at FROM portfolios WHERE id = $1", id).
+		Scan(&p.ID, &p.Name, &p.NonResident, &p.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, ErrNotFound
+	}
+	return p, err
+}
+
+// CreatePortfolio создаёт портфель и возвращает его с присвоенным идентификатором
+func (r *PostgresRepo) CreatePortfolio(p models.Portfolio) (models.Portfolio, error) {
+	err := r.db.QueryRow(`
+		INSERT INTO portfolios (name, non_resident)
+		VALUES ($1, $2)
+		RETURNING id, created_at`, p.Name, p.NonResident).Scan(&p.ID, &p.CreatedAt)
+	return p, err
+}
+
+// UpdatePortfolio изменяет наименование и налоговые настройки портфеля
+func (r *PostgresRepo) UpdatePortfolio(p models.Portfolio) error {
+	res, err := r.db.Exec("UPDATE portfolios SET name = $2, non_resident = $3 WHERE id = $1", p.ID, p.Name, p.NonResident)
+	return checkAffected(res, err)
+}
+
+// DeletePortfolio удаляет портфель вместе со счетами и операциями
+func (r *PostgresRepo) DeletePortfolio(id int64) error {
+	res, err := r.db.Exec("DELETE FROM portfolios WHERE id = $1", id)
+	return checkAffected(res, err)
+}
+
+// GetAccounts возвращает счета портфеля
+func (r *PostgresRepo) GetAccounts(portfolioID int64) ([]models.Account, error) {
+	rows, err := r.db.Query(`
+		SELECT id, portfolio_id, name, broker
+		FROM accounts
+		WHERE portfolio_id = $1
+		ORDER BY id`, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []models.Account{}
+	for rows.Next() {
+		var a models.Account
+		if err := rows.Scan(&a.ID, &a.PortfolioID, &a.Name, &a.Broker); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+
+	return accounts, rows.Err()
+}
+
+// CreateAccount создаёт счёт и возвращает его с присвоенным идентификатором
+func (r *PostgresRepo) CreateAccount(a models.Account) (models.Account, error) {
+	err := r.db.QueryRow(`
+		INSERT INTO accounts (portfolio_id, name, broker)
+		VALUES ($1, $2, $3)
+		RETURNING id`, a.PortfolioID, a.Name, a.Broker).Scan(&a.ID)
+	return a, err
+}
+
+// UpdateAccount изменяет наименование и брокера счёта
+func (r *PostgresRepo) UpdateAccount(a models.Account) error {
+	res, err := r.db.Exec(`
+		UPDATE accounts
+		SET name = $3,
+			broker = $4
+		WHERE id = $1 AND portfolio_id = $2`, a.ID, a.PortfolioID, a.Name, a.Broker)
+	return checkAffected(res, err)
+}
+
+// DeleteAccount удаляет счёт портфеля вместе с операциями, если оставшиеся операции проходят проверку check
+func (r *PostgresRepo) DeleteAccount(portfolioID, id int64, check LedgerCheck) error {
+	return r.changeLedger(portfolioID, check, func(tx *sql.Tx) error {
+		res, err := tx.Exec("DELETE FROM accounts WHERE id = $1 AND portfolio_id = $2", id, portfolioID)
+		return checkAffected(res, err)
+	})
+}
+
+// GetTrades возвращает операции по всем счетам портфеля в хронологическом порядке
+func (r *PostgresRepo) GetTrades(portfolioID int64) ([]models.Trade, error) {
+	return getTrades(r.db, portfolioID)
+}
+
+// queryer - метод чтения, общий для *sql.DB и *sql.Tx
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func getTrades(q queryer, portfolioID int64) ([]models.Trade, error) {
+	rows, err := q.Query(`
+		SELECT t.id, t.account_id, t.isin, t.type, to_char(t.date, 'YYYY-MM-DD'), t.quantity,
+			t.price, t.accruedint, t.amount, t.fee, t.currency, t.comment
+		FROM trades t
+		JOIN accounts a ON a.id = t.account_id
+		WHERE a.portfolio_id = $1
+		ORDER BY t.date, t.id`, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trades := []models.Trade{}
+	for rows.Next() {
+		var t models.Trade
+		err := rows.Scan(&t.ID, &t.AccountID, &t.Isin, &t.Type, &t.Date, &t.Quantity,
+			&t.Price, &t.AccruedInt, &t.Amount, &t.Fee, &t.Currency, &t.Comment)
+		if err != nil {
+			return nil, err
+		}
+		trades = append(trades, t)
+	}
+
+	return trades, rows.Err()
+}
+
+// CreateTrade создаёт операцию, если операции портфеля с ней проходят проверку check,
+// и возвращает её с присвоенным идентификатором
+func (r *PostgresRepo) CreateTrade(portfolioID int64, t models.Trade, check LedgerCheck) (models.Trade, error) {
+	err := r.changeLedger(portfolioID, check, func(tx *sql.Tx) error {
+		return tx.QueryRow(`
+			INSERT INTO trades (account_id, isin, type, date, quantity, price, accruedint, amount, fee, currency, comment)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			RETURNING id`,
+			t.AccountID, t.Isin, t.Type, t.Date, t.Quantity, t.Price, t.AccruedInt, t.Amount, t.Fee, t.Currency, t.Comment).
+			Scan(&t.ID)
+	})
+	return t, err
+}
+
+// UpdateTrade изменяет операцию по счёту портфеля, если операции портфеля после изменения проходят проверку check
+func (r *PostgresRepo) UpdateTrade(portfolioID int64, t models.Trade, check LedgerCheck) error {
+	return r.changeLedger(portfolioID, check, func(tx *sql.Tx) error {
+		res, err := tx.Exec(`
+			UPDATE trades t
+			SET account_id = $3,
+				isin = $4,
+				type = $5,
+				date = $6,
+				quantity = $7,
+				price = $8,
+				accruedint = $9,
+				amount = $10,
+				fee = $11,
+				currency = $12,
+				comment = $13
+			FROM accounts a
+			WHERE t.id = $1 AND a.id = t.account_id AND a.portfolio_id = $2`,
+			t.ID, portfolioID, t.AccountID, t.Isin, t.Type, t.Date, t.Quantity, t.Price, t.AccruedInt, t.Amount, t.Fee,
+			t.Currency, t.Comment)
+		return checkAffected(res, err)
+	})
+}
+
+// DeleteTrade удаляет операцию по счёту портфеля, если оставшиеся операции проходят проверку check
+func (r *PostgresRepo) DeleteTrade(portfolioID, id int64, check LedgerCheck) error {
+	return r.changeLedger(portfolioID, check, func(tx *sql.Tx) error {
+		res, err := tx.Exec(`
+			DELETE FROM trades t
+			USING accounts a
+			WHERE t.id = $1 AND a.id = t.account_id AND a.portfolio_id = $2`, id, portfolioID)
+		return checkAffected(res, err)
+	})
+}
+
+// changeLedger выполняет изменение операций портфеля change и проверку check в одной транзакции.
+// Строка портфеля блокируется, поэтому параллельные изменения операций портфеля проверяются по очереди.
+func (r *PostgresRepo) changeLedger(portfolioID int64, check LedgerCheck, change func(tx *sql.Tx) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRow("SELECT id FROM portfolios WHERE id = $1 FOR UPDATE", portfolioID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := change(tx); err != nil {
+		return err
+	}
+	trades, err := getTrades(tx, portfolioID)
+	if err != nil {
+		return err
+	}
+	if err := check(trades); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// checkAffected возвращает ErrNotFound, если запрос не изменил ни одной записи
+func checkAffected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
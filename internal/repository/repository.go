@@ -2,6 +2,9 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
+	"simple-invest/internal/models"
+	"time"
 
 	"github.com/WLM1ke/gomoex"
 	_ "github.com/lib/pq"
@@ -12,8 +15,46 @@ type Repository interface {
 	GetBonds() ([]gomoex.Security, error)
 	UpdateShares([]gomoex.Security) (int, error)
 	UpdateBonds([]gomoex.Security) (int, error)
+
+	GetCoupons(isin string) ([]models.Coupon, error)
+	GetAmortizations(isin string) ([]models.Amortization, error)
+	GetOffers(isin string) ([]models.Offer, error)
+	CouponsBetween(from, to time.Time) ([]models.Coupon, error)
+	AmortizationsBetween(from, to time.Time) ([]models.Amortization, error)
+	BondizationUpdatedAt(isin string) (time.Time, error)
+	UpdateBondization(isin string, coupons []models.Coupon, amortizations []models.Amortization, offers []models.Offer) error
+
+	GetIndicatorSnapshots() ([]models.IndicatorSnapshot, error)
+	UpdateIndicatorSnapshots([]models.IndicatorSnapshot) (int, error)
+
+	GetSecurity(isin string) (sec gomoex.Security, market string, err error)
+
+	GetZCYC(date time.Time) (models.ZCYC, error)
+	UpdateZCYC(c models.ZCYC) error
+
+	GetPortfolios() ([]models.Portfolio, error)
+	GetPortfolio(id int64) (models.Portfolio, error)
+	CreatePortfolio(p models.Portfolio) (models.Portfolio, error)
+	UpdatePortfolio(p models.Portfolio) error
+	DeletePortfolio(id int64) error
+
+	GetAccounts(portfolioID int64) ([]models.Account, error)
+	CreateAccount(a models.Account) (models.Account, error)
+	UpdateAccount(a models.Account) error
+	DeleteAccount(portfolioID, id int64, check LedgerCheck) error
+
+	GetTrades(portfolioID int64) ([]models.Trade, error)
+	CreateTrade(portfolioID int64, t models.Trade, check LedgerCheck) (models.Trade, error)
+	UpdateTrade(portfolioID int64, t models.Trade, check LedgerCheck) error
+	DeleteTrade(portfolioID, id int64, check LedgerCheck) error
 }
 
+// LedgerCheck проверяет операции портфеля после изменения. Ошибка проверки отменяет изменение.
+type LedgerCheck func(trades []models.Trade) error
+
+// ErrNotFound возвращается, если запись отсутствует в БД
+var ErrNotFound = errors.New("not found")
+
 // Реализация PostgreSQL
 type PostgresRepo struct {
 	db *sql.DB
@@ -24,29 +65,44 @@ func NewPostgresRepo(db *sql.DB) *PostgresRepo {
 }
 
 func (r *PostgresRepo) GetShares() ([]gomoex.Security, error) {
-	rows, err := r.db.Query("SELECT ticker, lotsize, isin, board, instrument FROM securities")
+	return getSecurities(r, gomoex.MarketShares)
+}
 
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+func (r *PostgresRepo) GetBonds() ([]gomoex.Security, error) {
+	return getSecurities(r, gomoex.MarketBonds)
+}
 
-	secs := []gomoex.Security{}
-	for rows.Next() {
-		s := gomoex.Security{}
-		err := rows.Scan(&s.Ticker, &s.LotSize, &s.ISIN, &s.Board, &s.Instrument)
-		if err != nil {
-			return nil, err
-		}
-		secs = append(secs, s)
-	}
+func (r *PostgresRepo) UpdateShares(secs []gomoex.Security) (int, error) {
+	n, err := updateSecurities(r, gomoex.MarketShares, secs)
+	return n, err
+}
 
-	return secs, err
+func (r *PostgresRepo) UpdateBonds(secs []gomoex.Security) (int, error) {
+	n, err := updateSecurities(r, gomoex.MarketBonds, secs)
+	return n, err
 }
 
-func (r *PostgresRepo) GetBonds() ([]gomoex.Security, error) {
-	rows, err := r.db.Query("SELECT ticker, lotsize, isin, board, instrument FROM securities")
+// GetSecurity возвращает ценную бумагу по ISIN и её рынок (акции или облигации)
+func (r *PostgresRepo) GetSecurity(isin string) (gomoex.Security, string, error) {
+	s := gomoex.Security{}
+	var market string
+	err := r.db.QueryRow(`
+		SELECT ticker, lotsize, isin, board, sectype, instrument, market
+		FROM securities
+		WHERE isin = $1`, isin).Scan(&s.Ticker, &s.LotSize, &s.ISIN, &s.Board, &s.Type, &s.Instrument, &market)
+	if errors.Is(err, sql.ErrNoRows) {
+		return s, "", ErrNotFound
+	}
+	return s, market, err
+}
 
+// getSecurities возвращает ценные бумаги указанного рынка (акции или облигации)
+func getSecurities(r *PostgresRepo, market string) ([]gomoex.Security, error) {
+	rows, err := r.db.Query(`
+		SELECT ticker, lotsize, isin, board, sectype, instrument
+		FROM securities
+		WHERE market = $1
+		ORDER BY ticker`, market)
 	if err != nil {
 		return nil, err
 	}
@@ -55,33 +111,24 @@ func (r *PostgresRepo) GetBonds() ([]gomoex.Security, error) {
 	secs := []gomoex.Security{}
 	for rows.Next() {
 		s := gomoex.Security{}
-		err := rows.Scan(&s.Ticker, &s.LotSize, &s.ISIN, &s.Board, &s.Instrument)
+		err := rows.Scan(&s.Ticker, &s.LotSize, &s.ISIN, &s.Board, &s.Type, &s.Instrument)
 		if err != nil {
 			return nil, err
 		}
 		secs = append(secs, s)
 	}
 
-	return secs, err
+	return secs, rows.Err()
 }
 
-func (r *PostgresRepo) UpdateShares(secs []gomoex.Security) (int, error) {
-	n, err := updateSecurities(r, secs)
-	return n, err
-}
-
-func (r *PostgresRepo) UpdateBonds(secs []gomoex.Security) (int, error) {
-	n, err := updateSecurities(r, secs)
-	return n, err
-}
-
-func updateSecurities(r *PostgresRepo, secs []gomoex.Security) (int, error) {
-	// securityType string, - добавить входной параметр в функцию для фильтрации ЦБ
+// updateSecurities обновляет ценные бумаги рынка market, добавляя отсутствующие в БД
+func updateSecurities(r *PostgresRepo, market string, secs []gomoex.Security) (int, error) {
 	existing := make(map[string]bool)
 	rows, err := r.db.Query("SELECT isin FROM securities")
 	if err != nil {
 		return 0, err
 	}
+	defer rows.Close()
 	for rows.Next() {
 		var isin string
 		err = rows.Scan(&isin)
@@ -102,16 +149,17 @@ func updateSecurities(r *PostgresRepo, secs []gomoex.Security) (int, error) {
 				lotsize = $3,
 				board = $4,
 				sectype = $5,
-				instrument = $6
-			WHERE isin = $1;`, s.ISIN, s.Ticker, s.LotSize, s.Board, s.Type, s.Instrument)
+				instrument = $6,
+				market = $7
+			WHERE isin = $1;`, s.ISIN, s.Ticker, s.LotSize, s.Board, s.Type, s.Instrument, market)
 			if err != nil {
 				return updated, err
 			}
 		} else {
 			// тут создание
 			_, err := r.db.Exec(`
-				INSERT INTO securities (isin, ticker, lotsize, board, sectype, instrument)
-				VALUES ($1, $2, $3, $4, $5, $6)`, s.ISIN, s.Ticker, s.LotSize, s.Board, s.Type, s.Instrument)
+				INSERT INTO securities (isin, ticker, lotsize, board, sectype, instrument, market)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)`, s.ISIN, s.Ticker, s.LotSize, s.Board, s.Type, s.Instrument, market)
 			if err != nil {
 				return updated, err
 			}
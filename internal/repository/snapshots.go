@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"simple-invest/internal/models"
+)
+
+// GetIndicatorSnapshots возвращает сохранённые показатели всех облигаций
+func (r *PostgresRepo) GetIndicatorSnapshots() ([]models.IndicatorSnapshot, error) {
+	rows, err := r.db.Query("SELECT isin, computed_at, data FROM indicator_snapshots ORDER BY isin")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := []models.IndicatorSnapshot{}
+	for rows.Next() {
+		var s models.IndicatorSnapshot
+		if err := rows.Scan(&s.Isin, &s.ComputedAt, &s.Data); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, s)
+	}
+
+	return snapshots, rows.Err()
+}
+
+// UpdateIndicatorSnapshots заменяет сохранённые показатели облигаций набором snapshots.
+// Показатели облигаций, отсутствующих в snapshots, удаляются.
+func (r *PostgresRepo) UpdateIndicatorSnapshots(snapshots []models.IndicatorSnapshot) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM indicator_snapshots"); err != nil {
+		return 0, err
+	}
+	for _, s := range snapshots {
+		_, err := tx.Exec(`
+			INSERT INTO indicator_snapshots (isin, computed_at, data)
+			VALUES ($1, $2, $3)`,
+			s.Isin, s.ComputedAt, []byte(s.Data))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(snapshots), tx.Commit()
+}
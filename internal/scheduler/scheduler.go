@@ -0,0 +1,145 @@
+// Пакет scheduler реализует периодический запуск фоновых задач сервиса.
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Job описывает периодическую задачу
+type Job struct {
+	Name     string                          // Наименование задачи
+	Interval time.Duration                   // Интервал запуска, нулевой интервал отключает задачу
+	Run      func(ctx context.Context) error // Выполняемая функция
+}
+
+// Status содержит сведения о последнем запуске задачи
+type Status struct {
+	Name       string    `json:"name"`                 // Наименование задачи
+	Interval   string    `json:"interval"`             // Интервал запуска
+	Running    bool      `json:"running"`              // Задача выполняется
+	Runs       int       `json:"runs"`                 // Количество запусков
+	LastStart  time.Time `json:"last_start"`           // Время начала последнего запуска
+	LastFinish time.Time `json:"last_finish"`          // Время окончания последнего запуска
+	LastError  string    `json:"last_error,omitempty"` // Ошибка последнего запуска
+	NextStart  time.Time `json:"next_start,omitempty"` // Время следующего запуска
+}
+
+// Scheduler запускает задачи с заданными интервалами
+type Scheduler struct {
+	log    *slog.Logger
+	jobs   []Job
+	mu     sync.Mutex
+	status map[string]*Status
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New создаёт планировщик. Задачи с неположительным интервалом не запускаются.
+func New(log *slog.Logger, jobs ...Job) *Scheduler {
+	s := &Scheduler{
+		log:    log,
+		status: make(map[string]*Status),
+	}
+	for _, j := range jobs {
+		if j.Interval <= 0 {
+			log.Info("scheduler job disabled", slog.String("job", j.Name))
+			continue
+		}
+		s.jobs = append(s.jobs, j)
+		s.status[j.Name] = &Status{Name: j.Name, Interval: j.Interval.String()}
+	}
+	return s
+}
+
+// Start запускает задачи. Первый запуск каждой задачи выполняется сразу.
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go func(j Job) {
+			defer s.wg.Done()
+			s.loop(ctx, j)
+		}(j)
+	}
+}
+
+// Stop останавливает планировщик и ожидает завершения выполняемых задач, но не дольше срока ctx
+func (s *Scheduler) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Status возвращает сведения о задачах в порядке их регистрации
+func (s *Scheduler) Status() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		statuses = append(statuses, *s.status[j.Name])
+	}
+	return statuses
+}
+
+func (s *Scheduler) loop(ctx context.Context, j Job) {
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.run(ctx, j)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, j Job) {
+	s.mu.Lock()
+	st := s.status[j.Name]
+	st.Running = true
+	st.LastStart = time.Now()
+	s.mu.Unlock()
+
+	err := j.Run(ctx)
+
+	s.mu.Lock()
+	st.Running = false
+	st.Runs++
+	st.LastFinish = time.Now()
+	st.NextStart = st.LastStart.Add(j.Interval)
+	st.LastError = ""
+	if err != nil {
+		st.LastError = err.Error()
+	}
+	elapsed := st.LastFinish.Sub(st.LastStart)
+	s.mu.Unlock()
+
+	if err != nil {
+		s.log.Error("scheduler job failed", slog.String("job", j.Name), slog.String("error", err.Error()))
+		return
+	}
+	s.log.Info("scheduler job finished", slog.String("job", j.Name), slog.Duration("duration", elapsed))
+}
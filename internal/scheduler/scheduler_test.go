@@ -0,0 +1,52 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	var runs atomic.Int32
+	s := New(log,
+		Job{Name: "ok", Interval: time.Millisecond * 10, Run: func(ctx context.Context) error {
+			runs.Add(1)
+			return nil
+		}},
+		Job{Name: "failing", Interval: time.Hour, Run: func(ctx context.Context) error {
+			return errors.New("moex unavailable")
+		}},
+		Job{Name: "disabled", Run: func(ctx context.Context) error {
+			t.Error("disabled job started")
+			return nil
+		}},
+	)
+
+	s.Start()
+	require.Eventually(t, func() bool { return runs.Load() >= 3 }, time.Second, time.Millisecond*5)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, s.Stop(ctx))
+
+	statuses := s.Status()
+	require.Len(t, statuses, 2)
+
+	assert.Equal(t, "ok", statuses[0].Name)
+	assert.Equal(t, int(runs.Load()), statuses[0].Runs)
+	assert.Empty(t, statuses[0].LastError)
+	assert.False(t, statuses[0].Running)
+
+	assert.Equal(t, "failing", statuses[1].Name)
+	assert.Equal(t, 1, statuses[1].Runs)
+	assert.Equal(t, "moex unavailable", statuses[1].LastError)
+}
@@ -0,0 +1,106 @@
+package securities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	defaultBatchWorkers = 8    // Количество одновременных расчётов по умолчанию
+	maxBatchSize        = 3000 // Максимальное количество облигаций в пакетном запросе
+)
+
+// ErrIncorrectBatch возвращается при пустом пакетном запросе или превышении его максимального размера
+var ErrIncorrectBatch = errors.New("incorrect batch")
+
+// BondIndicatorsResult содержит результат расчёта показателей облигации в пакетном запросе
+type BondIndicatorsResult struct {
+	Isin       string          `json:"isin"`                 // Ценная бумага
+	Indicators *bondIndicators `json:"indicators,omitempty"` // Показатели облигации
+	Error      string          `json:"error,omitempty"`      // Ошибка расчёта
+}
+
+// BatchBondIndicators рассчитывает показатели облигаций isins и всех сохранённых облигаций режима торгов board
+// с параметрами расчёта opts.
+// Ошибка расчёта отдельной облигации не прерывает обработку остальных и возвращается в её результате.
+func (s *SecuritiesService) BatchBondIndicators(ctx context.Context, isins []string, board string, opts IndicatorOptions) ([]BondIndicatorsResult, error) {
+	if board != "" {
+		bonds, err := s.repo.GetBonds()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrStorage, err)
+		}
+		for _, b := range bonds {
+			if b.Board == board {
+				isins = append(isins, b.ISIN)
+			}
+		}
+	}
+
+	isins = uniqueISINs(isins)
+	if len(isins) == 0 {
+		return nil, fmt.Errorf("%w: no bonds to calculate", ErrIncorrectBatch)
+	}
+	if len(isins) > maxBatchSize {
+		return nil, fmt.Errorf("%w: too many bonds: %d, maximum %d", ErrIncorrectBatch, len(isins), maxBatchSize)
+	}
+
+	return s.bondIndicatorsResults(ctx, isins, opts), nil
+}
+
+// bondIndicatorsResults рассчитывает показатели облигаций isins параллельно в BatchWorkers потоков.
+// Результаты возвращаются в порядке isins.
+func (s *SecuritiesService) bondIndicatorsResults(ctx context.Context, isins []string, opts IndicatorOptions) []BondIndicatorsResult {
+	results := make([]BondIndicatorsResult, len(isins))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(s.opts.BatchWorkers, len(isins)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = s.bondIndicatorsResult(ctx, isins[i], opts)
+			}
+		}()
+	}
+
+	for i := range isins {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+func (s *SecuritiesService) bondIndicatorsResult(ctx context.Context, isin string, opts IndicatorOptions) BondIndicatorsResult {
+	res := BondIndicatorsResult{Isin: isin}
+	if err := ctx.Err(); err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	bI, err := s.BondIndicators(ctx, isin, opts)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Indicators = &bI
+	return res
+}
+
+// uniqueISINs удаляет пустые и повторяющиеся коды, сохраняя порядок
+func uniqueISINs(isins []string) []string {
+	seen := make(map[string]bool, len(isins))
+	unique := make([]string, 0, len(isins))
+	for _, isin := range isins {
+		if isin == "" || seen[isin] {
+			continue
+		}
+		seen[isin] = true
+		unique = append(unique, isin)
+	}
+	return unique
+}
@@ -0,0 +1,119 @@
+package securities
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+const defaultBondizationMaxAge = time.Hour * 24 // Срок актуальности сохранённого графика выплат по умолчанию
+
+// Payments содержит выплаты по облигациям за период
+type Payments struct {
+	Coupons       []Coupon       `json:"coupons"`       // Купоны
+	Amortizations []Amortization `json:"amortizations"` // Амортизационные выплаты
+}
+
+// bondization возвращает график купонов и амортизаций облигации из БД.
+// График загружается с Мосбиржи, если он отсутствует в БД, устарел или указан признак update.
+// При недоступности Мосбиржи используется ранее сохранённый график.
+func (s *SecuritiesService) bondization(ctx context.Context, isin string, update bool) ([]Coupon, []Amortization, error) {
+	updatedAt, err := s.repo.BondizationUpdatedAt(isin)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrStorage, err)
+	}
+
+	if update || updatedAt.IsZero() || time.Since(updatedAt) > s.opts.BondizationMaxAge {
+		coupons, amortizations, err := s.downloadBondization(ctx, isin)
+		if err == nil {
+			return coupons, amortizations, nil
+		}
+		if updatedAt.IsZero() {
+			return nil, nil, err
+		}
+		log.Printf("bondization %s: using stored data updated at %s: %s", isin, updatedAt.Format(time.DateTime), err)
+	}
+
+	coupons, err := s.repo.GetCoupons(isin)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrStorage, err)
+	}
+	amortizations, err := s.repo.GetAmortizations(isin)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrStorage, err)
+	}
+
+	return coupons, amortizations, nil
+}
+
+// downloadBondization получает график выплат и оферт облигации от Мосбиржи и сохраняет в БД
+func (s *SecuritiesService) downloadBondization(ctx context.Context, isin string) ([]Coupon, []Amortization, error) {
+	coupons, err := s.md.Coupons(ctx, isin)
+	if err != nil {
+		return nil, nil, err
+	}
+	amortizations, err := s.md.Amortizations(ctx, isin)
+	if err != nil {
+		return nil, nil, err
+	}
+	offers, err := s.md.Offers(ctx, isin)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.repo.UpdateBondization(isin, coupons, amortizations, offers); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrStorage, err)
+	}
+
+	return coupons, amortizations, nil
+}
+
+// RefreshBondization обновляет сохранённый график выплат облигации, если он устарел
+func (s *SecuritiesService) RefreshBondization(ctx context.Context, isin string) error {
+	_, _, err := s.bondization(ctx, isin, false)
+	return err
+}
+
+// RefreshBondizations обновляет устаревшие графики выплат всех сохранённых облигаций
+func (s *SecuritiesService) RefreshBondizations(ctx context.Context) error {
+	bonds, err := s.repo.GetBonds()
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	var lastErr error
+	for _, b := range bonds {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.RefreshBondization(ctx, b.ISIN); err != nil {
+			failed++
+			lastErr = fmt.Errorf("%s: %w", b.ISIN, err)
+		}
+	}
+
+	log.Printf("Bondizations refreshed: %d, failed: %d", len(bonds)-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("bondization refresh failed for %d of %d bonds, last error: %w", failed, len(bonds), lastErr)
+	}
+	return nil
+}
+
+// Payments возвращает сохранённые в БД выплаты по всем облигациям за период [from, to]
+func (s *SecuritiesService) Payments(from, to time.Time) (Payments, error) {
+	var p Payments
+	var err error
+
+	p.Coupons, err = s.repo.CouponsBetween(from, to)
+	if err != nil {
+		return p, err
+	}
+	p.Amortizations, err = s.repo.AmortizationsBetween(from, to)
+	if err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
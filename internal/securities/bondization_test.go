@@ -0,0 +1,136 @@
+package securities
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"simple-invest/internal/models"
+	"simple-invest/internal/repository"
+	"simple-invest/internal/securities/moextest"
+
+	"github.com/WLM1ke/gomoex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memRepo хранит данные в памяти. Нереализованные методы Repository вызывают панику.
+type memRepo struct {
+	repository.Repository
+	mu            sync.Mutex
+	shares        []gomoex.Security
+	bonds         []gomoex.Security
+	coupons       map[string][]Coupon
+	amortizations map[string][]Amortization
+	offers        map[string][]Offer
+	updatedAt     map[string]time.Time
+	snapshots     []models.IndicatorSnapshot
+	zcyc          *models.ZCYC
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{
+		coupons:       make(map[string][]Coupon),
+		amortizations: make(map[string][]Amortization),
+		offers:        make(map[string][]Offer),
+		updatedAt:     make(map[string]time.Time),
+	}
+}
+
+func (r *memRepo) GetShares() ([]gomoex.Security, error) { return r.shares, nil }
+func (r *memRepo) GetBonds() ([]gomoex.Security, error)  { return r.bonds, nil }
+
+func (r *memRepo) GetSecurity(isin string) (gomoex.Security, string, error) {
+	for _, s := range r.shares {
+		if s.ISIN == isin {
+			return s, gomoex.MarketShares, nil
+		}
+	}
+	for _, b := range r.bonds {
+		if b.ISIN == isin {
+			return b, gomoex.MarketBonds, nil
+		}
+	}
+	return gomoex.Security{}, "", repository.ErrNotFound
+}
+
+func (r *memRepo) GetCoupons(isin string) ([]Coupon, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.coupons[isin], nil
+}
+
+func (r *memRepo) GetAmortizations(isin string) ([]Amortization, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.amortizations[isin], nil
+}
+
+func (r *memRepo) GetOffers(isin string) ([]Offer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.offers[isin], nil
+}
+
+func (r *memRepo) BondizationUpdatedAt(isin string) (time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.updatedAt[isin], nil
+}
+
+func (r *memRepo) UpdateBondization(isin string, coupons []Coupon, amortizations []Amortization, offers []Offer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.coupons[isin] = coupons
+	r.amortizations[isin] = amortizations
+	r.offers[isin] = offers
+	r.updatedAt[isin] = time.Now()
+	return nil
+}
+
+func (r *memRepo) GetIndicatorSnapshots() ([]models.IndicatorSnapshot, error) {
+	return r.snapshots, nil
+}
+
+func (r *memRepo) UpdateIndicatorSnapshots(snapshots []models.IndicatorSnapshot) (int, error) {
+	r.snapshots = snapshots
+	return len(snapshots), nil
+}
+
+func (r *memRepo) GetZCYC(date time.Time) (models.ZCYC, error) {
+	if r.zcyc == nil || r.zcyc.Date > date.Format(time.DateOnly) {
+		return models.ZCYC{}, repository.ErrNotFound
+	}
+	return *r.zcyc, nil
+}
+
+func TestSecuritiesService_bondization(t *testing.T) {
+	srv := moextest.NewServer(t)
+	md, err := NewMoexClient(srv.URL, http.DefaultClient)
+	require.NoError(t, err)
+
+	repo := newMemRepo()
+	s := New(repo, md, Options{BondizationMaxAge: time.Hour})
+	ctx := context.Background()
+
+	// Первое обращение загружает график с Мосбиржи и сохраняет его
+	coupons, amortizations, err := s.bondization(ctx, moextest.ISINAmortizing, false)
+	require.NoError(t, err)
+	assert.Len(t, coupons, 21)
+	assert.Len(t, amortizations, 4)
+	assert.Len(t, repo.coupons[moextest.ISINAmortizing], 21)
+
+	// При недоступности Мосбиржи используется сохранённый график, даже устаревший
+	srv.Close()
+	repo.updatedAt[moextest.ISINAmortizing] = time.Now().Add(-time.Hour * 2)
+	coupons, amortizations, err = s.bondization(ctx, moextest.ISINAmortizing, true)
+	require.NoError(t, err)
+	assert.Len(t, coupons, 21)
+	assert.Len(t, amortizations, 4)
+
+	// Без сохранённого графика возвращается ошибка Мосбиржи
+	_, _, err = s.bondization(ctx, moextest.ISINOfz, false)
+	assert.Error(t, err)
+}
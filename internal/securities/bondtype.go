@@ -0,0 +1,289 @@
+package securities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Типы облигаций
+const (
+	BondFixed      = "fixed"      // С постоянным или заранее известным купоном
+	BondFloater    = "floater"    // С купоном, привязанным к ключевой ставке или RUONIA
+	BondLinker     = "linker"     // С номиналом, индексируемым на инфляцию
+	BondAmortizing = "amortizing" // С амортизацией номинала
+	BondPerpetual  = "perpetual"  // Бессрочная
+)
+
+// Базовые ставки флоатеров
+const (
+	BaseKeyRate = "key_rate" // Ключевая ставка Банка России
+	BaseRUONIA  = "ruonia"   // Ставка RUONIA
+)
+
+// Источники спреда флоатера
+const (
+	spreadRequest  = "request"  // Задан в запросе
+	spreadInferred = "inferred" // Рассчитан по последнему известному купону
+)
+
+// fixingLastCoupon - источник значения базовой ставки при её недоступности: ставка последнего известного купона
+const fixingLastCoupon = "last_coupon"
+
+const (
+	ruoniaIndex      = "RUONIA" // Код индикатора RUONIA на индексном рынке Мосбиржи
+	perpetualHorizon = 10       // Предполагаемый срок до погашения бессрочной облигации без оферты, лет
+	noMatDate        = "0000-00-00"
+	defaultInflation = 4.0 // Ожидаемая инфляция по умолчанию (цель Банка России), % годовых
+)
+
+// Assumptions содержит допущения, использованные при расчёте показателей облигации
+type Assumptions struct {
+	Base             string   `json:"base,omitempty"`              // Базовая ставка флоатера
+	Fixing           float64  `json:"fixing,omitempty"`            // Значение базовой ставки, %
+	FixingSource     string   `json:"fixing_source,omitempty"`     // last_coupon - базовая ставка недоступна
+	Spread           *float64 `json:"spread,omitempty"`            // Спред к базовой ставке, п.п.
+	SpreadSource     string   `json:"spread_source,omitempty"`     // Источник спреда: request или inferred
+	Inflation        float64  `json:"inflation,omitempty"`         // Ожидаемая инфляция для индексации номинала, % годовых
+	IndexedFaceValue float64  `json:"indexed_facevalue,omitempty"` // Номинал на дату события с учётом индексации
+	Horizon          string   `json:"horizon,omitempty"`           // Предполагаемая дата погашения бессрочной облигации
+	ProjectedCoupons int      `json:"projected_coupons"`           // Количество купонов с прогнозной суммой
+}
+
+// bondType определяет тип облигации по данным Мосбиржи. Бессрочность и индексация номинала
+// важнее плавающего купона, плавающий купон - важнее амортизации.
+func bondType(bond Bond, coupons []Coupon, amortizations []Amortization, settleDate time.Time) (string, error) {
+	name := strings.ToUpper(bond.SecName + " " + bond.ShortName)
+	switch {
+	case bond.MatDate == "" || bond.MatDate == noMatDate:
+		return BondPerpetual, nil
+	case strings.Contains(name, "ОФЗ-ИН") || strings.HasPrefix(bond.Isin, "SU52"):
+		return BondLinker, nil
+	case strings.Contains(name, "ОФЗ-ПК") || strings.HasPrefix(bond.Isin, "SU29"):
+		return BondFloater, nil
+	}
+
+	// Неизвестные суммы будущих купонов характерны для флоатеров
+	unknown, err := unknownCoupons(coupons, settleDate)
+	if err != nil {
+		return "", err
+	}
+	if unknown > 0 {
+		return BondFloater, nil
+	}
+	// Единственная амортизационная выплата - погашение номинала
+	if len(amortizations) > 1 {
+		return BondAmortizing, nil
+	}
+	return BondFixed, nil
+}
+
+// unknownCoupons возвращает количество будущих купонов с неизвестной суммой
+func unknownCoupons(coupons []Coupon, settleDate time.Time) (int, error) {
+	n := 0
+	for _, c := range coupons {
+		date, err := time.Parse(time.DateOnly, c.Coupondate)
+		if err != nil {
+			return 0, err
+		}
+		if date.After(settleDate) && c.Value == 0 {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// fixing возвращает базовую ставку флоатера, %. По умолчанию используется ключевая ставка из настроек,
+// а если она не задана - RUONIA по данным Мосбиржи.
+func (s *SecuritiesService) fixing(ctx context.Context, base string) (string, float64, error) {
+	if base == "" {
+		base = BaseRUONIA
+		if s.opts.KeyRate > 0 {
+			base = BaseKeyRate
+		}
+	}
+
+	switch base {
+	case BaseKeyRate:
+		if s.opts.KeyRate <= 0 {
+			return base, 0, fmt.Errorf("key rate is not configured")
+		}
+		return base, s.opts.KeyRate, nil
+	case BaseRUONIA:
+		value, err := s.md.IndexValue(ctx, ruoniaIndex)
+		return base, value, err
+	}
+	return base, 0, fmt.Errorf("unknown base rate %q", base)
+}
+
+// projectFloater заполняет суммы будущих купонов флоатера по ставке fixing+spread. Если спред не задан,
+// он рассчитывается по ставке последнего известного купона, то есть предполагается сохранение текущей базовой ставки.
+func projectFloater(coupons []Coupon, bond Bond, settleDate time.Time, fixing float64, spread *float64, a *Assumptions) ([]Coupon, error) {
+	a.Fixing = fixing
+	a.SpreadSource = spreadRequest
+	if spread == nil {
+		inferred := roundFloat(lastCouponRate(coupons, bond)-fixing, precision)
+		spread = &inferred
+		a.SpreadSource = spreadInferred
+	}
+	a.Spread = spread
+
+	rate := (fixing + *spread) / 100
+	return projectCoupons(coupons, bond, settleDate, a, func(c Coupon, days float64) (float64, bool) {
+		if c.Value != 0 {
+			return c.Value, false
+		}
+		face := c.Facevalue
+		if face == 0 {
+			face = bond.FaceValue
+		}
+		return roundFloat(face*rate*days/daysInYear, 2), true
+	})
+}
+
+// lastCouponRate возвращает ставку последнего купона с известной суммой, %, а если таких нет - ставку купона облигации
+func lastCouponRate(coupons []Coupon, bond Bond) float64 {
+	rate := bond.CouponPercent
+	for _, c := range coupons {
+		if c.Value != 0 && c.Valueprc != 0 {
+			rate = c.Valueprc
+		}
+	}
+	return rate
+}
+
+// projectLinker рассчитывает суммы будущих купонов линкера по номиналу, индексируемому с ожидаемой инфляцией.
+// Объявленная Мосбиржей сумма ближайшего купона не пересчитывается. Суммы следующих купонов Мосбиржа приводит
+// по текущему номиналу без индексации, поэтому они заменяются прогнозом.
+func projectLinker(coupons []Coupon, bond Bond, settleDate time.Time, inflation float64, a *Assumptions) ([]Coupon, error) {
+	a.Inflation = inflation
+	nearest := true
+	return projectCoupons(coupons, bond, settleDate, a, func(c Coupon, days float64) (float64, bool) {
+		if nearest {
+			nearest = false
+			if c.Value != 0 {
+				return c.Value, false
+			}
+		}
+		date, _ := time.Parse(time.DateOnly, c.Coupondate)
+		rate := c.Valueprc
+		if rate == 0 {
+			rate = bond.CouponPercent
+		}
+		face := indexedFaceValue(bond.FaceValue, inflation, settleDate, date)
+		return roundFloat(face*rate/100*days/daysInYear, 2), true
+	})
+}
+
+// projectCoupons возвращает копию графика купонов, в которой суммы будущих купонов рассчитаны функцией value
+// по длительности купонного периода в днях
+func projectCoupons(coupons []Coupon, bond Bond, settleDate time.Time, a *Assumptions, value func(c Coupon, days float64) (float64, bool)) ([]Coupon, error) {
+	projected := make([]Coupon, len(coupons))
+	var prev time.Time
+	for i, c := range coupons {
+		date, err := time.Parse(time.DateOnly, c.Coupondate)
+		if err != nil {
+			return nil, err
+		}
+		days := float64(bond.CouponPeriod)
+		if !prev.IsZero() {
+			days = date.Sub(prev).Hours() / 24
+		}
+		prev = date
+
+		if date.After(settleDate) {
+			var ok bool
+			if c.Value, ok = value(c, days); ok {
+				a.ProjectedCoupons++
+			}
+		}
+		projected[i] = c
+	}
+	return projected, nil
+}
+
+// extendCoupons дополняет график купонов бессрочной облигации до даты horizon купонами последнего известного размера
+func extendCoupons(coupons []Coupon, bond Bond, horizon time.Time, a *Assumptions) ([]Coupon, error) {
+	if bond.CouponPeriod <= 0 || len(coupons) == 0 {
+		return coupons, nil
+	}
+	last := coupons[len(coupons)-1]
+	date, err := time.Parse(time.DateOnly, last.Coupondate)
+	if err != nil {
+		return nil, err
+	}
+
+	extended := append([]Coupon{}, coupons...)
+	for date = date.AddDate(0, 0, int(bond.CouponPeriod)); !date.After(horizon); date = date.AddDate(0, 0, int(bond.CouponPeriod)) {
+		c := last
+		c.Coupondate = date.Format(time.DateOnly)
+		c.Recorddate = date.AddDate(0, 0, -1).Format(time.DateOnly)
+		extended = append(extended, c)
+		a.ProjectedCoupons++
+	}
+	return extended, nil
+}
+
+// indexedFaceValue возвращает номинал на дату date при ежегодной индексации на inflation процентов
+func indexedFaceValue(faceValue, inflation float64, settleDate, date time.Time) float64 {
+	if !date.After(settleDate) {
+		return faceValue
+	}
+	return roundFloat(faceValue*math.Pow(1+inflation/100, yearFraction(settleDate, date)), 2)
+}
+
+// projectBond дополняет график купонов прогнозными выплатами в соответствии с типом облигации и возвращает
+// номинал, выплачиваемый в дату события. faceValue - номинал на дату расчётов.
+func projectBond(d bondData, bondType string, faceValue float64, settleDate, eventDate time.Time, spread *float64, a *Assumptions) ([]Coupon, float64, error) {
+	coupons := d.schedule
+	bond := d.bond
+	bond.FaceValue = faceValue
+
+	var err error
+	if bondType == BondPerpetual {
+		if coupons, err = extendCoupons(coupons, bond, eventDate, a); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if bondType == BondLinker {
+		if coupons, err = projectLinker(coupons, bond, settleDate, d.inflation, a); err != nil {
+			return nil, 0, err
+		}
+		a.IndexedFaceValue = indexedFaceValue(faceValue, d.inflation, settleDate, eventDate)
+		return coupons, a.IndexedFaceValue, nil
+	}
+
+	unknown, err := unknownCoupons(coupons, settleDate)
+	if err != nil {
+		return nil, 0, err
+	}
+	if unknown > 0 || spread != nil && bondType == BondFloater {
+		if d.base == "" {
+			return nil, 0, errors.New("no base rate for floater coupons")
+		}
+		a.Base = d.base
+		if d.fixingSource != "" {
+			// Купоны прогнозируются по ставке последнего купона, спред к недоступной базовой ставке не применяется
+			a.FixingSource = d.fixingSource
+			spread = nil
+		}
+		if coupons, err = projectFloater(coupons, bond, settleDate, d.fixing, spread, a); err != nil {
+			return nil, 0, err
+		}
+	}
+	return coupons, faceValue, nil
+}
+
+// needsFixing определяет, требуется ли базовая ставка для прогноза купонов облигации
+func needsFixing(bond Bond, coupons []Coupon, amortizations []Amortization) (bool, error) {
+	bt, err := bondType(bond, coupons, amortizations, time.Time{})
+	if err != nil || bt == BondFloater || bt == BondLinker {
+		return bt == BondFloater, err
+	}
+	unknown, err := unknownCoupons(coupons, time.Time{})
+	return unknown > 0, err
+}
@@ -0,0 +1,98 @@
+package securities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_bondType(t *testing.T) {
+	settleDate := time.Date(2024, time.June, 4, 0, 0, 0, 0, time.UTC)
+	known := []Coupon{{Coupondate: "2024-09-01", Value: 30}, {Coupondate: "2024-12-01", Value: 30}}
+	unknown := []Coupon{{Coupondate: "2024-03-01", Value: 0}, {Coupondate: "2024-09-01", Value: 40}, {Coupondate: "2024-12-01"}}
+	amortizations := []Amortization{{Amortdate: "2025-06-01"}, {Amortdate: "2025-12-01"}}
+
+	tests := []struct {
+		name          string
+		bond          Bond
+		coupons       []Coupon
+		amortizations []Amortization
+		want          string
+	}{
+		{"fixed", Bond{MatDate: "2030-01-01"}, known, amortizations[:1], BondFixed},
+		{"amortizing", Bond{MatDate: "2030-01-01"}, known, amortizations, BondAmortizing},
+		{"floater by coupons", Bond{MatDate: "2030-01-01"}, unknown, amortizations, BondFloater},
+		{"floater by name", Bond{MatDate: "2030-01-01", SecName: "ОФЗ-ПК 29006 29/01/2025"}, known, nil, BondFloater},
+		{"linker", Bond{Isin: "SU52002RMFS1", MatDate: "2028-02-02"}, unknown, nil, BondLinker},
+		{"perpetual", Bond{MatDate: "0000-00-00"}, unknown, nil, BondPerpetual},
+		{"perpetual without matdate", Bond{}, known, nil, BondPerpetual},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := bondType(tt.bond, tt.coupons, tt.amortizations, settleDate)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_projectFloater(t *testing.T) {
+	settleDate := time.Date(2024, time.June, 4, 0, 0, 0, 0, time.UTC)
+	bond := Bond{FaceValue: 1000, CouponPeriod: 182, CouponPercent: 16}
+	coupons := []Coupon{
+		{Coupondate: "2024-03-01", Value: 60, Valueprc: 12},
+		{Coupondate: "2024-08-30", Value: 79.78, Valueprc: 16},
+		{Coupondate: "2025-02-28"},
+	}
+
+	var a Assumptions
+	got, err := projectFloater(coupons, bond, settleDate, 15, nil, &a)
+	require.NoError(t, err)
+	// Спред по последнему известному купону: 16 - 15
+	assert.Equal(t, 1.0, *a.Spread)
+	assert.Equal(t, spreadInferred, a.SpreadSource)
+	assert.Equal(t, 1, a.ProjectedCoupons)
+	assert.Equal(t, 79.78, got[1].Value)
+	assert.Equal(t, 79.78, got[2].Value)
+	assert.Zero(t, coupons[2].Value)
+
+	spread := 2.0
+	a = Assumptions{}
+	got, err = projectFloater(coupons, bond, settleDate, 15, &spread, &a)
+	require.NoError(t, err)
+	assert.Equal(t, spreadRequest, a.SpreadSource)
+	assert.Equal(t, 84.77, got[2].Value)
+}
+
+func Test_projectLinker(t *testing.T) {
+	settleDate := time.Date(2024, time.June, 4, 0, 0, 0, 0, time.UTC)
+	bond := Bond{FaceValue: 1000, CouponPeriod: 365, CouponPercent: 2.5}
+	coupons := []Coupon{
+		{Coupondate: "2024-12-04", Value: 12.71, Valueprc: 2.5},
+		{Coupondate: "2025-06-04", Value: 12.71, Valueprc: 2.5},
+	}
+
+	// Объявленный ближайший купон сохраняется, следующий рассчитывается по индексированному номиналу
+	var a Assumptions
+	got, err := projectLinker(coupons, bond, settleDate, 4, &a)
+	require.NoError(t, err)
+	assert.Equal(t, 12.71, got[0].Value)
+	assert.Equal(t, 12.96, got[1].Value)
+	assert.Equal(t, 1, a.ProjectedCoupons)
+	assert.Equal(t, 1040.0, indexedFaceValue(1000, 4, settleDate, settleDate.AddDate(0, 0, 365)))
+}
+
+func Test_extendCoupons(t *testing.T) {
+	bond := Bond{CouponPeriod: 91}
+	coupons := []Coupon{{Coupondate: "2024-06-01", Value: 25}}
+
+	var a Assumptions
+	got, err := extendCoupons(coupons, bond, time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC), &a)
+	require.NoError(t, err)
+	require.Len(t, got, 5)
+	assert.Equal(t, "2025-05-31", got[4].Coupondate)
+	assert.Equal(t, 25.0, got[4].Value)
+	assert.Equal(t, 4, a.ProjectedCoupons)
+}
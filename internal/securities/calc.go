@@ -0,0 +1,519 @@
+package securities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"simple-invest/internal/models"
+	"simple-invest/internal/repository"
+	"simple-invest/internal/tax"
+)
+
+// bondData содержит данные облигации, полученные от Мосбиржи и внешних источников, необходимые для расчёта показателей
+type bondData struct {
+	bond          Bond
+	market        *BondMarketData // Торговые данные, nil - сделок нет
+	price         *marketPrice    // Цена по цепочке источников, nil - цена недоступна
+	secType       string          // Тип бумаги Мосбиржи
+	schedule      []Coupon        // График купонов
+	amortizations []Amortization  // График амортизационных выплат
+	offers        []Offer         // График оферт
+	base          string          // Базовая ставка флоатера
+	fixing        float64         // Значение базовой ставки, %
+	fixingSource  string          // Источник значения базовой ставки, если она недоступна
+	inflation     float64         // Ожидаемая инфляция для линкеров, % годовых
+	fx            *FXRate         // Курс валюты номинала, nil - для рублёвых облигаций
+	curve         *models.ZCYC    // Сохранённая G-кривая на дату расчётов, nil - кривая не загружена
+}
+
+// bondCalc содержит график выплат облигации на дату расчётов и формулы расчёта доходностей, общие для прямого
+// расчёта показателей по цене и обратного расчёта цены по доходности
+type bondCalc struct {
+	bond          Bond
+	bondType      string
+	assumptions   Assumptions
+	settleDate    time.Time
+	eventDate     time.Time
+	offer         *offerEvent    // Ближайшая оферта, nil - событие облигации - погашение
+	offers        []offerEvent   // Будущие оферты
+	daysToEvent   int64          // Дней от даты анализа до события
+	netDays       float64        // Срок до события, приведённый с учётом амортизации, дней
+	face          float64        // Номинал на дату расчётов
+	accruedInt    float64        // НКД на дату расчётов
+	schedule      []Coupon       // График купонов по данным Мосбиржи
+	coupons       []Coupon       // График купонов с прогнозными суммами
+	amortizations []Amortization // График амортизационных выплат
+	faceValue     float64        // Номинал, выплачиваемый в дату события
+	couponsAmount float64        // Сумма будущих купонов
+	flows         []cashFlow     // График будущих выплат до даты события
+	couponTaxRate float64        // Ставка налога на купонный доход
+	gainTaxRate   float64        // Ставка налога на доход от погашения
+	taxModel      tax.Model      // Налоговая модель для определения ЛДВ
+	acquired      time.Time      // Дата приобретения для определения ЛДВ, нулевая - дата расчётов
+}
+
+// bondData загружает данные облигации, кроме торговых, с базовой ставкой для флоатеров, курсом валюты номинала
+// и сохранённой G-кривой для рублёвых облигаций
+func (s *SecuritiesService) bondData(ctx context.Context, isin string, opts IndicatorOptions) (bondData, error) {
+	d := bondData{inflation: s.opts.Inflation}
+	if opts.Inflation != nil {
+		d.inflation = *opts.Inflation
+	}
+
+	sec, _, err := s.repo.GetSecurity(isin)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return d, fmt.Errorf("%w: %w", ErrStorage, err)
+	}
+	d.secType = sec.Type
+
+	d.bond, err = s.md.Bond(ctx, isin)
+	if err != nil {
+		return d, err
+	}
+
+	d.schedule, d.amortizations, err = s.bondization(ctx, isin, false)
+	if err != nil {
+		return d, err
+	}
+	d.offers, err = s.repo.GetOffers(isin)
+	if err != nil {
+		return d, fmt.Errorf("%w: %w", ErrStorage, err)
+	}
+
+	need, err := needsFixing(d.bond, d.schedule, d.amortizations)
+	if err != nil {
+		return d, err
+	}
+	if need {
+		if d.base, d.fixing, err = s.fixing(ctx, opts.Base); err != nil {
+			log.Printf("fixing %s %s: %v, using last coupon rate", isin, d.base, err)
+			d.fixing, d.fixingSource = lastCouponRate(d.schedule, d.bond), fixingLastCoupon
+		}
+	}
+
+	settle, err := settleDate(d.bond, opts, time.Now().Truncate(time.Hour*24))
+	if err != nil {
+		return d, err
+	}
+	if currency := currencyCode(d.bond.FaceUnit); currency != rubleCode {
+		rate, err := s.fxRate(ctx, currency, settle, d.schedule)
+		if err != nil {
+			return d, err
+		}
+		d.fx = &rate
+	} else {
+		// Спреды к G-кривой рассчитываются только по сохранённой кривой, без обращения к Мосбирже.
+		// Устаревшая кривая не используется.
+		curve, err := s.repo.GetZCYC(settle)
+		switch {
+		case err == nil:
+			fresh, err := curveFresh(curve, settle)
+			if err != nil {
+				return d, err
+			}
+			if fresh {
+				d.curve = &curve
+			}
+		case !errors.Is(err, repository.ErrNotFound):
+			return d, fmt.Errorf("%w: %w", ErrStorage, err)
+		}
+	}
+
+	return d, nil
+}
+
+// settleDate возвращает дату расчётов: заданную в opts, по данным Мосбиржи или следующий за today день
+func settleDate(bond Bond, opts IndicatorOptions, today time.Time) (time.Time, error) {
+	switch {
+	case !opts.SettleDate.IsZero():
+		return opts.SettleDate, nil
+	case bond.SettleDate != "":
+		return time.Parse(time.DateOnly, bond.SettleDate)
+	}
+	return today.AddDate(0, 0, 1), nil
+}
+
+// newBondCalc формирует график будущих выплат облигации по данным d на дату расчётов.
+// Если дата расчётов задана в opts, номинал и НКД рассчитываются на эту дату по графику выплат,
+// а срок до события отсчитывается от неё. Функция не обращается к внешним источникам данных.
+func newBondCalc(d bondData, opts IndicatorOptions, today time.Time) (*bondCalc, error) {
+	c := &bondCalc{
+		bond:          d.bond,
+		schedule:      d.schedule,
+		amortizations: d.amortizations,
+		face:          d.bond.FaceValue,
+		accruedInt:    d.bond.AccruedInt,
+		couponTaxRate: opts.Tax.Rate(tax.CouponKind(d.secType, d.bond.Isin)),
+		gainTaxRate:   opts.Tax.Rate(tax.CapitalGain),
+		taxModel:      opts.Tax,
+	}
+
+	var err error
+	c.settleDate, err = settleDate(d.bond, opts, today)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.SettleDate.IsZero() {
+		today = c.settleDate
+		c.face = outstandingFace(d.amortizations, c.settleDate, d.bond.FaceValue)
+	}
+
+	c.bondType, err = bondType(d.bond, d.schedule, d.amortizations, c.settleDate)
+	if err != nil {
+		return nil, err
+	}
+
+	// Событием облигации считается ближайшая оферта, при её отсутствии - погашение
+	c.offers, err = futureOffers(d.bond, d.offers, c.settleDate)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(c.offers) > 0:
+		c.offer = &c.offers[0]
+		c.eventDate, err = time.Parse(time.DateOnly, c.offer.Date)
+	case c.bondType == BondPerpetual:
+		// Бессрочная облигация без оферты считается погашаемой по номиналу через perpetualHorizon лет
+		c.eventDate = c.settleDate.AddDate(perpetualHorizon, 0, 0)
+		c.assumptions.Horizon = c.eventDate.Format(time.DateOnly)
+	default:
+		c.eventDate, err = time.Parse(time.DateOnly, d.bond.MatDate)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !c.eventDate.After(c.settleDate) {
+		return nil, fmt.Errorf("%w: settle date %s is not before event date %s", ErrNoCashFlows,
+			c.settleDate.Format(time.DateOnly), c.eventDate.Format(time.DateOnly))
+	}
+	c.daysToEvent = int64(c.eventDate.Sub(today).Hours() / 24)
+
+	c.coupons, c.faceValue, err = projectBond(d, c.bondType, c.face, c.settleDate, c.eventDate, opts.Spread, &c.assumptions)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.SettleDate.IsZero() {
+		c.accruedInt, err = accruedInterest(c.coupons, c.settleDate, d.bond.CouponPeriod)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, cp := range c.coupons {
+		paymentDate, err := time.Parse(time.DateOnly, cp.Coupondate)
+		if err != nil {
+			return nil, err
+		}
+		if paymentDate.After(c.settleDate) && !paymentDate.After(c.eventDate) {
+			c.couponsAmount += cp.Value
+		}
+	}
+
+	// Для амортизируемых ооблигаций необходимо приведение периода
+	c.netDays = float64(c.daysToEvent)
+	if len(c.amortizations) > 0 {
+		amortizations, err := truncateAmortizations(c.amortizations, c.eventDate)
+		if err != nil {
+			return nil, err
+		}
+		c.netDays, err = amortizationsNetPeriod(amortizations, c.settleDate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Эффективная доходность рассчитывается по графику будущих выплат с учётом прогнозных купонов
+	c.flows, err = bondCashFlows(c.coupons, c.amortizations, c.faceValue, c.settleDate, c.eventDate)
+	if err != nil {
+		return nil, err
+	}
+
+	// Номинал, непогашенный к оферте, выкупается по цене оферты
+	if n := len(c.flows); c.offer != nil && c.offer.Price != offerPar && n > 0 && c.flows[n-1].Date.Equal(c.eventDate) {
+		premium := roundFloat(c.flows[n-1].Principal*(c.offer.Price-offerPar)/100, 2)
+		c.flows[n-1].Principal += premium
+		c.faceValue += premium
+	}
+
+	return c, nil
+}
+
+// outstandingFace возвращает непогашенный номинал на дату date по графику амортизаций.
+// Если выплат после даты нет, возвращается текущий номинал current.
+func outstandingFace(amortizations []Amortization, date time.Time, current float64) float64 {
+	face := 0.0
+	for _, a := range amortizations {
+		amortDate, err := time.Parse(time.DateOnly, a.Amortdate)
+		if err == nil && amortDate.After(date) {
+			face += a.Value
+		}
+	}
+	if face == 0 {
+		return current
+	}
+	return roundFloat(face, 2)
+}
+
+// accruedInterest рассчитывает НКД на дату date пропорционально прошедшей части текущего купонного периода.
+// Начало первого периода определяется по длительности купонного периода period.
+func accruedInterest(coupons []Coupon, date time.Time, period int32) (float64, error) {
+	var start time.Time
+	for i, c := range coupons {
+		end, err := time.Parse(time.DateOnly, c.Coupondate)
+		if err != nil {
+			return 0, err
+		}
+		if i == 0 {
+			start = end.AddDate(0, 0, -int(period))
+		}
+		if end.After(date) {
+			if !date.After(start) {
+				return 0, nil
+			}
+			return roundFloat(c.Value*date.Sub(start).Hours()/end.Sub(start).Hours(), 2), nil
+		}
+		start = end
+	}
+	return 0, nil
+}
+
+// ldvExempt определяет применение льготы долгосрочного владения к доходу от погашения
+func (c *bondCalc) ldvExempt() bool {
+	acquired := c.acquired
+	if acquired.IsZero() {
+		acquired = c.settleDate
+	}
+	return c.taxModel.LongTermExempt(acquired, c.eventDate)
+}
+
+// maturityTax возвращает налог при погашении облигации, купленной по цене price (с НКД).
+// Доход линкера рассчитывается от индексированного номинала, выплачиваемого при погашении.
+func (c *bondCalc) maturityTax(price float64) float64 {
+	face := c.face
+	if c.bondType == BondLinker {
+		face = c.faceValue
+	}
+	if c.ldvExempt() || price >= face {
+		return 0
+	}
+	return roundFloat((face-price)*c.gainTaxRate, 2)
+}
+
+// simpleYield рассчитывает простую доходность при покупке по цене price (с НКД), net - с учётом налогов
+func (c *bondCalc) simpleYield(price float64, net bool) float64 {
+	if !net {
+		return (c.couponsAmount + c.faceValue - price) / price * 365 / c.netDays
+	}
+	return (c.couponsAmount*(1-c.couponTaxRate) + c.faceValue - c.maturityTax(price) - price) / price * 365 / c.netDays
+}
+
+// effectiveYield рассчитывает эффективную доходность при покупке по цене price (с НКД), net - с учётом налогов
+func (c *bondCalc) effectiveYield(price float64, net bool) (float64, error) {
+	if !net {
+		return effectiveYield(c.flows, price, c.settleDate)
+	}
+	return effectiveYield(netCashFlows(c.flows, c.couponTaxRate, c.maturityTax(price)), price, c.settleDate)
+}
+
+// Доходности по лучшим ценам спроса и предложения
+type quoteIndicators struct {
+	Bid         float64 `json:"bid,omitempty"`          // Лучшая цена спроса в процентах от номинала
+	Offer       float64 `json:"offer,omitempty"`        // Лучшая цена предложения в процентах от номинала
+	BidYield    float64 `json:"bid_yield,omitempty"`    // Эффективная доходность при продаже по цене спроса
+	OfferYield  float64 `json:"offer_yield,omitempty"`  // Эффективная доходность при покупке по цене предложения
+	SpreadPrice float64 `json:"spread_price,omitempty"` // Спред между ценами предложения и спроса, п.п. номинала
+	SpreadBP    float64 `json:"spread_bp,omitempty"`    // Спред между доходностями по ценам спроса и предложения, б.п.
+}
+
+// Показатели позиции из заданного количества облигаций
+type positionIndicators struct {
+	Quantity   int64   `json:"quantity"`   // Количество облигаций
+	Amount     float64 `json:"amount"`     // Стоимость покупки с НКД
+	Coupons    float64 `json:"coupons"`    // Купоны до даты события
+	Redemption float64 `json:"redemption"` // Выплаты номинала до даты события
+	Tax        float64 `json:"tax"`        // НДФЛ с купонов и при погашении
+	NetIncome  float64 `json:"net_income"` // Доход за вычетом налогов и стоимости покупки
+}
+
+// calcBondIndicators рассчитывает показатели облигации по загруженным данным d. Цена берётся из opts,
+// а если она не задана - по цепочке источников торговых данных. Функция не обращается к внешним источникам данных.
+func calcBondIndicators(d bondData, opts IndicatorOptions, today time.Time) (bondIndicators, error) {
+	bI := bondIndicators{Isin: d.bond.Isin}
+
+	price := marketPrice{Source: PriceRequest, Price: opts.Price}
+	if opts.Price == 0 {
+		if d.price == nil {
+			return bI, errNoMoexData
+		}
+		price = *d.price
+	}
+	percentPrice := price.Price
+
+	c, err := newBondCalc(d, opts, today)
+	if err != nil {
+		return bI, err
+	}
+	marketSettle, err := settleDate(d.bond, IndicatorOptions{}, today)
+	if err != nil {
+		return bI, err
+	}
+	bond := c.bond
+
+	bI.TaxRate = c.couponTaxRate
+	bI.BondType = c.bondType
+	bI.FaceValue = c.face
+	bI.AccruedInt = c.accruedInt
+	bI.Coupon = bond.CouponValue
+	bI.PercentPrice = percentPrice
+	bI.PriceSource = price.Source
+	bI.PriceTime = price.Time
+	bI.Price = roundFloat(c.face*percentPrice/100, 2) + c.accruedInt
+	bI.DaysToEvent = c.daysToEvent
+	bI.MatDate = bond.MatDate
+	if c.offer != nil {
+		bI.OfferDate = c.offer.Date
+	}
+	bI.FaceUnit = bond.FaceUnit
+	bI.SettleDate = c.settleDate.Format(time.DateOnly)
+	if d.market != nil {
+		bI.ValToday = d.market.ValToday
+		bI.NumTrades = d.market.NumTrades
+		bI.MoexYield = roundFloat(d.market.Yield/100, precision)
+	}
+
+	if percentPrice != 0 {
+		bI.CurrentYield = roundFloat(bond.CouponPercent/percentPrice, precision)
+		bI.NetCurrentYield = roundFloat(bond.CouponPercent*c.face*(1-c.couponTaxRate)/bI.Price/100, precision)
+	}
+
+	switch c.bondType {
+	case BondFloater:
+		bI.CouponType = CouponFloating
+	case BondLinker:
+		bI.CouponType = CouponIndexed
+	default:
+		bI.CouponType, err = couponType(c.schedule, c.settleDate)
+		if err != nil {
+			return bI, err
+		}
+	}
+	// Единственная амортизационная выплата - погашение номинала
+	bI.HasAmortization = len(c.amortizations) > 1
+
+	bI.SimpleYield = roundFloat(c.simpleYield(bI.Price, false), precision)
+	bI.NetSimpleYield = roundFloat(c.simpleYield(bI.Price, true), precision)
+	bI.MaturityTax = c.maturityTax(bI.Price)
+
+	if bI.Price > 0 {
+		effYield, err := c.effectiveYield(bI.Price, false)
+		if err != nil {
+			return bI, err
+		}
+		netEffYield, err := c.effectiveYield(bI.Price, true)
+		if err != nil {
+			return bI, err
+		}
+		bI.EffectiveYield = roundFloat(effYield, precision)
+		bI.NetEffectiveYield = roundFloat(netEffYield, precision)
+
+		sens := rateSensitivity(c.flows, effYield, c.settleDate)
+		bI.MacaulayDuration = roundFloat(sens.MacaulayDuration, precision)
+		bI.ModifiedDuration = roundFloat(sens.ModifiedDuration, precision)
+		bI.Convexity = roundFloat(sens.Convexity, precision)
+		bI.DV01 = roundFloat(sens.DV01, precision)
+
+		// Ошибка расчёта спредов не прерывает расчёт остальных показателей
+		if d.curve != nil {
+			bI.Spreads, err = curveSpreads(c.flows, bI.Price, effYield, sens.MacaulayDuration, c.settleDate, *d.curve)
+			if err != nil {
+				log.Printf("spreads %s: %v", bond.Isin, err)
+				bI.Spreads = nil
+			}
+		}
+	}
+
+	if c.offer != nil {
+		bI.Offer, err = c.offerIndicators(d, opts, today, bI.Price)
+		if err != nil {
+			return bI, err
+		}
+	}
+
+	// Для облигаций с номиналом в иностранной валюте показатели дополнительно рассчитываются в рублях
+	if d.fx != nil {
+		bI.FX, err = rubIndicators(c.flows, bI, *d.fx, opts.FXChange, c.settleDate, c.couponTaxRate, c.gainTaxRate, c.ldvExempt())
+		if err != nil {
+			return bI, err
+		}
+	}
+
+	// Котировки относятся к текущей дате расчётов Мосбиржи и не рассчитываются на другую дату
+	if d.market != nil && c.settleDate.Equal(marketSettle) {
+		bI.Quotes = c.quotes(*d.market)
+	}
+
+	if opts.Quantity > 0 {
+		bI.Position = c.position(opts.Quantity, bI.Price)
+	}
+
+	bI.Assumptions = c.assumptions
+	return bI, nil
+}
+
+// position рассчитывает показатели позиции из quantity облигаций, купленных по цене price (с НКД)
+func (c *bondCalc) position(quantity int64, price float64) *positionIndicators {
+	coupons, redemption := 0.0, 0.0
+	for _, cf := range c.flows {
+		coupons += cf.Coupon
+		redemption += cf.Principal
+	}
+	tax := coupons*c.couponTaxRate + c.maturityTax(price)
+
+	q := float64(quantity)
+	return &positionIndicators{
+		Quantity:   quantity,
+		Amount:     roundFloat(price*q, 2),
+		Coupons:    roundFloat(coupons*q, 2),
+		Redemption: roundFloat(redemption*q, 2),
+		Tax:        roundFloat(tax*q, 2),
+		NetIncome:  roundFloat((coupons+redemption-tax-price)*q, 2),
+	}
+}
+
+// quotes рассчитывает доходности по лучшим ценам спроса и предложения. Если котировок нет, возвращается nil.
+// Доходность, которую не удалось рассчитать по котировке, не возвращается и не прерывает расчёт.
+func (c *bondCalc) quotes(m BondMarketData) *quoteIndicators {
+	if m.Bid <= 0 && m.Offer <= 0 {
+		return nil
+	}
+
+	// yield рассчитывает эффективную доходность по чистой цене в процентах от номинала
+	yield := func(side string, percentPrice float64) (float64, bool) {
+		if percentPrice <= 0 {
+			return 0, false
+		}
+		y, err := c.effectiveYield(roundFloat(c.face*percentPrice/100, 2)+c.accruedInt, false)
+		if err != nil {
+			log.Printf("quotes %s %s %v: %v", c.bond.Isin, side, percentPrice, err)
+			return 0, false
+		}
+		return y, true
+	}
+
+	q := &quoteIndicators{Bid: m.Bid, Offer: m.Offer}
+	bidYield, bidOK := yield("bid", m.Bid)
+	offerYield, offerOK := yield("offer", m.Offer)
+	q.BidYield = roundFloat(bidYield, precision)
+	q.OfferYield = roundFloat(offerYield, precision)
+	if m.Bid > 0 && m.Offer > 0 {
+		q.SpreadPrice = roundFloat(m.Offer-m.Bid, precision)
+	}
+	// Спред рассчитывается по неокруглённым доходностям
+	if bidOK && offerOK {
+		q.SpreadBP = roundFloat((bidYield-offerYield)*10000, 2)
+	}
+	return q
+}
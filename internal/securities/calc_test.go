@@ -0,0 +1,215 @@
+package securities
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"simple-invest/internal/securities/moextest"
+	"simple-invest/internal/tax"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_outstandingFace(t *testing.T) {
+	amortizations := []Amortization{
+		{Amortdate: "2026-12-02", Value: 250},
+		{Amortdate: "2027-03-03", Value: 250},
+		{Amortdate: "2027-06-02", Value: 250},
+		{Amortdate: "2027-09-01", Value: 250},
+	}
+	tests := []struct {
+		date string
+		want float64
+	}{
+		{"2024-06-04", 1000},
+		{"2026-12-02", 750},
+		{"2027-01-15", 750},
+		{"2027-08-01", 250},
+		{"2027-09-01", 500}, // Выплаты после даты погашения отсутствуют - текущий номинал
+	}
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			date, _ := time.Parse(time.DateOnly, tt.date)
+			assert.Equal(t, tt.want, outstandingFace(amortizations, date, 500))
+		})
+	}
+}
+
+func Test_accruedInterest(t *testing.T) {
+	coupons := []Coupon{
+		{Coupondate: "2024-03-06", Value: 29.92},
+		{Coupondate: "2024-06-05", Value: 29.92},
+		{Coupondate: "2024-09-04", Value: 29.92},
+	}
+	tests := []struct {
+		date string
+		want float64
+	}{
+		{"2023-12-01", 0},     // До начала первого купонного периода
+		{"2024-01-20", 14.8},  // Первый период отсчитывается от даты купона за вычетом его длительности
+		{"2024-03-06", 0},     // В дату выплаты купона
+		{"2024-04-20", 14.8},  // 45 из 91 дня
+		{"2024-06-04", 29.59}, // Совпадает с НКД по данным Мосбиржи
+		{"2024-10-01", 0},     // После последнего купона
+	}
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			date, _ := time.Parse(time.DateOnly, tt.date)
+			got, err := accruedInterest(coupons, date, 91)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_bondCalc_maturityTax(t *testing.T) {
+	settleDate := time.Date(2024, time.June, 4, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		bondType string
+		event    time.Time
+		tm       tax.Model
+		want     float64
+	}{
+		{"fixed", BondFixed, settleDate.AddDate(1, 0, 0), tax.Model{Resident: true}, 0},
+		{"linker", BondLinker, settleDate.AddDate(1, 0, 0), tax.Model{Resident: true}, 6.5},   // Доход от индексированного номинала 1100
+		{"linker ldv", BondLinker, settleDate.AddDate(4, 0, 0), tax.Model{Resident: true}, 0}, // Льгота долгосрочного владения
+		{"linker non-resident", BondLinker, settleDate.AddDate(4, 0, 0), tax.Model{}, 15},     // Нерезидентам ЛДВ не применяется
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := bondCalc{bondType: tt.bondType, settleDate: settleDate, eventDate: tt.event,
+				face: 1000, faceValue: 1100, gainTaxRate: tt.tm.Rate(tax.CapitalGain), taxModel: tt.tm}
+			assert.Equal(t, tt.want, c.maturityTax(1050))
+		})
+	}
+}
+
+func Test_calcBondIndicators(t *testing.T) {
+	s := New(newMemRepo(), newTestMoexClient(t), Options{})
+	ctx := context.Background()
+	opts := IndicatorOptions{Tax: tax.Default()}
+	today := time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)
+
+	d, err := s.bondData(ctx, moextest.ISINOfz, opts)
+	require.NoError(t, err)
+	market, err := s.md.BondMarketData(ctx, moextest.ISINOfz)
+	require.NoError(t, err)
+
+	// Без торговых данных и заданной цены показатели не рассчитываются
+	_, err = calcBondIndicators(d, opts, today)
+	assert.ErrorIs(t, err, errNoMoexData)
+
+	d.market = &market
+	d.price = &marketPrice{Source: PriceLast, Price: market.Last, Time: "2024-06-04 18:39:58"}
+	want, err := calcBondIndicators(d, opts, today)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-06-04", want.SettleDate)
+	assert.Equal(t, PriceLast, want.PriceSource)
+	assert.Equal(t, "2024-06-04 18:39:58", want.PriceTime)
+	assert.Nil(t, want.Position)
+
+	// Цена, равная цене последней сделки, не меняет показателей
+	withPrice := opts
+	withPrice.Price = market.Last
+	got, err := calcBondIndicators(d, withPrice, today)
+	require.NoError(t, err)
+	assert.Equal(t, PriceRequest, got.PriceSource)
+	got.PriceSource, got.PriceTime = want.PriceSource, want.PriceTime
+	assert.Equal(t, want, got)
+
+	// Более низкая цена - более высокая доходность
+	withPrice.Price = market.Last - 5
+	got, err = calcBondIndicators(d, withPrice, today)
+	require.NoError(t, err)
+	assert.Equal(t, withPrice.Price, got.PercentPrice)
+	assert.Greater(t, got.EffectiveYield, want.EffectiveYield)
+	assert.Equal(t, market.NumTrades, got.NumTrades)
+
+	// Дата расчётов, равная дате расчётов Мосбиржи, не меняет показателей
+	withSettle := opts
+	withSettle.SettleDate = time.Date(2024, 6, 4, 0, 0, 0, 0, time.UTC)
+	got, err = calcBondIndicators(d, withSettle, today)
+	require.NoError(t, err)
+	assert.Equal(t, want.AccruedInt, got.AccruedInt)
+	assert.Equal(t, want.EffectiveYield, got.EffectiveYield)
+	assert.Equal(t, want.DaysToEvent-1, got.DaysToEvent)
+
+	// Показатели позиции пропорциональны количеству
+	withQuantity := opts
+	withQuantity.Quantity = 10
+	got, err = calcBondIndicators(d, withQuantity, today)
+	require.NoError(t, err)
+	require.NotNil(t, got.Position)
+	assert.Equal(t, int64(10), got.Position.Quantity)
+	assert.InDelta(t, want.Price*10, got.Position.Amount, 0.01)
+	assert.Equal(t, 10000.0, got.Position.Redemption)
+	assert.InDelta(t, got.Position.Coupons+got.Position.Redemption-got.Position.Tax-got.Position.Amount,
+		got.Position.NetIncome, 0.05)
+}
+
+func TestSecuritiesService_BondIndicatorsQuotes(t *testing.T) {
+	s := New(newMemRepo(), newTestMoexClient(t), Options{})
+	ctx := context.Background()
+	opts := IndicatorOptions{Tax: tax.Default()}
+
+	got, err := s.BondIndicators(ctx, moextest.ISINOfz, opts)
+	require.NoError(t, err)
+	require.NotNil(t, got.Quotes)
+	assert.Equal(t, 57.45, got.Quotes.Bid)
+	assert.Equal(t, 57.6, got.Quotes.Offer)
+	assert.Equal(t, 0.15, got.Quotes.SpreadPrice)
+	// Доходность по цене спроса выше, по цене предложения - ниже доходности по цене последней сделки
+	assert.Greater(t, got.Quotes.BidYield, got.EffectiveYield)
+	assert.Less(t, got.Quotes.OfferYield, got.EffectiveYield)
+	// Спред рассчитывается по неокруглённым доходностям: по округлённым он составил бы 3 б.п.
+	assert.Equal(t, 3.76, got.Quotes.SpreadBP)
+
+	// Цена, рассчитанная по доходности предложения, совпадает с ценой предложения
+	price, err := s.PriceForYield(ctx, moextest.ISINOfz, got.Quotes.OfferYield, YieldEffective, opts)
+	require.NoError(t, err)
+	assert.InDelta(t, got.Quotes.Offer, price.PercentPrice, 0.05)
+
+	// Без котировок блок отсутствует
+	d, err := s.bondData(ctx, moextest.ISINOfz, opts)
+	require.NoError(t, err)
+	d.market = &BondMarketData{Last: 57.5}
+	d.price = &marketPrice{Source: PriceLast, Price: 57.5}
+	noQuotes, err := calcBondIndicators(d, opts, time.Now())
+	require.NoError(t, err)
+	assert.Nil(t, noQuotes.Quotes)
+
+	// Односторонняя котировка
+	d.market.Offer = 57.6
+	oneSided, err := calcBondIndicators(d, opts, time.Now())
+	require.NoError(t, err)
+	require.NotNil(t, oneSided.Quotes)
+	assert.Equal(t, got.Quotes.OfferYield, oneSided.Quotes.OfferYield)
+	assert.Zero(t, oneSided.Quotes.BidYield)
+	assert.Zero(t, oneSided.Quotes.SpreadBP)
+
+	// Доходность по далёкой от рынка котировке не рассчитывается и не прерывает расчёт
+	d.market.Bid = 0.001
+	farBid, err := calcBondIndicators(d, opts, time.Now())
+	require.NoError(t, err)
+	require.NotNil(t, farBid.Quotes)
+	assert.Equal(t, 0.001, farBid.Quotes.Bid)
+	assert.Zero(t, farBid.Quotes.BidYield)
+	assert.Equal(t, got.Quotes.OfferYield, farBid.Quotes.OfferYield)
+	assert.Equal(t, 57.599, farBid.Quotes.SpreadPrice)
+	assert.Zero(t, farBid.Quotes.SpreadBP)
+
+	// Котировки не пересчитываются на заданную дату расчётов, отличную от текущей
+	opts.SettleDate = time.Date(2024, time.March, 8, 0, 0, 0, 0, time.UTC)
+	past, err := s.BondIndicators(ctx, moextest.ISINOfz, opts)
+	require.NoError(t, err)
+	assert.Nil(t, past.Quotes)
+
+	opts.SettleDate, err = time.Parse(time.DateOnly, got.SettleDate)
+	require.NoError(t, err)
+	current, err := s.BondIndicators(ctx, moextest.ISINOfz, opts)
+	require.NoError(t, err)
+	assert.Equal(t, got.Quotes, current.Quotes)
+}
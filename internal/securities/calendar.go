@@ -0,0 +1,108 @@
+package securities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"simple-invest/internal/models"
+	"simple-invest/internal/repository"
+
+	"github.com/WLM1ke/gomoex"
+)
+
+// CalendarEvents возвращает события по quantity бумагам isin с датой не ранее from: для облигаций - выплаты
+// и даты фиксации купонов, амортизации, погашение и оферты по сохранённому графику, для акций - даты закрытия
+// реестра под дивиденды по данным Мосбиржи. События упорядочены по дате.
+func (s *SecuritiesService) CalendarEvents(ctx context.Context, isin string, quantity int64, from time.Time) ([]models.CalendarEvent, error) {
+	sec, market, err := s.repo.GetSecurity(isin)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %w", ErrStorage, err)
+	}
+	ticker := sec.Ticker
+	if ticker == "" {
+		ticker = isin
+	}
+
+	var events []models.CalendarEvent
+	if market == gomoex.MarketShares {
+		events, err = s.dividendEvents(ctx, isin, ticker, quantity)
+	} else {
+		events, err = s.bondEvents(ctx, isin, ticker, quantity)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	start := from.Format(time.DateOnly)
+	filtered := []models.CalendarEvent{}
+	for _, e := range events {
+		if e.Date >= start {
+			filtered = append(filtered, e)
+		}
+	}
+	sort.SliceStable(filtered, func(i, j int) bool { return filtered[i].Date < filtered[j].Date })
+	return filtered, nil
+}
+
+// bondEvents возвращает события по графику купонов, амортизаций и оферт облигации
+func (s *SecuritiesService) bondEvents(ctx context.Context, isin, ticker string, quantity int64) ([]models.CalendarEvent, error) {
+	coupons, amortizations, err := s.bondization(ctx, isin, false)
+	if err != nil {
+		return nil, err
+	}
+	offers, err := s.repo.GetOffers(isin)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrStorage, err)
+	}
+
+	q := float64(quantity)
+	event := func(date, kind string, value float64, unit string) models.CalendarEvent {
+		return models.CalendarEvent{Date: date, Isin: isin, Ticker: ticker, Type: kind, Quantity: quantity,
+			Value: value, Amount: roundFloat(value*q, 2), Currency: currencyCode(unit)}
+	}
+
+	var events []models.CalendarEvent
+	for _, c := range coupons {
+		events = append(events, event(c.Coupondate, models.EventCoupon, c.Value, c.Faceunit))
+		if c.Recorddate != "" {
+			events = append(events, event(c.Recorddate, models.EventCouponRecord, c.Value, c.Faceunit))
+		}
+	}
+	// Последняя выплата номинала - погашение, в том числе частичная выплата оставшегося номинала
+	// амортизируемой облигации
+	var maturity string
+	for _, a := range amortizations {
+		maturity = max(maturity, a.Amortdate)
+	}
+	for _, a := range amortizations {
+		kind := models.EventAmortization
+		if a.Amortdate == maturity {
+			kind = models.EventMaturity
+		}
+		events = append(events, event(a.Amortdate, kind, a.Value, a.Faceunit))
+	}
+	for _, o := range offers {
+		e := newOfferEvent(o)
+		events = append(events, event(o.Offerdate, models.EventOffer, roundFloat(o.Facevalue*e.Price/100, 2), o.Faceunit))
+	}
+	return events, nil
+}
+
+// dividendEvents возвращает даты закрытия реестра под дивиденды акции
+func (s *SecuritiesService) dividendEvents(ctx context.Context, isin, ticker string, quantity int64) ([]models.CalendarEvent, error) {
+	dividends, err := s.md.Dividends(ctx, ticker)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]models.CalendarEvent, 0, len(dividends))
+	for _, d := range dividends {
+		events = append(events, models.CalendarEvent{Date: d.Date.Format(time.DateOnly), Isin: isin, Ticker: ticker,
+			Type: models.EventDividend, Quantity: quantity, Value: d.Dividend,
+			Amount: roundFloat(d.Dividend*float64(quantity), 2), Currency: currencyCode(d.Currency)})
+	}
+	return events, nil
+}
@@ -0,0 +1,66 @@
+package securities
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"simple-invest/internal/models"
+	"simple-invest/internal/securities/moextest"
+
+	"github.com/WLM1ke/gomoex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecuritiesService_CalendarEvents(t *testing.T) {
+	repo := newMemRepo()
+	repo.shares = []gomoex.Security{{Ticker: moextest.TickerShare, ISIN: moextest.ISINShare, Board: gomoex.BoardTQBR}}
+	s := New(repo, newTestMoexClient(t), Options{})
+	ctx := context.Background()
+	from := time.Date(2024, 6, 5, 0, 0, 0, 0, time.UTC)
+
+	got, err := s.CalendarEvents(ctx, moextest.ISINOffer, 2, from)
+	require.NoError(t, err)
+	require.NotEmpty(t, got)
+	// Прошедшие события не включаются, события упорядочены по дате
+	assert.Equal(t, models.CalendarEvent{Date: "2024-12-02", Isin: moextest.ISINOffer, Ticker: moextest.ISINOffer,
+		Type: models.EventCouponRecord, Quantity: 2, Value: 59.84, Amount: 119.68, Currency: "RUB"}, got[0])
+	assert.Equal(t, models.EventCoupon, got[1].Type)
+	assert.Equal(t, "2024-12-03", got[1].Date)
+	kinds := make(map[string]int)
+	for i, e := range got {
+		kinds[e.Type]++
+		if i > 0 {
+			assert.LessOrEqual(t, got[i-1].Date, e.Date)
+		}
+	}
+	assert.Equal(t, map[string]int{models.EventCoupon: 8, models.EventCouponRecord: 8, models.EventOffer: 2, models.EventMaturity: 1}, kinds)
+	// Сумма выкупа по call-оферте - по цене выкупа выше номинала
+	assert.Contains(t, got, models.CalendarEvent{Date: "2026-12-01", Isin: moextest.ISINOffer, Ticker: moextest.ISINOffer,
+		Type: models.EventOffer, Quantity: 2, Value: 1010, Amount: 2020, Currency: "RUB"})
+
+	// Последняя выплата номинала амортизируемой облигации - погашение
+	got, err = s.CalendarEvents(ctx, moextest.ISINAmortizing, 1, from)
+	require.NoError(t, err)
+	last := got[len(got)-1]
+	assert.Equal(t, models.EventMaturity, last.Type)
+	assert.Equal(t, 250.0, last.Amount)
+	assert.Equal(t, models.EventAmortization, got[len(got)-4].Type)
+
+	// Погашение определяется по дате, даже если в графике указан первоначальный номинал
+	for i := range repo.amortizations[moextest.ISINAmortizing] {
+		repo.amortizations[moextest.ISINAmortizing][i].Facevalue = 1000
+	}
+	stored := New(repo, newTestMoexClient(t), Options{BondizationMaxAge: time.Hour})
+	got, err = stored.CalendarEvents(ctx, moextest.ISINAmortizing, 1, from)
+	require.NoError(t, err)
+	assert.Equal(t, models.EventMaturity, got[len(got)-1].Type)
+	assert.Equal(t, models.EventAmortization, got[len(got)-4].Type)
+
+	// Для акций - даты закрытия реестра под дивиденды
+	got, err = s.CalendarEvents(ctx, moextest.ISINShare, 10, from)
+	require.NoError(t, err)
+	assert.Equal(t, []models.CalendarEvent{{Date: "2024-07-11", Isin: moextest.ISINShare, Ticker: moextest.TickerShare,
+		Type: models.EventDividend, Quantity: 10, Value: 33.3, Amount: 333, Currency: "RUB"}}, got)
+}
@@ -0,0 +1,153 @@
+package securities
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"simple-invest/internal/models"
+	"simple-invest/internal/tax"
+
+	"github.com/WLM1ke/gomoex"
+)
+
+// CashFlows возвращает график будущих выплат по партиям облигаций isin до погашения с НДФЛ по налоговой
+// модели tm. Ближайшая оферта добавляется в график отдельной выплатой - суммой выкупа в дату оферты, а выплаты
+// после оферты соответствуют владению облигацией до погашения. Налог при погашении и выкупе рассчитывается
+// по каждой партии от стоимости приобретения одной бумаги, а если она не задана - от текущей цены с НКД;
+// ЛДВ определяется по дате приобретения партии. Без цены налог при погашении не рассчитывается.
+// Выплаты по партиям в одну дату объединяются.
+func (s *SecuritiesService) CashFlows(ctx context.Context, isin string, lots []models.Lot, tm tax.Model) ([]models.CashFlow, error) {
+	_, market, err := s.repo.GetSecurity(isin)
+	if err == nil && market == gomoex.MarketShares {
+		return nil, ErrNotBond
+	}
+
+	opts := IndicatorOptions{Tax: tm}
+	d, err := s.bondData(ctx, isin, opts)
+	if err != nil {
+		return nil, err
+	}
+	today := time.Now().Truncate(time.Hour * 24)
+	c, err := newBondCalc(d, opts, today)
+	if err != nil {
+		return nil, err
+	}
+
+	// График до погашения строится без учёта оферт, как и доходность к погашению
+	m := c
+	if c.offer != nil {
+		toMaturity := d
+		toMaturity.bond.OfferDate = ""
+		toMaturity.offers = nil
+		m, err = newBondCalc(toMaturity, opts, today)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var marketCost *float64
+	flows := []models.CashFlow{}
+	for _, lot := range lots {
+		cost := lot.Cost
+		if cost == 0 {
+			if marketCost == nil {
+				marketCost = new(float64)
+				marketData, err := s.md.BondMarketData(ctx, isin)
+				if err != nil && !errors.Is(err, errNoMoexData) {
+					return nil, err
+				}
+				if price, ok := marketData.price(s.opts.PriceSources); ok {
+					*marketCost = roundFloat(c.face*price.Price/100, 2) + c.accruedInt
+				}
+			}
+			cost = *marketCost
+		}
+
+		c.acquired, m.acquired = lot.Date, lot.Date
+		lotFlows := m.cashFlows(lot.Quantity, cost)
+		if m != c {
+			if toOffer := c.cashFlows(lot.Quantity, cost); len(toOffer) > 0 && toOffer[len(toOffer)-1].Type == models.CashFlowOffer {
+				lotFlows = append(lotFlows, toOffer[len(toOffer)-1])
+			}
+		}
+		flows = mergeCashFlows(flows, lotFlows)
+	}
+	sort.SliceStable(flows, func(i, j int) bool { return flows[i].Date < flows[j].Date })
+	return flows, nil
+}
+
+// mergeCashFlows добавляет к выплатам flows выплаты add, суммируя выплаты одного вида в одну дату
+func mergeCashFlows(flows, add []models.CashFlow) []models.CashFlow {
+	for _, a := range add {
+		i := slices.IndexFunc(flows, func(f models.CashFlow) bool { return f.Date == a.Date && f.Type == a.Type })
+		if i < 0 {
+			flows = append(flows, a)
+			continue
+		}
+		f := &flows[i]
+		f.Quantity += a.Quantity
+		f.Amount = roundFloat(f.Amount+a.Amount, 2)
+		f.Tax = roundFloat(f.Tax+a.Tax, 2)
+		f.Net = roundFloat(f.Net+a.Net, 2)
+	}
+	return flows
+}
+
+// cashFlows формирует график выплат по quantity облигациям, купленным по цене cost за одну бумагу.
+// Выплата номинала в дату события - выкуп по оферте или погашение, остальные выплаты номинала - амортизации.
+func (c *bondCalc) cashFlows(quantity int64, cost float64) []models.CashFlow {
+	known := make(map[string]float64, len(c.schedule))
+	for _, cp := range c.schedule {
+		known[cp.Coupondate] = cp.Value
+	}
+	estimatedFace := c.bondType == BondLinker || c.bondType == BondPerpetual
+
+	currency := currencyCode(c.bond.FaceUnit)
+	q := float64(quantity)
+	flow := func(date time.Time, kind string, amount, tax float64, estimated bool) models.CashFlow {
+		amount, tax = roundFloat(amount*q, 2), roundFloat(tax*q, 2)
+		return models.CashFlow{
+			Date:      date.Format(time.DateOnly),
+			Isin:      c.bond.Isin,
+			Type:      kind,
+			Quantity:  quantity,
+			Amount:    amount,
+			Tax:       tax,
+			Net:       roundFloat(amount-tax, 2),
+			Currency:  currency,
+			Estimated: estimated,
+		}
+	}
+
+	flows := []models.CashFlow{}
+	for _, cf := range c.flows {
+		date := cf.Date.Format(time.DateOnly)
+		if cf.Coupon > 0 {
+			value, ok := known[date]
+			estimated := !ok || math.Abs(value-cf.Coupon) > 0.005
+			flows = append(flows, flow(cf.Date, models.CashFlowCoupon, cf.Coupon, cf.Coupon*c.couponTaxRate, estimated))
+		}
+		if cf.Principal <= 0 {
+			continue
+		}
+		if !cf.Date.Equal(c.eventDate) {
+			flows = append(flows, flow(cf.Date, models.CashFlowAmortization, cf.Principal, 0, false))
+			continue
+		}
+
+		kind := models.CashFlowMaturity
+		if c.offer != nil {
+			kind = models.CashFlowOffer
+		}
+		tax := 0.0
+		if cost > 0 {
+			tax = c.maturityTax(cost)
+		}
+		flows = append(flows, flow(cf.Date, kind, cf.Principal, tax, estimatedFace))
+	}
+	return flows
+}
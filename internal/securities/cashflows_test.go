@@ -0,0 +1,84 @@
+package securities
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"simple-invest/internal/models"
+	"simple-invest/internal/securities/moextest"
+	"simple-invest/internal/tax"
+
+	"github.com/WLM1ke/gomoex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecuritiesService_CashFlows(t *testing.T) {
+	repo := newMemRepo()
+	repo.shares = []gomoex.Security{{Ticker: moextest.TickerShare, ISIN: moextest.ISINShare, Board: gomoex.BoardTQBR}}
+	s := New(repo, newTestMoexClient(t), Options{})
+	ctx := context.Background()
+	tm := tax.Model{Year: 2024, Resident: true}
+
+	// Купоны и погашение по графику до погашения, выкуп по ближайшей оферте - отдельной выплатой
+	got, err := s.CashFlows(ctx, moextest.ISINOffer, []models.Lot{{Quantity: 2, Cost: 950}}, tm)
+	require.NoError(t, err)
+	require.Len(t, got, 10)
+	for i, cf := range got[:len(got)-1] {
+		if i == 3 {
+			continue
+		}
+		assert.Equal(t, models.CashFlowCoupon, cf.Type)
+		assert.Equal(t, 119.68, cf.Amount)
+		assert.Equal(t, 15.56, cf.Tax)
+		assert.Equal(t, 104.12, cf.Net)
+		assert.False(t, cf.Estimated)
+	}
+	assert.Equal(t, models.CashFlow{
+		Date: "2025-12-02", Isin: moextest.ISINOffer, Type: models.CashFlowOffer, Quantity: 2,
+		Amount: 2000, Tax: 13, Net: 1987, Currency: "RUB",
+	}, got[3])
+	assert.Greater(t, got[4].Date, got[3].Date)
+	assert.Equal(t, models.CashFlow{
+		Date: "2028-05-30", Isin: moextest.ISINOffer, Type: models.CashFlowMaturity, Quantity: 2,
+		Amount: 2000, Net: 2000, Currency: "RUB",
+	}, got[9])
+
+	// Без стоимости приобретения налог при выкупе рассчитывается от текущей цены
+	got, err = s.CashFlows(ctx, moextest.ISINOffer, []models.Lot{{Quantity: 1}}, tm)
+	require.NoError(t, err)
+	require.Len(t, got, 10)
+	assert.Equal(t, models.CashFlowOffer, got[3].Type)
+	assert.Equal(t, 1.43, got[3].Tax)
+
+	// Выплаты по партиям объединяются, ЛДВ определяется по дате приобретения партии
+	lots := []models.Lot{
+		{Quantity: 1, Cost: 950, Date: time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)},
+		{Quantity: 2, Cost: 950},
+	}
+	got, err = s.CashFlows(ctx, moextest.ISINOffer, lots, tm)
+	require.NoError(t, err)
+	require.Len(t, got, 10)
+	assert.Equal(t, models.CashFlow{
+		Date: "2025-12-02", Isin: moextest.ISINOffer, Type: models.CashFlowOffer, Quantity: 3,
+		Amount: 3000, Tax: 13, Net: 2987, Currency: "RUB",
+	}, got[3])
+	assert.Equal(t, 179.52, got[0].Amount)
+
+	// Амортизации до погашения
+	got, err = s.CashFlows(ctx, moextest.ISINAmortizing, []models.Lot{{Quantity: 1, Cost: 1000}}, tm)
+	require.NoError(t, err)
+	var amortizations int
+	for _, cf := range got {
+		if cf.Type == models.CashFlowAmortization {
+			amortizations++
+			assert.Zero(t, cf.Tax)
+		}
+	}
+	assert.Positive(t, amortizations)
+	assert.Equal(t, models.CashFlowMaturity, got[len(got)-1].Type)
+
+	_, err = s.CashFlows(ctx, moextest.ISINShare, []models.Lot{{Quantity: 1}}, tm)
+	assert.ErrorIs(t, err, ErrNotBond)
+}
@@ -0,0 +1,84 @@
+package securities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"simple-invest/internal/tax"
+
+	"github.com/WLM1ke/gomoex"
+)
+
+// ErrStorage возвращается при ошибке чтения или сохранения данных в БД
+var ErrStorage = errors.New("cannot get stored data")
+
+// DividendYield содержит дивидендную доходность акции за год, предшествующий дате расчёта
+type DividendYield struct {
+	Ticker    string  `json:"ticker"`    // Тикер
+	Isin      string  `json:"isin"`      // ISIN код
+	Date      string  `json:"date"`      // Дата расчёта
+	Price     float64 `json:"price"`     // Цена последней сделки
+	Dividends float64 `json:"dividends"` // Сумма дивидендов с датой закрытия реестра за год до даты расчёта
+	Payments  int     `json:"payments"`  // Количество выплат
+	Yield     float64 `json:"yield"`     // Дивидендная доходность
+	NetYield  float64 `json:"net_yield"` // Дивидендная доходность после налога
+	TaxRate   float64 `json:"tax_rate"`  // Ставка налога на дивиденды
+}
+
+// DividendYield рассчитывает дивидендную доходность акции по дивидендам за год до даты date и текущей цене.
+// Доходность после налога рассчитывается по модели tm.
+func (s *SecuritiesService) DividendYield(ctx context.Context, ticker string, date time.Time, tm tax.Model) (DividendYield, error) {
+	dy := DividendYield{Ticker: ticker, Date: date.Format(time.DateOnly)}
+
+	board := gomoex.BoardTQBR
+	shares, err := s.repo.GetShares()
+	if err != nil {
+		return dy, fmt.Errorf("%w: %w", ErrStorage, err)
+	}
+	for _, sh := range shares {
+		if sh.Ticker == ticker {
+			board = sh.Board
+			dy.Isin = sh.ISIN
+		}
+	}
+
+	dividends, err := s.md.Dividends(ctx, ticker)
+	if err != nil {
+		return dy, err
+	}
+	marketData, err := s.md.ShareMarketData(ctx, board, ticker)
+	if err != nil {
+		return dy, err
+	}
+
+	dy.Dividends, dy.Payments = trailingDividends(dividends, date)
+	for _, d := range dividends {
+		if dy.Isin == "" {
+			dy.Isin = d.ISIN
+		}
+	}
+	dy.Price = marketData.Last
+	dy.TaxRate = tm.Rate(tax.DividendKind(dy.Isin))
+	if dy.Price > 0 {
+		dy.Yield = roundFloat(dy.Dividends/dy.Price, precision)
+		dy.NetYield = roundFloat(dy.Dividends*(1-dy.TaxRate)/dy.Price, precision)
+	}
+
+	return dy, nil
+}
+
+// trailingDividends возвращает сумму и количество дивидендов с датой закрытия реестра в течение года до даты date
+func trailingDividends(dividends []gomoex.Dividend, date time.Time) (float64, int) {
+	from := date.AddDate(-1, 0, 0)
+	sum := 0.0
+	count := 0
+	for _, d := range dividends {
+		if d.Date.After(from) && !d.Date.After(date) && d.Dividend > 0 {
+			sum += d.Dividend
+			count++
+		}
+	}
+	return roundFloat(sum, precision), count
+}
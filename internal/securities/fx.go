@@ -0,0 +1,347 @@
+package securities
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	rubleCode     = "RUB"                // Код рубля ISO 4217
+	DefaultCBRURL = "https://www.cbr.ru" // Адрес сайта Банка России
+)
+
+// Источники курсов валют
+const (
+	FXMoex        = "moex"        // Биржевой курс расчётов "завтра" валютного рынка Мосбиржи
+	FXCBR         = "cbr"         // Официальный курс Банка России
+	FXStatic      = "static"      // Курсы, заданные в настройках
+	fxBondization = "bondization" // Отношение рублёвой суммы купона к сумме в валюте по графику выплат
+)
+
+// Инструменты валютного рынка Мосбиржи (режим CETS) для получения курса валюты к рублю
+var moexFXInstruments = map[string]string{
+	"USD": "USD000UTSTOM",
+	"EUR": "EUR_RUB__TOM",
+	"CNY": "CNYRUB_TOM",
+	"HKD": "HKDRUB_TOM",
+	"GBP": "GBPRUB_TOM",
+	"CHF": "CHFRUB_TOM",
+	"JPY": "JPYRUB_TOM",
+}
+
+var errUnknownCurrency = errors.New("unknown currency")
+
+// FXRate - курс валюты к рублю
+type FXRate struct {
+	Currency string  `json:"currency"` // Код валюты
+	Rate     float64 `json:"rate"`     // Стоимость единицы валюты, руб.
+	Date     string  `json:"date"`     // Дата курса
+	Source   string  `json:"source"`   // Источник курса
+}
+
+// FXSource представляет источник курсов валют
+type FXSource interface {
+	// Rate возвращает курс валюты currency к рублю на дату date
+	Rate(ctx context.Context, currency string, date time.Time) (FXRate, error)
+}
+
+// MoexFX получает текущие курсы валют с валютного рынка Мосбиржи. Курс на прошедшую дату не поддерживается,
+// возвращается курс последней сделки.
+type MoexFX struct {
+	client *MoexClient
+}
+
+func NewMoexFX(client *MoexClient) *MoexFX {
+	return &MoexFX{client: client}
+}
+
+// Rate возвращает курс последней сделки, а при её отсутствии - средневзвешенный курс
+func (fx *MoexFX) Rate(ctx context.Context, currency string, _ time.Time) (FXRate, error) {
+	rate := FXRate{Currency: currency, Source: FXMoex}
+	secid, ok := moexFXInstruments[currency]
+	if !ok {
+		return rate, fmt.Errorf("%w %q for moex fx", errUnknownCurrency, currency)
+	}
+
+	path := fmt.Sprintf("/iss/engines/currency/markets/selt/boards/CETS/securities/%s.json", secid)
+	query := url.Values{"iss.only": {"marketdata"}, "marketdata.columns": {"LAST,WAPRICE,SYSTIME"}}
+
+	var moexData struct {
+		MarketData issTable `json:"marketdata"`
+	}
+	if err := fx.client.getJSON(ctx, path, query, &moexData); err != nil {
+		return rate, err
+	}
+
+	rows := moexData.MarketData.rows()
+	if len(rows) == 0 {
+		return rate, errNoMoexData
+	}
+	rate.Rate, _ = rows[0]["LAST"].(float64)
+	if rate.Rate == 0 {
+		rate.Rate, _ = rows[0]["WAPRICE"].(float64)
+	}
+	if rate.Rate == 0 {
+		return rate, errNoMoexData
+	}
+	if systime, _ := rows[0]["SYSTIME"].(string); len(systime) >= len(time.DateOnly) {
+		rate.Date = systime[:len(time.DateOnly)]
+	}
+	return rate, nil
+}
+
+// CBRClient получает официальные курсы валют Банка России
+type CBRClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewCBRClient создаёт клиент с адресом baseURL. Пустой адрес соответствует DefaultCBRURL.
+func NewCBRClient(baseURL string, client *http.Client) *CBRClient {
+	if baseURL == "" {
+		baseURL = DefaultCBRURL
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &CBRClient{baseURL: strings.TrimSuffix(baseURL, "/"), client: client}
+}
+
+// cbrRates - ответ Банка России с курсами валют на дату
+type cbrRates struct {
+	Date    string `xml:"Date,attr"`
+	Valutes []struct {
+		CharCode string `xml:"CharCode"`
+		Nominal  int    `xml:"Nominal"`
+		Value    string `xml:"Value"`
+	} `xml:"Valute"`
+}
+
+// Rate возвращает официальный курс, установленный Банком России на дату date
+func (c *CBRClient) Rate(ctx context.Context, currency string, date time.Time) (FXRate, error) {
+	rate := FXRate{Currency: currency, Source: FXCBR}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	query := url.Values{"date_req": {date.Format("02/01/2006")}}
+	u := fmt.Sprintf("%s/scripts/XML_daily.asp?%s", c.baseURL, query.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return rate, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return rate, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return rate, fmt.Errorf("cbr response status %s", resp.Status)
+	}
+
+	var rates cbrRates
+	decoder := xml.NewDecoder(resp.Body)
+	decoder.CharsetReader = charsetReader
+	if err := decoder.Decode(&rates); err != nil {
+		return rate, err
+	}
+
+	if d, err := time.Parse("02.01.2006", rates.Date); err == nil {
+		rate.Date = d.Format(time.DateOnly)
+	}
+	for _, v := range rates.Valutes {
+		if v.CharCode != currency {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.Replace(v.Value, ",", ".", 1), 64)
+		if err != nil {
+			return rate, err
+		}
+		if v.Nominal > 1 {
+			value /= float64(v.Nominal)
+		}
+		rate.Rate = value
+		return rate, nil
+	}
+	return rate, fmt.Errorf("%w %q for cbr rates", errUnknownCurrency, currency)
+}
+
+// Символы Unicode для байтов 0x80-0xFF кодировки windows-1251
+var cp1251 = [128]rune{
+	0x0402, 0x0403, 0x201A, 0x0453, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x20AC, 0x2030, 0x0409, 0x2039, 0x040A, 0x040C, 0x040B, 0x040F,
+	0x0452, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0xFFFD, 0x2122, 0x0459, 0x203A, 0x045A, 0x045C, 0x045B, 0x045F,
+	0x00A0, 0x040E, 0x045E, 0x0408, 0x00A4, 0x0490, 0x00A6, 0x00A7,
+	0x0401, 0x00A9, 0x0404, 0x00AB, 0x00AC, 0x00AD, 0x00AE, 0x0407,
+	0x00B0, 0x00B1, 0x0406, 0x0456, 0x0491, 0x00B5, 0x00B6, 0x00B7,
+	0x0451, 0x2116, 0x0454, 0x00BB, 0x0458, 0x0405, 0x0455, 0x0457,
+	0x0410, 0x0411, 0x0412, 0x0413, 0x0414, 0x0415, 0x0416, 0x0417,
+	0x0418, 0x0419, 0x041A, 0x041B, 0x041C, 0x041D, 0x041E, 0x041F,
+	0x0420, 0x0421, 0x0422, 0x0423, 0x0424, 0x0425, 0x0426, 0x0427,
+	0x0428, 0x0429, 0x042A, 0x042B, 0x042C, 0x042D, 0x042E, 0x042F,
+	0x0430, 0x0431, 0x0432, 0x0433, 0x0434, 0x0435, 0x0436, 0x0437,
+	0x0438, 0x0439, 0x043A, 0x043B, 0x043C, 0x043D, 0x043E, 0x043F,
+	0x0440, 0x0441, 0x0442, 0x0443, 0x0444, 0x0445, 0x0446, 0x0447,
+	0x0448, 0x0449, 0x044A, 0x044B, 0x044C, 0x044D, 0x044E, 0x044F,
+}
+
+// charsetReader преобразует ответ Банка России в кодировке windows-1251 в UTF-8
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	if !strings.EqualFold(charset, "windows-1251") {
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	b.Grow(len(data) * 2)
+	for _, c := range data {
+		if c < 0x80 {
+			b.WriteByte(c)
+		} else {
+			b.WriteRune(cp1251[c-0x80])
+		}
+	}
+	return strings.NewReader(b.String()), nil
+}
+
+// StaticFX возвращает курсы валют, заданные в настройках
+type StaticFX map[string]float64
+
+// ParseStaticFX разбирает курсы в формате "USD=90.5,CNY=12.4"
+func ParseStaticFX(s string) (StaticFX, error) {
+	fx := StaticFX{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		currency, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("incorrect fx rate %q, expected CUR=value", pair)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("incorrect fx rate %q, expected CUR=value", pair)
+		}
+		fx[strings.ToUpper(strings.TrimSpace(currency))] = rate
+	}
+	return fx, nil
+}
+
+// Rate возвращает заданный курс независимо от даты
+func (fx StaticFX) Rate(_ context.Context, currency string, date time.Time) (FXRate, error) {
+	rate, ok := fx[currency]
+	if !ok {
+		return FXRate{Currency: currency, Source: FXStatic}, fmt.Errorf("%w %q for static fx", errUnknownCurrency, currency)
+	}
+	return FXRate{Currency: currency, Rate: rate, Date: date.Format(time.DateOnly), Source: FXStatic}, nil
+}
+
+// couponFXRate рассчитывает курс по ближайшему к дате date купону, для которого известны суммы в валюте и в рублях
+func couponFXRate(coupons []Coupon, currency string, date time.Time) (FXRate, error) {
+	rate := FXRate{Currency: currency, Source: fxBondization}
+	best := math.MaxFloat64
+	for _, c := range coupons {
+		if c.Value <= 0 || c.ValueRub <= 0 {
+			continue
+		}
+		cd, err := time.Parse(time.DateOnly, c.Coupondate)
+		if err != nil {
+			return rate, err
+		}
+		if dist := math.Abs(cd.Sub(date).Hours()); dist < best {
+			best = dist
+			rate.Rate = roundFloat(c.ValueRub/c.Value, precision)
+			rate.Date = c.Coupondate
+		}
+	}
+	if rate.Rate == 0 {
+		return rate, fmt.Errorf("no fx rate for %s in bond payments", currency)
+	}
+	return rate, nil
+}
+
+// Показатели облигации с номиналом в иностранной валюте в рублях
+type fxIndicators struct {
+	Currency          string  `json:"currency"`            // Валюта номинала
+	Rate              float64 `json:"rate"`                // Курс валюты на дату расчётов, руб.
+	RateDate          string  `json:"rate_date"`           // Дата курса
+	RateSource        string  `json:"rate_source"`         // Источник курса
+	RateChange        float64 `json:"rate_change"`         // Ожидаемое изменение курса, % годовых
+	FaceValue         float64 `json:"facevalue"`           // Номинал, руб.
+	AccruedInt        float64 `json:"accruedint"`          // НКД, руб.
+	Price             float64 `json:"price"`               // Цена, руб.
+	EffectiveYield    float64 `json:"effective_yield"`     // Эффективная доходность в рублях
+	NetEffectiveYield float64 `json:"net_effective_yield"` // Итоговая эффективная доходность в рублях
+	MaturityTax       float64 `json:"maturity_tax"`        // Налог при погашении с учётом курсовой разницы, руб.
+	RevaluationTax    float64 `json:"revaluation_tax"`     // Часть налога при погашении, приходящаяся на курсовую разницу, руб.
+}
+
+// fxRate возвращает курс валюты currency на дату date из настроенного источника. При его отсутствии или
+// недоступности курс рассчитывается по рублёвым суммам купонов графика выплат coupons.
+func (s *SecuritiesService) fxRate(ctx context.Context, currency string, date time.Time, coupons []Coupon) (FXRate, error) {
+	if s.opts.FX != nil {
+		rate, err := s.opts.FX.Rate(ctx, currency, date)
+		if err == nil {
+			return rate, nil
+		}
+		log.Printf("fx rate %s from %T: %v, using bond payments", currency, s.opts.FX, err)
+	}
+	return couponFXRate(coupons, currency, date)
+}
+
+// rubIndicators рассчитывает показатели облигации в рублях по графику выплат в валюте номинала flows.
+// Курс на дату выплаты прогнозируется с постоянным темпом изменения change, % годовых. Налог при погашении
+// рассчитывается с разницы рублёвых сумм погашения и покупки и поэтому включает налог с курсовой разницы.
+func rubIndicators(flows []cashFlow, bI bondIndicators, rate FXRate, change float64, settleDate time.Time,
+	couponTaxRate, gainTaxRate float64, exempt bool) (*fxIndicators, error) {
+	fx := &fxIndicators{
+		Currency:   rate.Currency,
+		Rate:       rate.Rate,
+		RateDate:   rate.Date,
+		RateSource: rate.Source,
+		RateChange: change,
+		FaceValue:  roundFloat(bI.FaceValue*rate.Rate, 2),
+		AccruedInt: roundFloat(bI.AccruedInt*rate.Rate, 2),
+		Price:      roundFloat(bI.Price*rate.Rate, 2),
+	}
+
+	rubFlows := make([]cashFlow, len(flows))
+	principal := 0.0
+	for i, cf := range flows {
+		k := rate.Rate * math.Pow(1+change/100, yearFraction(settleDate, cf.Date))
+		rubFlows[i] = cashFlow{Date: cf.Date, Coupon: cf.Coupon * k, Principal: cf.Principal * k}
+		principal += rubFlows[i].Principal
+	}
+	if !exempt && principal > fx.Price {
+		fx.MaturityTax = roundFloat((principal-fx.Price)*gainTaxRate, 2)
+	}
+	fx.RevaluationTax = roundFloat(fx.MaturityTax-bI.MaturityTax*rate.Rate, 2)
+
+	if fx.Price <= 0 {
+		return fx, nil
+	}
+	effYield, err := effectiveYield(rubFlows, fx.Price, settleDate)
+	if err != nil {
+		return nil, err
+	}
+	netEffYield, err := effectiveYield(netCashFlows(rubFlows, couponTaxRate, fx.MaturityTax), fx.Price, settleDate)
+	if err != nil {
+		return nil, err
+	}
+	fx.EffectiveYield = roundFloat(effYield, precision)
+	fx.NetEffectiveYield = roundFloat(netEffYield, precision)
+	return fx, nil
+}
@@ -0,0 +1,112 @@
+package securities
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"simple-invest/internal/securities/moextest"
+	"simple-invest/internal/tax"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFXSource(t *testing.T) {
+	srv := moextest.NewServer(t)
+	moex, err := NewMoexClient(srv.URL, http.DefaultClient)
+	require.NoError(t, err)
+	date := time.Date(2024, time.June, 4, 0, 0, 0, 0, time.UTC)
+	static, err := ParseStaticFX("usd=90.5, CNY=12.4")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		source   FXSource
+		currency string
+		want     FXRate
+		wantErr  bool
+	}{
+		{"moex", NewMoexFX(moex), "USD", FXRate{Currency: "USD", Rate: 89.12, Date: "2024-06-04", Source: FXMoex}, false},
+		{"moex unknown", NewMoexFX(moex), "XAU", FXRate{}, true},
+		{"cbr", NewCBRClient(srv.URL, nil), "USD", FXRate{Currency: "USD", Rate: 89.0658, Date: "2024-06-04", Source: FXCBR}, false},
+		{"cbr nominal", NewCBRClient(srv.URL, nil), "JPY", FXRate{Currency: "JPY", Rate: 0.571234, Date: "2024-06-04", Source: FXCBR}, false},
+		{"cbr unknown", NewCBRClient(srv.URL, nil), "XAU", FXRate{}, true},
+		{"static", static, "USD", FXRate{Currency: "USD", Rate: 90.5, Date: "2024-06-04", Source: FXStatic}, false},
+		{"static unknown", static, "EUR", FXRate{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.source.Rate(context.Background(), tt.currency, date)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, errUnknownCurrency)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDelta(t, tt.want.Rate, got.Rate, 1e-9)
+			tt.want.Rate = got.Rate
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err = ParseStaticFX("USD:90")
+	assert.Error(t, err)
+}
+
+func Test_rubIndicators(t *testing.T) {
+	settleDate := time.Date(2024, time.June, 4, 0, 0, 0, 0, time.UTC)
+	flows := []cashFlow{{Date: settleDate.AddDate(1, 0, 0), Coupon: 50, Principal: 1000}}
+	bI := bondIndicators{FaceValue: 1000, Price: 1000}
+	rate := FXRate{Currency: "USD", Rate: 90, Source: FXStatic}
+
+	// При неизменном курсе доходность в рублях совпадает с доходностью в валюте, налога с курсовой разницы нет
+	fx, err := rubIndicators(flows, bI, rate, 0, settleDate, 0.13, 0.13, false)
+	require.NoError(t, err)
+	assert.Equal(t, 90000.0, fx.Price)
+	assert.Equal(t, 0.05, fx.EffectiveYield)
+	assert.Zero(t, fx.MaturityTax)
+	assert.Zero(t, fx.RevaluationTax)
+
+	// Рост курса на 10% облагается налогом при погашении номинала, купленного по номиналу
+	fx, err = rubIndicators(flows, bI, rate, 10, settleDate, 0.13, 0.13, false)
+	require.NoError(t, err)
+	assert.Equal(t, 0.155, fx.EffectiveYield)
+	assert.Equal(t, 1170.0, fx.MaturityTax)
+	assert.Equal(t, 1170.0, fx.RevaluationTax)
+	assert.Less(t, fx.NetEffectiveYield, fx.EffectiveYield)
+
+	// Льгота долгосрочного владения
+	fx, err = rubIndicators(flows, bI, rate, 10, settleDate, 0.13, 0.13, true)
+	require.NoError(t, err)
+	assert.Zero(t, fx.MaturityTax)
+}
+
+func TestSecuritiesService_BondIndicatorsFX(t *testing.T) {
+	md := newTestMoexClient(t)
+	opts := IndicatorOptions{Tax: tax.Default()}
+
+	s := New(newMemRepo(), md, Options{FX: NewMoexFX(md)})
+	got, err := s.BondIndicators(context.Background(), moextest.ISINCurrency, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "USD", got.FaceUnit)
+	require.NotNil(t, got.FX)
+	assert.Equal(t, FXMoex, got.FX.RateSource)
+	assert.Equal(t, 89.12, got.FX.Rate)
+	assert.Equal(t, roundFloat(got.Price*89.12, 2), got.FX.Price)
+	assert.InDelta(t, got.EffectiveYield, got.FX.EffectiveYield, 1e-4)
+
+	// Без источника курс рассчитывается по рублёвым суммам купонов
+	s = New(newMemRepo(), md, Options{})
+	got, err = s.BondIndicators(context.Background(), moextest.ISINCurrency, IndicatorOptions{Tax: tax.Default(), FXChange: 5})
+	require.NoError(t, err)
+	require.NotNil(t, got.FX)
+	assert.Equal(t, fxBondization, got.FX.RateSource)
+	assert.InDelta(t, 89.0658, got.FX.Rate, 0.01)
+	assert.Greater(t, got.FX.EffectiveYield, got.EffectiveYield)
+	assert.Positive(t, got.FX.RevaluationTax)
+
+	rub, err := s.BondIndicators(context.Background(), moextest.ISINOfz, opts)
+	require.NoError(t, err)
+	assert.Nil(t, rub.FX)
+}
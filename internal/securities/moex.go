@@ -0,0 +1,459 @@
+package securities
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"simple-invest/internal/models"
+
+	"github.com/WLM1ke/gomoex"
+)
+
+const (
+	DefaultMoexURL = "https://iss.moex.com" // Адрес ISS Мосбиржи
+	requestTimeout = time.Second * 10       // Таймаут запроса к ISS
+)
+
+// MarketData представляет источник рыночных и справочных данных Мосбиржи
+type MarketData interface {
+	// BoardSecurities возвращает список бумаг, торгуемых в режиме board
+	BoardSecurities(ctx context.Context, engine, market, board string) ([]gomoex.Security, error)
+	// Dividends возвращает дивиденды акции
+	Dividends(ctx context.Context, ticker string) ([]gomoex.Dividend, error)
+	// Coupons возвращает график купонов облигации
+	Coupons(ctx context.Context, isin string) ([]Coupon, error)
+	// Amortizations возвращает график амортизационных выплат облигации
+	Amortizations(ctx context.Context, isin string) ([]Amortization, error)
+	// Offers возвращает график оферт облигации
+	Offers(ctx context.Context, isin string) ([]Offer, error)
+	// Bond возвращает основные свойства облигации
+	Bond(ctx context.Context, isin string) (Bond, error)
+	// BondMarketData возвращает торговые данные облигации
+	BondMarketData(ctx context.Context, isin string) (BondMarketData, error)
+	// ShareMarketData возвращает торговые данные акции в режиме board
+	ShareMarketData(ctx context.Context, board, ticker string) (ShareMarketData, error)
+	// IndexValue возвращает текущее значение индикатора индексного рынка, например ставки RUONIA
+	IndexValue(ctx context.Context, index string) (float64, error)
+}
+
+// MoexClient получает данные от ISS Мосбиржи
+type MoexClient struct {
+	baseURL string
+	client  *http.Client
+	iss     *gomoex.ISSClient
+}
+
+// NewMoexClient создаёт клиент ISS с адресом baseURL. Пустой адрес соответствует DefaultMoexURL.
+func NewMoexClient(baseURL string, client *http.Client) (*MoexClient, error) {
+	if baseURL == "" {
+		baseURL = DefaultMoexURL
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	// Библиотека gomoex обращается к ISS по фиксированному адресу, поэтому её запросы перенаправляются на baseURL
+	issClient := client
+	if baseURL != DefaultMoexURL {
+		base, err := url.Parse(baseURL)
+		if err != nil {
+			return nil, err
+		}
+		next := client.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		redirected := *client
+		redirected.Transport = &rewriteTransport{base: base, next: next}
+		issClient = &redirected
+	}
+
+	return &MoexClient{
+		baseURL: baseURL,
+		client:  client,
+		iss:     gomoex.NewISSClient(issClient),
+	}, nil
+}
+
+// rewriteTransport заменяет схему и адрес сервера в запросах
+type rewriteTransport struct {
+	base *url.URL
+	next http.RoundTripper
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.URL.Scheme = t.base.Scheme
+	r.URL.Host = t.base.Host
+	r.Host = t.base.Host
+	return t.next.RoundTrip(r)
+}
+
+// issTable представляет таблицу ответа ISS
+type issTable struct {
+	Columns []string        `json:"columns"` // Названия колонок
+	Data    [][]interface{} `json:"data"`    // Данные
+}
+
+// rows возвращает строки таблицы в виде отображения "колонка - значение"
+func (t issTable) rows() []map[string]interface{} {
+	rows := make([]map[string]interface{}, 0, len(t.Data))
+	for _, data := range t.Data {
+		row := make(map[string]interface{}, len(t.Columns))
+		for i, col := range t.Columns {
+			if i < len(data) {
+				row[col] = data[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// getJSON выполняет запрос к ISS и разбирает ответ в v
+func (c *MoexClient) getJSON(ctx context.Context, path string, query url.Values, v any) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	query.Set("iss.meta", "off")
+	u := fmt.Sprintf("%s%s?%s", c.baseURL, path, query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("moex response status %s for %s", resp.Status, path)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+// BoardSecurities возвращает список бумаг, торгуемых в режиме board
+func (c *MoexClient) BoardSecurities(ctx context.Context, engine, market, board string) ([]gomoex.Security, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	return c.iss.BoardSecurities(ctx, engine, market, board)
+}
+
+// Dividends возвращает дивиденды акции
+func (c *MoexClient) Dividends(ctx context.Context, ticker string) ([]gomoex.Dividend, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	return c.iss.Dividends(ctx, ticker)
+}
+
+// Coupons получает данные о купонах по облигации
+func (c *MoexClient) Coupons(ctx context.Context, isin string) ([]Coupon, error) {
+	path := fmt.Sprintf("/iss/statistics/engines/stock/markets/bonds/bondization/%s.json", isin)
+
+	// Получение общего объёма данных и объёма, получаемого за одно обращение
+	var couponsCursor struct {
+		Cursor struct {
+			Data [][]int64 `json:"data"`
+		} `json:"coupons.cursor"`
+	}
+	err := c.getJSON(ctx, path, url.Values{"iss.only": {"coupons.cursor"}}, &couponsCursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(couponsCursor.Cursor.Data) < 1 || len(couponsCursor.Cursor.Data[0]) < 3 {
+		return nil, errors.New("incorrect data structure")
+	}
+
+	i := couponsCursor.Cursor.Data[0][0]
+	total := couponsCursor.Cursor.Data[0][1]
+	pagesize := couponsCursor.Cursor.Data[0][2]
+
+	var coupons []Coupon
+	// Последовательное получение блоков данных
+	for ; i < total; i += pagesize {
+		var bondPayments BondPayments
+		query := url.Values{"iss.only": {"coupons"}, "start": {fmt.Sprint(i)}}
+		if err := c.getJSON(ctx, path, query, &bondPayments); err != nil {
+			return nil, err
+		}
+
+		// Преобразуем данные в структуру Coupon
+		for _, row := range bondPayments.Coupons.rows() {
+			coupon := Coupon{}
+			coupon.Isin, _ = row["isin"].(string)
+			coupon.Coupondate, _ = row["coupondate"].(string)
+			coupon.Recorddate, _ = row["recorddate"].(string)
+			coupon.Initialfacevalue, _ = row["initialfacevalue"].(float64)
+			coupon.Facevalue, _ = row["facevalue"].(float64)
+			coupon.Faceunit, _ = row["faceunit"].(string)
+			coupon.Value, _ = row["value"].(float64)
+			coupon.Valueprc, _ = row["valueprc"].(float64)
+			coupon.ValueRub, _ = row["value_rub"].(float64)
+			coupons = append(coupons, coupon)
+		}
+	}
+
+	return coupons, nil
+}
+
+// Amortizations получает данные об амортизационных выплатах по облигации
+func (c *MoexClient) Amortizations(ctx context.Context, isin string) ([]Amortization, error) {
+	path := fmt.Sprintf("/iss/statistics/engines/stock/markets/bonds/bondization/%s.json", isin)
+
+	var bondPayments BondPayments
+	if err := c.getJSON(ctx, path, url.Values{"iss.only": {"amortizations"}}, &bondPayments); err != nil {
+		return nil, err
+	}
+
+	// Преобразуем полученные по API данные в структуру Amortization
+	var amortizations []Amortization
+	for _, row := range bondPayments.Amortizations.rows() {
+		amortization := Amortization{}
+		amortization.Isin, _ = row["isin"].(string)
+		amortization.Amortdate, _ = row["amortdate"].(string)
+		amortization.Facevalue, _ = row["facevalue"].(float64)
+		amortization.Initialfacevalue, _ = row["initialfacevalue"].(float64)
+		amortization.Faceunit, _ = row["faceunit"].(string)
+		amortization.Value, _ = row["value"].(float64)
+		amortization.ValueRub, _ = row["value_rub"].(float64)
+		date, err := time.Parse(time.DateOnly, amortization.Amortdate)
+		if err != nil {
+			return nil, fmt.Errorf("amortization %s: %w", isin, err)
+		}
+		amortization.Date = date
+		amortizations = append(amortizations, amortization)
+	}
+
+	return amortizations, nil
+}
+
+// Offers получает данные об офертах по облигации
+func (c *MoexClient) Offers(ctx context.Context, isin string) ([]Offer, error) {
+	path := fmt.Sprintf("/iss/statistics/engines/stock/markets/bonds/bondization/%s.json", isin)
+
+	var bondPayments BondPayments
+	if err := c.getJSON(ctx, path, url.Values{"iss.only": {"offers"}}, &bondPayments); err != nil {
+		return nil, err
+	}
+
+	var offers []Offer
+	for _, row := range bondPayments.Offers.rows() {
+		offer := Offer{}
+		offer.Isin, _ = row["isin"].(string)
+		offer.Offerdate, _ = row["offerdate"].(string)
+		offer.OfferdateStart, _ = row["offerdatestart"].(string)
+		offer.OfferdateEnd, _ = row["offerdateend"].(string)
+		offer.Facevalue, _ = row["facevalue"].(float64)
+		offer.Faceunit, _ = row["faceunit"].(string)
+		offer.Price, _ = row["price"].(float64)
+		offer.Value, _ = row["value"].(float64)
+		offer.Agent, _ = row["agent"].(string)
+		offer.Offertype, _ = row["offertype"].(string)
+		if offer.Offerdate == "" {
+			continue
+		}
+		offers = append(offers, offer)
+	}
+
+	return offers, nil
+}
+
+// Bond получает основные свойства облигации
+func (c *MoexClient) Bond(ctx context.Context, isin string) (Bond, error) {
+	b := Bond{Isin: isin}
+
+	secProperties := "ISIN,SHORTNAME,ACCRUEDINT,FACEVALUE,MATDATE,COUPONPERIOD,COUPONPERCENT,SECNAME,FACEUNIT,OFFERDATE,SETTLEDATE,COUPONVALUE"
+	path := fmt.Sprintf("/iss/engines/stock/markets/bonds/securities/%s.json", isin)
+	query := url.Values{"iss.only": {"securities"}, "securities.columns": {secProperties}}
+
+	var mBond struct {
+		Securities issTable `json:"securities"`
+	}
+	if err := c.getJSON(ctx, path, query, &mBond); err != nil {
+		return b, err
+	}
+
+	for _, row := range mBond.Securities.rows() {
+		b.ShortName, _ = row["SHORTNAME"].(string)
+		b.AccruedInt, _ = row["ACCRUEDINT"].(float64)
+		b.FaceValue, _ = row["FACEVALUE"].(float64)
+		b.MatDate, _ = row["MATDATE"].(string)
+		couponPeriod, _ := row["COUPONPERIOD"].(float64)
+		b.CouponPeriod = int32(couponPeriod)
+		b.CouponPercent, _ = row["COUPONPERCENT"].(float64)
+		b.SecName, _ = row["SECNAME"].(string)
+		b.FaceUnit, _ = row["FACEUNIT"].(string)
+		b.OfferDate, _ = row["OFFERDATE"].(string)
+		b.SettleDate, _ = row["SETTLEDATE"].(string)
+		b.CouponValue, _ = row["COUPONVALUE"].(float64)
+	}
+	return b, nil
+}
+
+// BondMarketData получает торговые данные облигации. Ошибка errNoMoexData возвращается, если Мосбиржа
+// не вернула данных по облигации; наличие цены определяется цепочкой источников сервиса.
+func (c *MoexClient) BondMarketData(ctx context.Context, isin string) (BondMarketData, error) {
+	var marketData BondMarketData
+
+	path := fmt.Sprintf("/iss/engines/stock/markets/bonds/securities/%s.json", isin)
+	query := url.Values{
+		"iss.only":           {"securities,marketdata"},
+		"securities.columns": {"PREVPRICE,PREVDATE"},
+		"marketdata.columns": {"LAST,MARKETPRICE,WAPRICE,BID,OFFER,YIELD,VALTODAY,NUMTRADES,TIME,SYSTIME"},
+	}
+
+	var moexData struct {
+		Securities issTable `json:"securities"`
+		MarketData issTable `json:"marketdata"`
+	}
+	if err := c.getJSON(ctx, path, query, &moexData); err != nil {
+		return marketData, err
+	}
+
+	secRows, marketRows := moexData.Securities.rows(), moexData.MarketData.rows()
+	if len(secRows) == 0 && len(marketRows) == 0 {
+		return marketData, errNoMoexData
+	}
+	for _, row := range secRows {
+		marketData.PrevPrice, _ = row["PREVPRICE"].(float64)
+		marketData.PrevDate, _ = row["PREVDATE"].(string)
+	}
+	for _, row := range marketRows {
+		if row["LAST"] != nil {
+			var ok bool
+			marketData.Last, ok = row["LAST"].(float64)
+			if !ok {
+				return marketData, fmt.Errorf("cannot convert data %v to float64", row["LAST"])
+			}
+		}
+		marketData.MarketPrice, _ = row["MARKETPRICE"].(float64)
+		marketData.WAPrice, _ = row["WAPRICE"].(float64)
+		marketData.Bid, _ = row["BID"].(float64)
+		marketData.Offer, _ = row["OFFER"].(float64)
+		marketData.Yield, _ = row["YIELD"].(float64)
+		marketData.ValToday, _ = row["VALTODAY"].(float64)
+		numTrades, _ := row["NUMTRADES"].(float64)
+		marketData.NumTrades = int64(numTrades)
+		marketData.Time, _ = row["TIME"].(string)
+		marketData.SysTime, _ = row["SYSTIME"].(string)
+	}
+	return marketData, nil
+}
+
+// ShareMarketData получает торговые данные акции в режиме board
+func (c *MoexClient) ShareMarketData(ctx context.Context, board, ticker string) (ShareMarketData, error) {
+	var marketData ShareMarketData
+
+	path := fmt.Sprintf("/iss/engines/stock/markets/shares/boards/%s/securities/%s.json", board, ticker)
+	query := url.Values{
+		"iss.only":           {"securities,marketdata"},
+		"securities.columns": {"CURRENCYID"},
+		"marketdata.columns": {"LAST,VALTODAY,NUMTRADES"},
+	}
+
+	var moexData struct {
+		Securities issTable `json:"securities"`
+		MarketData issTable `json:"marketdata"`
+	}
+	if err := c.getJSON(ctx, path, query, &moexData); err != nil {
+		return marketData, err
+	}
+
+	for _, row := range moexData.Securities.rows() {
+		marketData.Currency, _ = row["CURRENCYID"].(string)
+	}
+	rows := moexData.MarketData.rows()
+	if len(rows) == 0 || rows[0]["LAST"] == nil {
+		return marketData, errNoMoexData
+	}
+	var ok bool
+	marketData.Last, ok = rows[0]["LAST"].(float64)
+	if !ok {
+		return marketData, fmt.Errorf("cannot convert data %v to float64", rows[0]["LAST"])
+	}
+	marketData.ValToday, _ = rows[0]["VALTODAY"].(float64)
+	numTrades, _ := rows[0]["NUMTRADES"].(float64)
+	marketData.NumTrades = int64(numTrades)
+
+	return marketData, nil
+}
+
+// IndexValue получает текущее значение индикатора индексного рынка
+func (c *MoexClient) IndexValue(ctx context.Context, index string) (float64, error) {
+	path := fmt.Sprintf("/iss/engines/stock/markets/index/securities/%s.json", index)
+	query := url.Values{"iss.only": {"marketdata"}, "marketdata.columns": {"CURRENTVALUE"}}
+
+	var moexData struct {
+		MarketData issTable `json:"marketdata"`
+	}
+	if err := c.getJSON(ctx, path, query, &moexData); err != nil {
+		return 0, err
+	}
+
+	rows := moexData.MarketData.rows()
+	if len(rows) == 0 || rows[0]["CURRENTVALUE"] == nil {
+		return 0, errNoMoexData
+	}
+	value, ok := rows[0]["CURRENTVALUE"].(float64)
+	if !ok {
+		return 0, fmt.Errorf("cannot convert data %v to float64", rows[0]["CURRENTVALUE"])
+	}
+	return value, nil
+}
+
+// ZCYC получает параметры кривой бескупонной доходности ОФЗ на дату date.
+// При нулевой дате возвращаются последние рассчитанные параметры.
+func (c *MoexClient) ZCYC(ctx context.Context, date time.Time) (models.ZCYC, error) {
+	var zcyc models.ZCYC
+
+	query := url.Values{"iss.only": {"params"}}
+	if !date.IsZero() {
+		query.Set("date", date.Format(time.DateOnly))
+	}
+
+	var moexData struct {
+		Params issTable `json:"params"`
+	}
+	if err := c.getJSON(ctx, "/iss/engines/stock/zcyc.json", query, &moexData); err != nil {
+		return zcyc, err
+	}
+
+	rows := moexData.Params.rows()
+	if len(rows) == 0 {
+		return zcyc, errNoMoexData
+	}
+	row := rows[len(rows)-1] // Параметры последнего расчёта за день
+	if row["B1"] == nil {
+		return zcyc, errNoMoexData
+	}
+	zcyc.Date, _ = row["tradedate"].(string)
+	zcyc.Time, _ = row["tradetime"].(string)
+	zcyc.B1, _ = row["B1"].(float64)
+	zcyc.B2, _ = row["B2"].(float64)
+	zcyc.B3, _ = row["B3"].(float64)
+	zcyc.T1, _ = row["T1"].(float64)
+	for i := range zcyc.G {
+		zcyc.G[i], _ = row[fmt.Sprintf("G%d", i+1)].(float64)
+	}
+	return zcyc, nil
+}
@@ -0,0 +1,303 @@
+package securities
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"simple-invest/internal/models"
+	"simple-invest/internal/securities/moextest"
+	"simple-invest/internal/tax"
+
+	"github.com/WLM1ke/gomoex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMoexClient(t *testing.T) *MoexClient {
+	t.Helper()
+
+	srv := moextest.NewServer(t)
+	md, err := NewMoexClient(srv.URL, http.DefaultClient)
+	require.NoError(t, err)
+	return md
+}
+
+func TestMoexClient(t *testing.T) {
+	md := newTestMoexClient(t)
+	ctx := context.Background()
+
+	secs, err := md.BoardSecurities(ctx, gomoex.EngineStock, gomoex.MarketShares, gomoex.BoardTQBR)
+	require.NoError(t, err)
+	assert.Len(t, secs, 2)
+	assert.Equal(t, "SBER", secs[0].Ticker)
+	assert.Equal(t, 10, secs[0].LotSize)
+
+	divs, err := md.Dividends(ctx, moextest.TickerShare)
+	require.NoError(t, err)
+	assert.Len(t, divs, 3)
+
+	coupons, err := md.Coupons(ctx, moextest.ISINOfz)
+	require.NoError(t, err)
+	assert.Len(t, coupons, 41)
+	assert.Equal(t, 35.4, coupons[0].Value)
+
+	amortizations, err := md.Amortizations(ctx, moextest.ISINAmortizing)
+	require.NoError(t, err)
+	require.Len(t, amortizations, 4)
+	assert.Equal(t, amortizations[0].Amortdate, amortizations[0].Date.Format(time.DateOnly))
+
+	offers, err := md.Offers(ctx, moextest.ISINOffer)
+	require.NoError(t, err)
+	require.Len(t, offers, 3)
+	assert.Equal(t, "2025-12-02", offers[1].Offerdate)
+	assert.Equal(t, "Колл-опцион", offers[2].Offertype)
+	assert.Equal(t, 101.0, offers[2].Price)
+
+	offers, err = md.Offers(ctx, moextest.ISINOfz)
+	require.NoError(t, err)
+	assert.Empty(t, offers)
+
+	bond, err := md.Bond(ctx, moextest.ISINOfz)
+	require.NoError(t, err)
+	assert.Equal(t, "2041-05-15", bond.MatDate)
+	assert.Equal(t, 1000.0, bond.FaceValue)
+
+	// Торговые данные без цен возвращаются, наличие цены определяет сервис
+	noTrades, err := md.BondMarketData(ctx, moextest.ISINNoTrades)
+	require.NoError(t, err)
+	_, ok := noTrades.price(defaultPriceSources)
+	assert.False(t, ok)
+
+	share, err := md.ShareMarketData(ctx, gomoex.BoardTQBR, moextest.TickerShare)
+	require.NoError(t, err)
+	assert.Equal(t, 318.5, share.Last)
+	assert.Equal(t, "SUR", share.Currency)
+
+	ruonia, err := md.IndexValue(ctx, "RUONIA")
+	require.NoError(t, err)
+	assert.Equal(t, 15.95, ruonia)
+
+	zcyc, err := md.ZCYC(ctx, time.Date(2024, 6, 4, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, moextest.ZCYC, zcyc)
+
+	_, err = md.Bond(ctx, "UNKNOWN")
+	assert.Error(t, err)
+}
+
+func TestSecuritiesService_BondIndicators(t *testing.T) {
+	s := New(newMemRepo(), newTestMoexClient(t), Options{})
+
+	tests := []struct {
+		isin           string
+		price          float64
+		effectiveYield float64
+		moexYield      float64
+	}{
+		{isin: moextest.ISINOfz, price: 610.21, effectiveYield: 0.1402, moexYield: 0.1409},
+		{isin: moextest.ISINAmortizing, price: 979.59, effectiveYield: 0.1492, moexYield: 0.1495},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.isin, func(t *testing.T) {
+			got, err := s.BondIndicators(context.Background(), tt.isin, IndicatorOptions{Tax: tax.Default()})
+			require.NoError(t, err)
+			assert.Equal(t, tt.price, got.Price)
+			assert.Equal(t, tt.effectiveYield, got.EffectiveYield)
+			assert.Equal(t, tt.moexYield, got.MoexYield)
+			assert.Less(t, got.NetEffectiveYield, got.EffectiveYield)
+			assert.Positive(t, got.ModifiedDuration)
+		})
+	}
+
+	_, err := s.BondIndicators(context.Background(), moextest.ISINNoTrades, IndicatorOptions{Tax: tax.Default()})
+	assert.ErrorIs(t, err, errNoMoexData)
+
+	// Купоны ОФЗ до 2021 года не облагались налогом
+	got, err := s.BondIndicators(context.Background(), moextest.ISINOfz, IndicatorOptions{Tax: tax.Model{Year: 2020, Resident: true}})
+	require.NoError(t, err)
+	assert.Zero(t, got.TaxRate)
+	assert.Equal(t, got.EffectiveYield, got.NetEffectiveYield)
+
+	resident, err := s.BondIndicators(context.Background(), moextest.ISINAmortizing, IndicatorOptions{Tax: tax.Model{Year: 2024, Resident: true}})
+	require.NoError(t, err)
+	nonResident, err := s.BondIndicators(context.Background(), moextest.ISINAmortizing, IndicatorOptions{Tax: tax.Model{Year: 2024}})
+	require.NoError(t, err)
+	assert.Equal(t, 0.13, resident.TaxRate)
+	assert.Equal(t, 0.30, nonResident.TaxRate)
+	assert.Less(t, nonResident.NetEffectiveYield, resident.NetEffectiveYield)
+}
+
+func TestSecuritiesService_BondIndicatorsProjection(t *testing.T) {
+	s := New(newMemRepo(), newTestMoexClient(t), Options{})
+	ctx := context.Background()
+	opts := IndicatorOptions{Tax: tax.Default()}
+
+	ofz, err := s.BondIndicators(ctx, moextest.ISINOfz, opts)
+	require.NoError(t, err)
+	assert.Equal(t, BondFixed, ofz.BondType)
+	assert.Equal(t, CouponFixed, ofz.CouponType)
+	assert.Equal(t, Assumptions{}, ofz.Assumptions)
+
+	amortizing, err := s.BondIndicators(ctx, moextest.ISINAmortizing, opts)
+	require.NoError(t, err)
+	assert.Equal(t, BondAmortizing, amortizing.BondType)
+
+	// Ключевая ставка не задана в настройках, используется RUONIA, спред - по последнему известному купону
+	floater, err := s.BondIndicators(ctx, moextest.ISINFloater, opts)
+	require.NoError(t, err)
+	assert.Equal(t, BondFloater, floater.BondType)
+	assert.Equal(t, CouponFloating, floater.CouponType)
+	assert.Equal(t, BaseRUONIA, floater.Assumptions.Base)
+	assert.Equal(t, 15.95, floater.Assumptions.Fixing)
+	assert.Equal(t, 0.18, *floater.Assumptions.Spread)
+	assert.Equal(t, spreadInferred, floater.Assumptions.SpreadSource)
+	assert.Equal(t, 3, floater.Assumptions.ProjectedCoupons)
+	assert.Positive(t, floater.EffectiveYield)
+
+	spread := 1.5
+	wide, err := s.BondIndicators(ctx, moextest.ISINFloater, IndicatorOptions{Tax: tax.Default(), Spread: &spread})
+	require.NoError(t, err)
+	assert.Greater(t, wide.EffectiveYield, floater.EffectiveYield)
+
+	keyRate := New(newMemRepo(), newTestMoexClient(t), Options{KeyRate: 16})
+	floater, err = keyRate.BondIndicators(ctx, moextest.ISINFloater, opts)
+	require.NoError(t, err)
+	assert.Equal(t, BaseKeyRate, floater.Assumptions.Base)
+	assert.Equal(t, 0.13, *floater.Assumptions.Spread)
+
+	// Базовая ставка недоступна, купоны прогнозируются по ставке последнего известного купона
+	noKeyRate, err := s.BondIndicators(ctx, moextest.ISINFloater, IndicatorOptions{Tax: tax.Default(), Base: BaseKeyRate, Spread: &spread})
+	require.NoError(t, err)
+	assert.Equal(t, BaseKeyRate, noKeyRate.Assumptions.Base)
+	assert.Equal(t, 16.13, noKeyRate.Assumptions.Fixing)
+	assert.Equal(t, fixingLastCoupon, noKeyRate.Assumptions.FixingSource)
+	assert.Equal(t, 0.0, *noKeyRate.Assumptions.Spread)
+	assert.Equal(t, spreadInferred, noKeyRate.Assumptions.SpreadSource)
+
+	failing := New(newMemRepo(), indexValueError{s.md, errors.New("connection reset")}, Options{})
+	noRUONIA, err := failing.BondIndicators(ctx, moextest.ISINFloater, opts)
+	require.NoError(t, err)
+	assert.Equal(t, BaseRUONIA, noRUONIA.Assumptions.Base)
+	assert.Equal(t, fixingLastCoupon, noRUONIA.Assumptions.FixingSource)
+	assert.Equal(t, noKeyRate.EffectiveYield, noRUONIA.EffectiveYield)
+
+	linker, err := s.BondIndicators(ctx, moextest.ISINLinker, opts)
+	require.NoError(t, err)
+	assert.Equal(t, BondLinker, linker.BondType)
+	assert.Equal(t, CouponIndexed, linker.CouponType)
+	assert.Equal(t, defaultInflation, linker.Assumptions.Inflation)
+	assert.Greater(t, linker.Assumptions.IndexedFaceValue, linker.FaceValue)
+	assert.Equal(t, 7, linker.Assumptions.ProjectedCoupons)
+
+	inflation := 8.0
+	high, err := s.BondIndicators(ctx, moextest.ISINLinker, IndicatorOptions{Tax: tax.Default(), Inflation: &inflation})
+	require.NoError(t, err)
+	assert.Greater(t, high.EffectiveYield, linker.EffectiveYield)
+	// Реальная доходность линкера близка к разнице номинальной доходности и инфляции
+	assert.InDelta(t, 0.03, linker.EffectiveYield-defaultInflation/100, 0.01)
+}
+
+func TestSecuritiesService_BondIndicatorsWhatIf(t *testing.T) {
+	s := New(newMemRepo(), newTestMoexClient(t), Options{})
+	ctx := context.Background()
+
+	// Неторгуемая облигация оценивается по заданной цене
+	got, err := s.BondIndicators(ctx, moextest.ISINNoTrades, IndicatorOptions{Tax: tax.Default(), Price: 98.5, Quantity: 5})
+	require.NoError(t, err)
+	assert.Equal(t, 98.5, got.PercentPrice)
+	assert.Equal(t, 1006.7, got.Price)
+	assert.Zero(t, got.NumTrades)
+	assert.Positive(t, got.EffectiveYield)
+	require.NotNil(t, got.Position)
+	assert.Equal(t, 5033.5, got.Position.Amount)
+
+	// На прошедшую дату НКД рассчитывается по графику купонов, срок до погашения - от этой даты
+	opts := IndicatorOptions{Tax: tax.Default(), Price: 98.5, SettleDate: time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC)}
+	past, err := s.BondIndicators(ctx, moextest.ISINNoTrades, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-08", past.SettleDate)
+	assert.Zero(t, past.AccruedInt)
+	assert.Equal(t, 985.0, past.Price)
+
+	opts.SettleDate = time.Date(2024, 6, 4, 0, 0, 0, 0, time.UTC)
+	current, err := s.BondIndicators(ctx, moextest.ISINNoTrades, opts)
+	require.NoError(t, err)
+	assert.Equal(t, got.AccruedInt, current.AccruedInt)
+	assert.Equal(t, current.DaysToEvent+88, past.DaysToEvent)
+
+	// Заданная цена заменяет только отсутствующие торговые данные, но не ошибку их получения
+	errReset := errors.New("connection reset")
+	failing := New(newMemRepo(), marketDataError{s.md, errReset}, Options{})
+	_, err = failing.BondIndicators(ctx, moextest.ISINNoTrades, IndicatorOptions{Tax: tax.Default(), Price: 98.5})
+	assert.ErrorIs(t, err, errReset)
+}
+
+// marketDataError возвращает ошибку err при получении торговых данных облигаций
+type marketDataError struct {
+	MarketData
+	err error
+}
+
+func (m marketDataError) BondMarketData(ctx context.Context, isin string) (BondMarketData, error) {
+	return BondMarketData{}, m.err
+}
+
+// indexValueError возвращает ошибку err при получении значений индикаторов
+type indexValueError struct {
+	MarketData
+	err error
+}
+
+func (m indexValueError) IndexValue(ctx context.Context, index string) (float64, error) {
+	return 0, m.err
+}
+
+func TestSecuritiesService_DividendYield(t *testing.T) {
+	repo := newMemRepo()
+	repo.shares = []gomoex.Security{{Ticker: moextest.TickerShare, ISIN: moextest.ISINShare, Board: gomoex.BoardTQBR, LotSize: 10}}
+	s := New(repo, newTestMoexClient(t), Options{})
+	date := time.Date(2024, time.August, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		model    tax.Model
+		netYield float64
+	}{
+		{"resident", tax.Model{Year: 2024, Resident: true}, 0.091},
+		{"non-resident", tax.Model{Year: 2024}, 0.0889},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.DividendYield(context.Background(), moextest.TickerShare, date, tt.model)
+			require.NoError(t, err)
+			assert.Equal(t, moextest.ISINShare, got.Isin)
+			assert.Equal(t, 33.3, got.Dividends)
+			assert.Equal(t, 1, got.Payments)
+			assert.Equal(t, 0.1046, got.Yield)
+			assert.Equal(t, tt.netYield, got.NetYield)
+		})
+	}
+}
+
+func TestSecuritiesService_Quote(t *testing.T) {
+	repo := newMemRepo()
+	repo.shares = []gomoex.Security{{Ticker: moextest.TickerShare, ISIN: moextest.ISINShare, Board: gomoex.BoardTQBR, LotSize: 10}}
+	s := New(repo, newTestMoexClient(t), Options{})
+	ctx := context.Background()
+
+	share, err := s.Quote(ctx, moextest.ISINShare)
+	require.NoError(t, err)
+	assert.Equal(t, models.Quote{Isin: moextest.ISINShare, CleanPrice: 318.5, Price: 318.5, Currency: "RUB"}, share)
+
+	// Облигация отсутствует в сохранённом списке бумаг
+	bond, err := s.Quote(ctx, moextest.ISINAmortizing)
+	require.NoError(t, err)
+	assert.Equal(t, models.Quote{Isin: moextest.ISINAmortizing, CleanPrice: 950, AccruedInt: 29.59, Price: 979.59, Currency: "RUB"}, bond)
+
+	_, err = s.Quote(ctx, moextest.ISINNoTrades)
+	assert.ErrorIs(t, err, errNoMoexData)
+}
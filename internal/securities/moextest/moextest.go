@@ -0,0 +1,69 @@
+// Пакет moextest предоставляет тестовый сервер ISS Мосбиржи, отвечающий сохранёнными данными.
+//
+// Ответы хранятся в каталоге testdata по пути запроса: запрос /iss/securities/SBER/dividends.json
+// обслуживается файлом testdata/iss/securities/SBER/dividends.json. Параметры запроса не учитываются,
+// поэтому файл содержит все таблицы, запрашиваемые по данному пути.
+// Сервер также отвечает на запрос официальных курсов валют Банка России /scripts/XML_daily.asp.
+package moextest
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"simple-invest/internal/models"
+)
+
+//go:embed testdata
+var fixtures embed.FS
+
+// Идентификаторы бумаг, для которых есть сохранённые данные
+const (
+	ISINOfz        = "SU26238RMFS4" // ОФЗ с постоянным купоном
+	ISINAmortizing = "RU000A100YL8" // Корпоративная облигация с амортизацией
+	ISINNoTrades   = "RU000A0JX0J2" // Облигация без сделок
+	ISINFloater    = "SU29014RMFS6" // ОФЗ-ПК с известным только ближайшим купоном
+	ISINLinker     = "SU52002RMFS1" // ОФЗ-ИН с индексируемым номиналом
+	ISINCurrency   = "RU000A105SG2" // Облигация с номиналом в долларах США
+	ISINIlliquid   = "RU000A106HB4" // Облигация без сделок за день с рыночной ценой и котировками
+	ISINOffer      = "RU000A107B43" // Облигация с put- и call-офертами
+	TickerShare    = "SBER"         // Акция с дивидендами
+	ISINShare      = "RU0009029540" // ISIN акции TickerShare
+)
+
+// ZCYC - параметры G-кривой из сохранённого ответа ISS на 2024-06-04 (testdata/iss/engines/stock/zcyc.json)
+var ZCYC = models.ZCYC{
+	Date: "2024-06-04",
+	Time: "18:39:52",
+	B1:   1342.57,
+	B2:   222.31,
+	B3:   -189.64,
+	T1:   2.18,
+	G:    [9]float64{41.52, -63.08, 25.77, -8.4},
+}
+
+// NewServer запускает тестовый сервер ISS. Сервер останавливается по завершении теста.
+func NewServer(t testing.TB) *httptest.Server {
+	t.Helper()
+
+	root, err := fs.Sub(fixtures, "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := fs.ReadFile(root, strings.TrimPrefix(r.URL.Path, "/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("content-type", "application/json")
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
@@ -0,0 +1,150 @@
+package securities
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Виды оферт
+const (
+	OfferPut  = "put"  // Выкуп по требованию владельцев
+	OfferCall = "call" // Досрочное погашение по решению эмитента
+)
+
+const offerPar = 100.0 // Цена выкупа по умолчанию, % от номинала
+
+// Оферта по облигации в графике оферт
+type offerEvent struct {
+	Date      string  `json:"date"`                 // Дата исполнения оферты
+	Type      string  `json:"type"`                 // Вид оферты: put или call
+	Price     float64 `json:"price"`                // Цена выкупа в процентах от номинала
+	StartDate string  `json:"start_date,omitempty"` // Дата начала приёма заявок
+	EndDate   string  `json:"end_date,omitempty"`   // Дата окончания приёма заявок
+}
+
+// Доходности к ближайшей оферте и к погашению
+type offerIndicators struct {
+	offerEvent
+	DaysToOffer        int64        `json:"days_to_offer"`         // Дней до оферты
+	YieldToOffer       float64      `json:"yield_to_offer"`        // Эффективная доходность к оферте
+	NetYieldToOffer    float64      `json:"net_yield_to_offer"`    // Итоговая эффективная доходность к оферте
+	DaysToMaturity     int64        `json:"days_to_maturity"`      // Дней до погашения
+	YieldToMaturity    float64      `json:"yield_to_maturity"`     // Эффективная доходность к погашению без учёта оферт
+	NetYieldToMaturity float64      `json:"net_yield_to_maturity"` // Итоговая эффективная доходность к погашению
+	Schedule           []offerEvent `json:"schedule"`              // Будущие оферты
+}
+
+// offerType определяет вид оферты по его описанию Мосбиржей. Оферты по требованию владельцев и оферты
+// без описания считаются put, досрочное погашение по решению эмитента - call.
+func offerType(o Offer) string {
+	t := strings.ToLower(o.Offertype)
+	switch {
+	case strings.Contains(t, "владел"):
+		return OfferPut
+	case strings.Contains(t, "call"), strings.Contains(t, "колл"), strings.Contains(t, "досрочн"), strings.Contains(t, "эмитент"):
+		return OfferCall
+	}
+	return OfferPut
+}
+
+// newOfferEvent возвращает оферту графика с видом и ценой выкупа
+func newOfferEvent(o Offer) offerEvent {
+	e := offerEvent{Date: o.Offerdate, Type: offerType(o), Price: o.Price, StartDate: o.OfferdateStart, EndDate: o.OfferdateEnd}
+	if e.Price <= 0 {
+		e.Price = offerPar
+	}
+	return e
+}
+
+// futureOffers возвращает упорядоченные по дате оферты графика offers после даты расчётов.
+// Если в графике нет будущих оферт, используется дата оферты bond по данным Мосбиржи.
+func futureOffers(bond Bond, offers []Offer, settleDate time.Time) ([]offerEvent, error) {
+	var events []offerEvent
+	for _, o := range offers {
+		date, err := time.Parse(time.DateOnly, o.Offerdate)
+		if err != nil {
+			return nil, err
+		}
+		if date.After(settleDate) {
+			events = append(events, newOfferEvent(o))
+		}
+	}
+	if len(events) == 0 && bond.OfferDate != "" {
+		date, err := time.Parse(time.DateOnly, bond.OfferDate)
+		if err != nil {
+			return nil, err
+		}
+		if date.After(settleDate) {
+			events = append(events, newOfferEvent(Offer{Offerdate: bond.OfferDate}))
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Date < events[j].Date })
+	return events, nil
+}
+
+// truncateAmortizations возвращает амортизационные выплаты до даты события eventDate.
+// Непогашенный к этой дате номинал выплачивается в дату события.
+func truncateAmortizations(am []Amortization, eventDate time.Time) ([]Amortization, error) {
+	var truncated []Amortization
+	var rest *Amortization
+	for _, a := range am {
+		date, err := time.Parse(time.DateOnly, a.Amortdate)
+		if err != nil {
+			return nil, err
+		}
+		if date.Before(eventDate) {
+			truncated = append(truncated, a)
+			continue
+		}
+		if rest == nil {
+			rest = &Amortization{Isin: a.Isin, Amortdate: eventDate.Format(time.DateOnly), Date: eventDate, Facevalue: a.Facevalue,
+				Initialfacevalue: a.Initialfacevalue, Faceunit: a.Faceunit}
+		}
+		rest.Value += a.Value
+		rest.ValueRub += a.ValueRub
+	}
+	if rest != nil {
+		truncated = append(truncated, *rest)
+	}
+	return truncated, nil
+}
+
+// offerIndicators рассчитывает доходности к ближайшей оферте и к погашению при покупке по цене price (с НКД)
+func (c *bondCalc) offerIndicators(d bondData, opts IndicatorOptions, today time.Time, price float64) (*offerIndicators, error) {
+	o := &offerIndicators{offerEvent: *c.offer, DaysToOffer: c.daysToEvent, Schedule: c.offers}
+
+	// Доходность к погашению рассчитывается по графику выплат без учёта оферт
+	toMaturity := d
+	toMaturity.bond.OfferDate = ""
+	toMaturity.offers = nil
+	m, err := newBondCalc(toMaturity, opts, today)
+	if err != nil {
+		return nil, err
+	}
+	o.DaysToMaturity = m.daysToEvent
+
+	if price <= 0 {
+		return o, nil
+	}
+	y, err := c.effectiveYield(price, false)
+	if err != nil {
+		return nil, err
+	}
+	netY, err := c.effectiveYield(price, true)
+	if err != nil {
+		return nil, err
+	}
+	o.YieldToOffer, o.NetYieldToOffer = roundFloat(y, precision), roundFloat(netY, precision)
+
+	y, err = m.effectiveYield(price, false)
+	if err != nil {
+		return nil, err
+	}
+	netY, err = m.effectiveYield(price, true)
+	if err != nil {
+		return nil, err
+	}
+	o.YieldToMaturity, o.NetYieldToMaturity = roundFloat(y, precision), roundFloat(netY, precision)
+	return o, nil
+}
@@ -0,0 +1,115 @@
+package securities
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"simple-invest/internal/securities/moextest"
+	"simple-invest/internal/tax"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_offerType(t *testing.T) {
+	tests := []struct {
+		offertype string
+		want      string
+	}{
+		{"Оферта", OfferPut},
+		{"", OfferPut},
+		{"Выкуп по требованию владельцев", OfferPut},
+		{"Досрочное погашение по требованию владельцев", OfferPut},
+		{"Колл-опцион", OfferCall},
+		{"Call-опцион", OfferCall},
+		{"Досрочное погашение по решению эмитента", OfferCall},
+	}
+	for _, tt := range tests {
+		t.Run(tt.offertype, func(t *testing.T) {
+			assert.Equal(t, tt.want, offerType(Offer{Offertype: tt.offertype}))
+		})
+	}
+}
+
+func Test_truncateAmortizations(t *testing.T) {
+	amortizations := []Amortization{
+		{Amortdate: "2026-12-02", Facevalue: 1000, Value: 250, ValueRub: 250},
+		{Amortdate: "2027-03-03", Facevalue: 1000, Value: 250, ValueRub: 250},
+		{Amortdate: "2027-06-02", Facevalue: 1000, Value: 250, ValueRub: 250},
+		{Amortdate: "2027-09-01", Facevalue: 1000, Value: 250, ValueRub: 250},
+	}
+	for i := range amortizations {
+		amortizations[i].Date, _ = time.Parse(time.DateOnly, amortizations[i].Amortdate)
+	}
+
+	got, err := truncateAmortizations(amortizations, time.Date(2027, 3, 10, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, []Amortization{
+		amortizations[0],
+		amortizations[1],
+		{Amortdate: "2027-03-10", Date: time.Date(2027, 3, 10, 0, 0, 0, 0, time.UTC), Facevalue: 1000, Value: 500, ValueRub: 500},
+	}, got)
+
+	// Событие в дату погашения не меняет графика
+	got, err = truncateAmortizations(amortizations, time.Date(2027, 9, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, amortizations, got)
+}
+
+func TestSecuritiesService_BondIndicatorsOffers(t *testing.T) {
+	s := New(newMemRepo(), newTestMoexClient(t), Options{})
+	ctx := context.Background()
+	opts := IndicatorOptions{Tax: tax.Default(), Quantity: 1, SettleDate: time.Date(2024, 6, 4, 0, 0, 0, 0, time.UTC)}
+
+	got, err := s.BondIndicators(ctx, moextest.ISINOffer, opts)
+	require.NoError(t, err)
+	assert.Equal(t, "2025-12-02", got.OfferDate)
+	require.NotNil(t, got.Offer)
+	offer := got.Offer
+	assert.Equal(t, OfferPut, offer.Type)
+	assert.Equal(t, 100.0, offer.Price)
+	assert.Equal(t, "2025-11-18", offer.StartDate)
+	assert.Equal(t, int64(546), offer.DaysToOffer)
+	assert.Equal(t, got.DaysToEvent, offer.DaysToOffer)
+	assert.Equal(t, int64(1456), offer.DaysToMaturity)
+	// Прошедшая оферта в график не попадает
+	require.Len(t, offer.Schedule, 2)
+	assert.Equal(t, offerEvent{Date: "2026-12-01", Type: OfferCall, Price: 101, StartDate: "2026-11-17", EndDate: "2026-11-24"},
+		offer.Schedule[1])
+
+	// Показатели рассчитываются к оферте, доходность к погашению - по всем купонам
+	assert.Equal(t, got.EffectiveYield, offer.YieldToOffer)
+	assert.Equal(t, got.NetEffectiveYield, offer.NetYieldToOffer)
+	assert.Positive(t, offer.YieldToMaturity)
+	assert.NotEqual(t, offer.YieldToOffer, offer.YieldToMaturity)
+
+	// Купоны учитываются только до даты оферты
+	require.NotNil(t, got.Position)
+	assert.Equal(t, 179.52, got.Position.Coupons)
+	assert.Equal(t, 1000.0, got.Position.Redemption)
+	wantSimple := (3*59.84 + 1000 - got.Price) / got.Price * 365 / 546
+	assert.InDelta(t, wantSimple, got.SimpleYield, 1e-4)
+
+	// После put-оферты событием становится call-оферта с выкупом по цене выше номинала
+	opts.SettleDate = time.Date(2025, 12, 10, 0, 0, 0, 0, time.UTC)
+	got, err = s.BondIndicators(ctx, moextest.ISINOffer, opts)
+	require.NoError(t, err)
+	require.NotNil(t, got.Offer)
+	assert.Equal(t, OfferCall, got.Offer.Type)
+	assert.Equal(t, "2026-12-01", got.OfferDate)
+	assert.Equal(t, 1010.0, got.Position.Redemption)
+	assert.Len(t, got.Offer.Schedule, 1)
+
+	// После всех оферт показатели рассчитываются к погашению
+	opts.SettleDate = time.Date(2027, 1, 10, 0, 0, 0, 0, time.UTC)
+	got, err = s.BondIndicators(ctx, moextest.ISINOffer, opts)
+	require.NoError(t, err)
+	assert.Nil(t, got.Offer)
+	assert.Empty(t, got.OfferDate)
+
+	// Облигации без оферт
+	got, err = s.BondIndicators(ctx, moextest.ISINOfz, IndicatorOptions{Tax: tax.Default()})
+	require.NoError(t, err)
+	assert.Nil(t, got.Offer)
+}
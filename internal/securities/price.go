@@ -0,0 +1,127 @@
+package securities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Виды доходности для расчёта цены
+const (
+	YieldSimple       = "simple"        // Простая доходность
+	YieldNetSimple    = "net_simple"    // Простая доходность с учётом налогов
+	YieldEffective    = "effective"     // Эффективная доходность к погашению
+	YieldNetEffective = "net_effective" // Эффективная доходность к погашению с учётом налогов
+)
+
+const priceAccuracy = 1e-6 // Точность подбора цены, в единицах валюты номинала
+
+// ErrIncorrectYield возвращается при неизвестном виде доходности или недостижимой доходности
+var ErrIncorrectYield = errors.New("incorrect target yield")
+
+// BondPrice - цена облигации, обеспечивающая заданную доходность
+type BondPrice struct {
+	Isin         string  `json:"isin"`                // Ценная бумага
+	YieldType    string  `json:"yield_type"`          // Вид доходности
+	TargetYield  float64 `json:"target_yield"`        // Целевая доходность
+	PercentPrice float64 `json:"percent_price"`       // Чистая цена в процентах от номинала
+	CleanPrice   float64 `json:"clean_price"`         // Чистая цена
+	AccruedInt   float64 `json:"accruedint"`          // НКД
+	Price        float64 `json:"price"`               // Цена с НКД в валюте номинала
+	PriceRub     float64 `json:"price_rub,omitempty"` // Цена с НКД в рублях для облигаций с номиналом в иностранной валюте
+	FaceUnit     string  `json:"faceunit"`            // Валюта номинала
+	MaturityTax  float64 `json:"maturity_tax"`        // Налог при погашении при покупке по рассчитанной цене
+	SettleDate   string  `json:"settledate"`          // Дата расчётов
+}
+
+// PriceForYield рассчитывает цену облигации, при которой её доходность вида yieldType равна target.
+// Используются те же график выплат и формулы, что и при расчёте показателей в BondIndicators.
+func (s *SecuritiesService) PriceForYield(ctx context.Context, isin string, target float64, yieldType string, opts IndicatorOptions) (BondPrice, error) {
+	if yieldType == "" {
+		yieldType = YieldEffective
+	}
+	bp := BondPrice{Isin: isin, YieldType: yieldType, TargetYield: target}
+
+	// excess(c, price) имеет знак разности доходности при цене price и целевой доходности
+	var excess func(c *bondCalc, price float64) float64
+	switch yieldType {
+	case YieldSimple, YieldNetSimple:
+		net := yieldType == YieldNetSimple
+		excess = func(c *bondCalc, price float64) float64 {
+			return c.simpleYield(price, net) - target
+		}
+	case YieldEffective, YieldNetEffective:
+		if target <= minYield {
+			return bp, fmt.Errorf("%w: effective yield must be greater than %v", ErrIncorrectYield, minYield)
+		}
+		net := yieldType == YieldNetEffective
+		// Доходность выше целевой, если приведённая по целевой ставке стоимость выплат больше цены
+		excess = func(c *bondCalc, price float64) float64 {
+			flows := c.flows
+			if net {
+				flows = netCashFlows(c.flows, c.couponTaxRate, c.maturityTax(price))
+			}
+			return presentValue(flows, target, c.settleDate) - price
+		}
+	default:
+		return bp, fmt.Errorf("%w: unknown yield type %q", ErrIncorrectYield, yieldType)
+	}
+
+	d, err := s.bondData(ctx, isin, opts)
+	if err != nil {
+		return bp, err
+	}
+	c, err := newBondCalc(d, opts, time.Now().Truncate(time.Hour*24))
+	if err != nil {
+		return bp, err
+	}
+
+	price, err := solvePrice(c.flows, func(price float64) float64 { return excess(c, price) })
+	if err != nil {
+		return bp, err
+	}
+
+	bp.AccruedInt = c.accruedInt
+	bp.Price = roundFloat(price, 2)
+	bp.CleanPrice = roundFloat(price-c.accruedInt, 2)
+	if c.face > 0 {
+		bp.PercentPrice = roundFloat(bp.CleanPrice/c.face*100, precision)
+	}
+	bp.FaceUnit = c.bond.FaceUnit
+	bp.MaturityTax = c.maturityTax(price)
+	bp.SettleDate = c.settleDate.Format(time.DateOnly)
+
+	if d.fx != nil {
+		bp.PriceRub = roundFloat(bp.Price*d.fx.Rate, 2)
+	}
+
+	return bp, nil
+}
+
+// solvePrice подбирает цену с НКД, при которой excess обращается в ноль. Доходность монотонно убывает
+// с ростом цены, поэтому используется метод деления отрезка пополам.
+func solvePrice(flows []cashFlow, excess func(price float64) float64) (float64, error) {
+	total := 0.0
+	for _, cf := range flows {
+		total += cf.amount()
+	}
+	if total <= 0 {
+		return 0, ErrNoCashFlows
+	}
+
+	// Цена ищется в интервале от близкой к нулю до удвоенной суммы будущих выплат
+	low, high := priceAccuracy, total*2
+	if excess(low) < 0 || excess(high) > 0 {
+		return 0, fmt.Errorf("%w: yield is unreachable at positive price", ErrIncorrectYield)
+	}
+	for high-low > priceAccuracy {
+		mid := (low + high) / 2
+		if excess(mid) > 0 {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2, nil
+}
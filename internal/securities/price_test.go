@@ -0,0 +1,79 @@
+package securities
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"simple-invest/internal/securities/moextest"
+	"simple-invest/internal/tax"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecuritiesService_PriceForYield(t *testing.T) {
+	s := New(newMemRepo(), newTestMoexClient(t), Options{})
+	ctx := context.Background()
+	opts := IndicatorOptions{Tax: tax.Model{Year: 2024, Resident: true}}
+
+	tests := []struct {
+		isin      string
+		yieldType string
+	}{
+		{moextest.ISINOfz, YieldSimple},
+		{moextest.ISINOfz, YieldNetSimple},
+		{moextest.ISINOfz, YieldEffective},
+		{moextest.ISINOfz, YieldNetEffective},
+		{moextest.ISINAmortizing, YieldEffective},
+		{moextest.ISINAmortizing, YieldNetEffective},
+		{moextest.ISINFloater, YieldNetSimple},
+	}
+	for _, tt := range tests {
+		t.Run(tt.isin+" "+tt.yieldType, func(t *testing.T) {
+			target := 0.18
+			got, err := s.PriceForYield(ctx, tt.isin, target, tt.yieldType, opts)
+			require.NoError(t, err)
+
+			// Доходность при рассчитанной цене совпадает с целевой
+			d, err := s.bondData(ctx, tt.isin, opts)
+			require.NoError(t, err)
+			c, err := newBondCalc(d, opts, time.Now())
+			require.NoError(t, err)
+			var y float64
+			switch tt.yieldType {
+			case YieldSimple, YieldNetSimple:
+				y = c.simpleYield(got.Price, tt.yieldType == YieldNetSimple)
+			default:
+				y, err = c.effectiveYield(got.Price, tt.yieldType == YieldNetEffective)
+				require.NoError(t, err)
+			}
+			assert.InDelta(t, target, y, 1e-4)
+			assert.Equal(t, roundFloat(got.Price-got.AccruedInt, 2), got.CleanPrice)
+			assert.InDelta(t, got.CleanPrice/c.bond.FaceValue*100, got.PercentPrice, 1e-4)
+		})
+	}
+
+	// Обратный расчёт по доходности, рассчитанной по рыночной цене, возвращает рыночную цену
+	bI, err := s.BondIndicators(ctx, moextest.ISINOfz, opts)
+	require.NoError(t, err)
+	got, err := s.PriceForYield(ctx, moextest.ISINOfz, bI.EffectiveYield, "", opts)
+	require.NoError(t, err)
+	assert.Equal(t, YieldEffective, got.YieldType)
+	assert.InDelta(t, bI.PercentPrice, got.PercentPrice, 0.01)
+
+	net, err := s.PriceForYield(ctx, moextest.ISINOfz, 0.18, YieldNetEffective, opts)
+	require.NoError(t, err)
+	gross, err := s.PriceForYield(ctx, moextest.ISINOfz, 0.18, YieldEffective, opts)
+	require.NoError(t, err)
+	assert.Less(t, net.Price, gross.Price)
+
+	currency, err := s.PriceForYield(ctx, moextest.ISINCurrency, 0.08, YieldEffective, opts)
+	require.NoError(t, err)
+	assert.Positive(t, currency.PriceRub)
+
+	_, err = s.PriceForYield(ctx, moextest.ISINOfz, 0.18, "current", opts)
+	assert.ErrorIs(t, err, ErrIncorrectYield)
+	_, err = s.PriceForYield(ctx, moextest.ISINOfz, -1, YieldEffective, opts)
+	assert.ErrorIs(t, err, ErrIncorrectYield)
+}
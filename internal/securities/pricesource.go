@@ -0,0 +1,75 @@
+package securities
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Источники цены облигации
+const (
+	PriceLast    = "last"        // Цена последней сделки
+	PriceMarket  = "marketprice" // Рыночная цена, рассчитываемая Мосбиржей
+	PriceWA      = "waprice"     // Средневзвешенная цена за день
+	PricePrev    = "prevprice"   // Цена последней сделки предыдущего торгового дня
+	PriceMid     = "mid"         // Середина между лучшими ценами спроса и предложения
+	PriceRequest = "request"     // Цена задана в запросе
+)
+
+// defaultPriceSources - порядок источников цены по умолчанию
+var defaultPriceSources = []string{PriceLast, PriceMarket, PriceWA, PricePrev, PriceMid}
+
+// marketPrice - цена облигации, выбранная по цепочке источников
+type marketPrice struct {
+	Source string  // Источник цены
+	Price  float64 // Цена в процентах от номинала
+	Time   string  // Время цены по данным Мосбиржи
+}
+
+// ParsePriceSources разбирает цепочку источников цены в формате "last,marketprice,mid"
+func ParsePriceSources(s string) ([]string, error) {
+	var sources []string
+	for _, source := range strings.Split(s, ",") {
+		source = strings.TrimSpace(strings.ToLower(source))
+		if source == "" {
+			continue
+		}
+		switch source {
+		case PriceLast, PriceMarket, PriceWA, PricePrev, PriceMid:
+			sources = append(sources, source)
+		default:
+			return nil, fmt.Errorf("unknown price source %q", source)
+		}
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("empty price source list")
+	}
+	return sources, nil
+}
+
+// price возвращает первую доступную цену по цепочке источников sources
+func (m BondMarketData) price(sources []string) (marketPrice, bool) {
+	for _, source := range sources {
+		p := marketPrice{Source: source, Time: m.SysTime}
+		switch source {
+		case PriceLast:
+			p.Price = m.Last
+			if m.Time != "" && len(m.SysTime) >= len("2006-01-02") {
+				p.Time = m.SysTime[:len("2006-01-02")] + " " + m.Time
+			}
+		case PriceMarket:
+			p.Price = m.MarketPrice
+		case PriceWA:
+			p.Price = m.WAPrice
+		case PricePrev:
+			p.Price, p.Time = m.PrevPrice, m.PrevDate
+		case PriceMid:
+			if m.Bid > 0 && m.Offer > 0 {
+				p.Price = roundFloat((m.Bid+m.Offer)/2, precision)
+			}
+		}
+		if p.Price > 0 {
+			return p, true
+		}
+	}
+	return marketPrice{}, false
+}
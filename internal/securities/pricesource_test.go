@@ -0,0 +1,106 @@
+package securities
+
+import (
+	"context"
+	"testing"
+
+	"simple-invest/internal/securities/moextest"
+	"simple-invest/internal/tax"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePriceSources(t *testing.T) {
+	got, err := ParsePriceSources(" Last, mid ,")
+	require.NoError(t, err)
+	assert.Equal(t, []string{PriceLast, PriceMid}, got)
+
+	_, err = ParsePriceSources("last,close")
+	assert.Error(t, err)
+
+	_, err = ParsePriceSources(" , ")
+	assert.Error(t, err)
+}
+
+func TestBondMarketData_price(t *testing.T) {
+	m := BondMarketData{
+		Last:        99.1,
+		MarketPrice: 99.05,
+		PrevPrice:   98.7,
+		PrevDate:    "2024-05-31",
+		Bid:         98.9,
+		Offer:       99.3,
+		Time:        "18:39:58",
+		SysTime:     "2024-06-04 18:50:03",
+	}
+	tests := []struct {
+		name    string
+		m       BondMarketData
+		sources []string
+		want    marketPrice
+		ok      bool
+	}{
+		{"last", m, defaultPriceSources, marketPrice{PriceLast, 99.1, "2024-06-04 18:39:58"}, true},
+		{"order", m, []string{PriceMid, PriceLast}, marketPrice{PriceMid, 99.1, "2024-06-04 18:50:03"}, true},
+		{"skip empty", m, []string{PriceWA, PricePrev}, marketPrice{PricePrev, 98.7, "2024-05-31"}, true},
+		{"one-sided quote", BondMarketData{Bid: 98.9}, []string{PriceMid}, marketPrice{}, false},
+		{"no prices", BondMarketData{}, defaultPriceSources, marketPrice{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.m.price(tt.sources)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSecuritiesService_BondIndicatorsPriceSources(t *testing.T) {
+	md := newTestMoexClient(t)
+	ctx := context.Background()
+	opts := IndicatorOptions{Tax: tax.Default()}
+
+	marketData, err := md.BondMarketData(ctx, moextest.ISINIlliquid)
+	require.NoError(t, err)
+	assert.Zero(t, marketData.Last)
+	assert.Equal(t, 97.65, marketData.MarketPrice)
+	assert.Equal(t, 97.3, marketData.PrevPrice)
+	assert.Equal(t, 98.2, marketData.Offer)
+
+	tests := []struct {
+		sources   []string
+		source    string
+		price     float64
+		priceTime string
+	}{
+		{nil, PriceMarket, 97.65, "2024-06-04 18:50:03"},
+		{[]string{PriceWA, PricePrev}, PricePrev, 97.3, "2024-05-31"},
+		{[]string{PriceMid}, PriceMid, 97.5, "2024-06-04 18:50:03"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			s := New(newMemRepo(), md, Options{PriceSources: tt.sources})
+			got, err := s.BondIndicators(ctx, moextest.ISINIlliquid, opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.source, got.PriceSource)
+			assert.Equal(t, tt.price, got.PercentPrice)
+			assert.Equal(t, tt.priceTime, got.PriceTime)
+			assert.Positive(t, got.EffectiveYield)
+		})
+	}
+
+	// Ни один из источников цепочки не содержит цены
+	s := New(newMemRepo(), md, Options{PriceSources: []string{PriceLast, PriceWA}})
+	_, err = s.BondIndicators(ctx, moextest.ISINIlliquid, opts)
+	assert.ErrorIs(t, err, errNoMoexData)
+
+	got, err := s.BondIndicators(ctx, moextest.ISINIlliquid, IndicatorOptions{Tax: tax.Default(), Price: 98})
+	require.NoError(t, err)
+	assert.Equal(t, PriceRequest, got.PriceSource)
+	assert.Empty(t, got.PriceTime)
+
+	q, err := New(newMemRepo(), md, Options{}).Quote(ctx, moextest.ISINIlliquid)
+	require.NoError(t, err)
+	assert.Equal(t, 976.5, q.CleanPrice)
+}
@@ -0,0 +1,67 @@
+package securities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"simple-invest/internal/models"
+	"simple-invest/internal/repository"
+
+	"github.com/WLM1ke/gomoex"
+)
+
+const moexRubleCode = "SUR" // Обозначение рубля в ISS Мосбиржи
+
+// Quote возвращает текущую цену бумаги по данным Мосбиржи. Для облигации цена рассчитывается как
+// цена по цепочке источников в процентах от номинала плюс НКД, для акции - цена последней сделки.
+// Бумаги, отсутствующие в сохранённом списке, считаются облигациями.
+func (s *SecuritiesService) Quote(ctx context.Context, isin string) (models.Quote, error) {
+	q := models.Quote{Isin: isin}
+
+	sec, market, err := s.repo.GetSecurity(isin)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return q, fmt.Errorf("%w: %w", ErrStorage, err)
+	}
+
+	if market == gomoex.MarketShares {
+		board := sec.Board
+		if board == "" {
+			board = gomoex.BoardTQBR
+		}
+		marketData, err := s.md.ShareMarketData(ctx, board, sec.Ticker)
+		if err != nil {
+			return q, err
+		}
+		q.CleanPrice = marketData.Last
+		q.Price = marketData.Last
+		q.Currency = currencyCode(marketData.Currency)
+		return q, nil
+	}
+
+	bond, err := s.md.Bond(ctx, isin)
+	if err != nil {
+		return q, err
+	}
+	marketData, err := s.md.BondMarketData(ctx, isin)
+	if err != nil {
+		return q, err
+	}
+	price, ok := marketData.price(s.opts.PriceSources)
+	if !ok {
+		return q, errNoMoexData
+	}
+	q.CleanPrice = roundFloat(price.Price/100*bond.FaceValue, precision)
+	q.AccruedInt = bond.AccruedInt
+	q.Price = roundFloat(q.CleanPrice+q.AccruedInt, precision)
+	q.Currency = currencyCode(bond.FaceUnit)
+	return q, nil
+}
+
+// currencyCode приводит обозначение валюты Мосбиржи к коду ISO 4217
+func currencyCode(unit string) string {
+	if unit == moexRubleCode || unit == "" {
+		return rubleCode
+	}
+	return unit
+}
@@ -0,0 +1,108 @@
+package securities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"simple-invest/internal/curves"
+	"simple-invest/internal/models"
+	"simple-invest/internal/tax"
+
+	"github.com/WLM1ke/gomoex"
+)
+
+// ErrNotBond возвращается при сценарном анализе бумаги, не являющейся облигацией
+var ErrNotBond = errors.New("scenario analysis is available for bonds only")
+
+// Reprice рассчитывает цены облигации isin с НКД при сдвигах кривой доходности shocks.
+// Будущие купоны и выплаты номинала дисконтируются по эффективной доходности при текущей цене,
+// увеличенной на сдвиг ставки на срок каждой выплаты. Прогнозные купоны флоатера сдвигаются вместе со ставкой.
+func (s *SecuritiesService) Reprice(ctx context.Context, isin string, shocks []curves.Shock) (models.ScenarioQuote, error) {
+	q := models.ScenarioQuote{Isin: isin}
+
+	_, market, err := s.repo.GetSecurity(isin)
+	if err == nil && market == gomoex.MarketShares {
+		return q, ErrNotBond
+	}
+
+	opts := IndicatorOptions{Tax: tax.Default()}
+	d, err := s.pricedBondData(ctx, isin, opts)
+	if err != nil {
+		return q, err
+	}
+	c, err := newBondCalc(d, opts, time.Now().Truncate(time.Hour*24))
+	if err != nil {
+		return q, err
+	}
+
+	q.Currency = currencyCode(d.bond.FaceUnit)
+	q.Price = roundFloat(c.face*d.price.Price/100, 2) + c.accruedInt
+	y, err := c.effectiveYield(q.Price, false)
+	if err != nil {
+		return q, err
+	}
+	q.Yield = roundFloat(y, precision)
+
+	q.Prices = make([]float64, len(shocks))
+	for i, shock := range shocks {
+		flows, err := c.shockedFlows(shock)
+		if err != nil {
+			return q, err
+		}
+		pv, err := shockedPresentValue(flows, y, c.settleDate, shock)
+		if err != nil {
+			return q, err
+		}
+		q.Prices[i] = roundFloat(pv, 2)
+	}
+	return q, nil
+}
+
+// shockedFlows возвращает будущие выплаты облигации при сдвиге ставок shock. Прогнозные купоны флоатера
+// пропорциональны ставке fixing+spread и пересчитываются по ставке, сдвинутой на срок выплаты.
+// Объявленные купоны и остальные выплаты не изменяются.
+func (c *bondCalc) shockedFlows(shock curves.Shock) ([]cashFlow, error) {
+	a := c.assumptions
+	if a.Spread == nil {
+		return c.flows, nil
+	}
+	rate := (a.Fixing + *a.Spread) / 100
+	if rate <= 0 {
+		return nil, fmt.Errorf("%w: floater coupon rate %.2f%% must be positive for scenario analysis", ErrCalculation, rate*100)
+	}
+
+	announced := make(map[string]bool, len(c.schedule))
+	for _, cp := range c.schedule {
+		if cp.Value != 0 {
+			announced[cp.Coupondate] = true
+		}
+	}
+	flows := make([]cashFlow, len(c.flows))
+	for i, cf := range c.flows {
+		if cf.Coupon > 0 && !announced[cf.Date.Format(time.DateOnly)] {
+			shift := shock.At(yearFraction(c.settleDate, cf.Date))
+			cf.Coupon = roundFloat(cf.Coupon*math.Max(0, 1+shift/rate), 2)
+		}
+		flows[i] = cf
+	}
+	return flows, nil
+}
+
+// shockedPresentValue дисконтирует выплаты по ставке y, увеличенной на сдвиг shock на срок выплаты.
+// Сдвиг, при котором ставка дисконтирования не превышает -100%, считается некорректным.
+func shockedPresentValue(flows []cashFlow, y float64, settleDate time.Time, shock curves.Shock) (float64, error) {
+	pv := 0.0
+	for _, cf := range flows {
+		t := yearFraction(settleDate, cf.Date)
+		base := 1 + y + shock.At(t)
+		if base <= 0 {
+			return 0, fmt.Errorf("%w: scenario %q gives discount rate %.2f%% at %.2f years", curves.ErrIncorrectShock,
+				shock.Name, (base-1)*100, t)
+		}
+		pv += cf.amount() / math.Pow(base, t)
+	}
+	return pv, nil
+}
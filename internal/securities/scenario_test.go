@@ -0,0 +1,80 @@
+package securities
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"simple-invest/internal/curves"
+	"simple-invest/internal/securities/moextest"
+	"simple-invest/internal/tax"
+
+	"github.com/WLM1ke/gomoex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecuritiesService_Reprice(t *testing.T) {
+	repo := newMemRepo()
+	repo.shares = []gomoex.Security{{Ticker: moextest.TickerShare, ISIN: moextest.ISINShare, Board: gomoex.BoardTQBR}}
+	s := New(repo, newTestMoexClient(t), Options{})
+	ctx := context.Background()
+
+	shocks := []curves.Shock{
+		{Type: curves.ShockParallel},
+		{Type: curves.ShockParallel, Shift: 200},
+		{Type: curves.ShockParallel, Shift: -200},
+		{Type: curves.ShockSteepener, Shift: 200},
+	}
+	for i := range shocks {
+		require.NoError(t, shocks[i].Validate())
+	}
+
+	got, err := s.Reprice(ctx, moextest.ISINOfz, shocks)
+	require.NoError(t, err)
+	bI, err := s.BondIndicators(ctx, moextest.ISINOfz, IndicatorOptions{Tax: tax.Default()})
+	require.NoError(t, err)
+	assert.Equal(t, bI.Price, got.Price)
+	assert.Equal(t, bI.EffectiveYield, got.Yield)
+	assert.Equal(t, "RUB", got.Currency)
+	require.Len(t, got.Prices, len(shocks))
+
+	// Без сдвига цена не меняется
+	assert.InDelta(t, got.Price, got.Prices[0], 0.01)
+	// Изменение цены при параллельном сдвиге согласуется с модифицированной дюрацией и выпуклостью
+	for i, shift := range map[int]float64{1: 0.02, 2: -0.02} {
+		want := got.Price * (1 - bI.ModifiedDuration*shift + bI.Convexity*shift*shift/2)
+		assert.InDelta(t, want, got.Prices[i], got.Price*0.002, shift)
+	}
+	assert.Less(t, got.Prices[1], got.Price)
+	assert.Greater(t, got.Prices[2], got.Price)
+	// Длинная ОФЗ дешевеет при росте длинных ставок
+	assert.Less(t, got.Prices[3], got.Price)
+
+	// Прогнозные купоны флоатера следуют за ставкой, поэтому его цена почти не зависит от сдвига
+	floater, err := s.Reprice(ctx, moextest.ISINFloater, shocks)
+	require.NoError(t, err)
+	fI, err := s.BondIndicators(ctx, moextest.ISINFloater, IndicatorOptions{Tax: tax.Default()})
+	require.NoError(t, err)
+	fixedChange := floater.Price * fI.ModifiedDuration * 0.02
+	assert.Less(t, floater.Price-floater.Prices[1], fixedChange/5)
+	assert.Less(t, floater.Prices[2]-floater.Price, fixedChange/5)
+
+	_, err = s.Reprice(ctx, moextest.ISINShare, shocks)
+	assert.ErrorIs(t, err, ErrNotBond)
+}
+
+func Test_shockedPresentValue(t *testing.T) {
+	settleDate := time.Date(2024, 6, 4, 0, 0, 0, 0, time.UTC)
+	flows := []cashFlow{{Date: settleDate.AddDate(1, 0, 0), Coupon: 100, Principal: 1000}}
+	shock := curves.Shock{Type: curves.ShockParallel, Shift: -3000}
+	require.NoError(t, shock.Validate())
+
+	pv, err := shockedPresentValue(flows, 0.1, settleDate, shock)
+	require.NoError(t, err)
+	assert.InDelta(t, 1100/0.8, pv, 0.01)
+
+	// Ставка дисконтирования не выше -100% - некорректный сдвиг, а не NaN в ответе
+	_, err = shockedPresentValue(flows, -0.75, settleDate, shock)
+	assert.ErrorIs(t, err, curves.ErrIncorrectShock)
+}
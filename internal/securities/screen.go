@@ -0,0 +1,225 @@
+package securities
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"simple-invest/internal/models"
+	"simple-invest/internal/tax"
+)
+
+const defaultYieldField = "effective_yield" // Показатель доходности для отбора по умолчанию
+
+// ErrIncorrectFilter возвращается при отборе по неизвестному показателю
+var ErrIncorrectFilter = errors.New("incorrect screen filter")
+
+// ScreenFilter содержит условия отбора облигаций. Незаданные (nil, пустые) условия не применяются.
+type ScreenFilter struct {
+	YieldField   string   // Показатель доходности для отбора по диапазону, по умолчанию effective_yield
+	MinYield     *float64 // Минимальная доходность
+	MaxYield     *float64 // Максимальная доходность
+	MinDays      *int64   // Минимальное количество дней до погашения (оферты)
+	MaxDays      *int64   // Максимальное количество дней до погашения (оферты)
+	FaceUnit     string   // Валюта номинала
+	CouponType   string   // Тип купона
+	BondType     string   // Тип облигации
+	Amortization *bool    // Наличие амортизации
+	MinValToday  *float64 // Минимальный объём торгов за день, руб.
+	MinNumTrades *int64   // Минимальное количество сделок за день
+	SortBy       string   // Показатель для сортировки
+	Desc         bool     // Сортировка по убыванию
+	Limit        int      // Максимальное количество облигаций в ответе
+}
+
+// ScreenedBond - показатели отобранной облигации со временем их расчёта
+type ScreenedBond struct {
+	bondIndicators
+	ComputedAt time.Time `json:"computed_at"` // Время расчёта показателей
+}
+
+// screenItem - показатели облигации и их представление для отбора по имени поля
+type screenItem struct {
+	indicators bondIndicators
+	computedAt time.Time
+	fields     map[string]any
+}
+
+// ScreenBonds отбирает облигации по сохранённым показателям
+func (s *SecuritiesService) ScreenBonds(f ScreenFilter) ([]ScreenedBond, error) {
+	if f.YieldField == "" {
+		f.YieldField = defaultYieldField
+	}
+	if err := checkScreenField(f.YieldField, true); err != nil {
+		return nil, err
+	}
+	if f.SortBy != "" {
+		if err := checkScreenField(f.SortBy, false); err != nil {
+			return nil, err
+		}
+	}
+
+	snapshots, err := s.repo.GetIndicatorSnapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]screenItem, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		item := screenItem{computedAt: snapshot.ComputedAt}
+		if err := json.Unmarshal(snapshot.Data, &item.indicators); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(snapshot.Data, &item.fields); err != nil {
+			return nil, err
+		}
+		if f.match(item) {
+			items = append(items, item)
+		}
+	}
+
+	if f.SortBy != "" {
+		sort.SliceStable(items, func(i, j int) bool {
+			a, b := items[i].fields[f.SortBy], items[j].fields[f.SortBy]
+			if f.Desc {
+				a, b = b, a
+			}
+			return lessValue(a, b)
+		})
+	}
+
+	if f.Limit > 0 && len(items) > f.Limit {
+		items = items[:f.Limit]
+	}
+
+	bonds := make([]ScreenedBond, len(items))
+	for i, item := range items {
+		bonds[i] = ScreenedBond{bondIndicators: item.indicators, ComputedAt: item.computedAt}
+	}
+	return bonds, nil
+}
+
+// RefreshIndicatorSnapshots рассчитывает показатели всех сохранённых облигаций для резидента РФ и заменяет
+// ими сохранённые ранее. Показатели облигаций, исключённых из списка или не рассчитанных, удаляются.
+// При отмене контекста или если не удалось рассчитать ни одну облигацию сохранённые показатели не изменяются.
+func (s *SecuritiesService) RefreshIndicatorSnapshots(ctx context.Context) error {
+	bonds, err := s.repo.GetBonds()
+	if err != nil {
+		return err
+	}
+
+	isins := make([]string, len(bonds))
+	for i, b := range bonds {
+		isins[i] = b.ISIN
+	}
+
+	results := s.bondIndicatorsResults(ctx, uniqueISINs(isins), IndicatorOptions{Tax: tax.Default()})
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	computedAt := time.Now()
+	snapshots := make([]models.IndicatorSnapshot, 0, len(results))
+	var lastErr string
+	for _, res := range results {
+		if res.Indicators == nil {
+			lastErr = res.Error
+			continue
+		}
+		data, err := json.Marshal(res.Indicators)
+		if err != nil {
+			return err
+		}
+		snapshots = append(snapshots, models.IndicatorSnapshot{Isin: res.Isin, ComputedAt: computedAt, Data: data})
+	}
+
+	if len(snapshots) == 0 && len(results) > 0 {
+		return fmt.Errorf("indicators failed for all %d bonds, last error: %s", len(results), lastErr)
+	}
+
+	updated, err := s.repo.UpdateIndicatorSnapshots(snapshots)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Indicator snapshots updated: %d, failed: %d", updated, len(results)-updated)
+	return nil
+}
+
+func (f ScreenFilter) match(item screenItem) bool {
+	bI := item.indicators
+
+	y, _ := item.fields[f.YieldField].(float64)
+	if f.MinYield != nil && y < *f.MinYield {
+		return false
+	}
+	if f.MaxYield != nil && y > *f.MaxYield {
+		return false
+	}
+	if f.MinDays != nil && bI.DaysToEvent < *f.MinDays {
+		return false
+	}
+	if f.MaxDays != nil && bI.DaysToEvent > *f.MaxDays {
+		return false
+	}
+	if f.FaceUnit != "" && bI.FaceUnit != f.FaceUnit {
+		return false
+	}
+	if f.CouponType != "" && bI.CouponType != f.CouponType {
+		return false
+	}
+	if f.BondType != "" && bI.BondType != f.BondType {
+		return false
+	}
+	if f.Amortization != nil && bI.HasAmortization != *f.Amortization {
+		return false
+	}
+	if f.MinValToday != nil && bI.ValToday < *f.MinValToday {
+		return false
+	}
+	if f.MinNumTrades != nil && bI.NumTrades < *f.MinNumTrades {
+		return false
+	}
+	return true
+}
+
+// checkScreenField проверяет, что показатель field есть среди показателей облигации
+func checkScreenField(field string, numeric bool) error {
+	var fields map[string]any
+	data, err := json.Marshal(bondIndicators{})
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	value, ok := fields[field]
+	if !ok {
+		return fmt.Errorf("%w: unknown field %q", ErrIncorrectFilter, field)
+	}
+	if _, isNumber := value.(float64); numeric && !isNumber {
+		return fmt.Errorf("%w: field %q is not numeric", ErrIncorrectFilter, field)
+	}
+	return nil
+}
+
+// lessValue сравнивает значения показателей одного типа
+func lessValue(a, b any) bool {
+	switch a := a.(type) {
+	case float64:
+		b, _ := b.(float64)
+		return a < b
+	case string:
+		b, _ := b.(string)
+		return a < b
+	case bool:
+		b, _ := b.(bool)
+		return !a && b
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package securities
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"simple-invest/internal/securities/moextest"
+
+	"github.com/WLM1ke/gomoex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecuritiesService_ScreenBonds(t *testing.T) {
+	repo := newMemRepo()
+	repo.bonds = []gomoex.Security{
+		{ISIN: moextest.ISINOfz},
+		{ISIN: moextest.ISINAmortizing},
+		{ISIN: moextest.ISINNoTrades},
+	}
+	s := New(repo, newTestMoexClient(t), Options{})
+
+	require.NoError(t, s.RefreshIndicatorSnapshots(context.Background()))
+	require.Len(t, repo.snapshots, 2)
+
+	minYield := 0.145
+	amortization := true
+	tests := []struct {
+		name   string
+		filter ScreenFilter
+		want   []string
+	}{
+		{
+			name:   "sort by effective yield",
+			filter: ScreenFilter{SortBy: "effective_yield", Desc: true},
+			want:   []string{moextest.ISINAmortizing, moextest.ISINOfz},
+		},
+		{
+			name:   "sort by duration",
+			filter: ScreenFilter{SortBy: "modified_duration"},
+			want:   []string{moextest.ISINAmortizing, moextest.ISINOfz},
+		},
+		{
+			name:   "yield range",
+			filter: ScreenFilter{MinYield: &minYield},
+			want:   []string{moextest.ISINAmortizing},
+		},
+		{
+			name:   "amortization",
+			filter: ScreenFilter{Amortization: &amortization},
+			want:   []string{moextest.ISINAmortizing},
+		},
+		{
+			name:   "limit",
+			filter: ScreenFilter{SortBy: "numtrades", Desc: true, Limit: 1},
+			want:   []string{moextest.ISINOfz},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bonds, err := s.ScreenBonds(tt.filter)
+			require.NoError(t, err)
+			got := make([]string, len(bonds))
+			for i, b := range bonds {
+				got[i] = b.Isin
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	bonds, err := s.ScreenBonds(ScreenFilter{})
+	require.NoError(t, err)
+	require.NotEmpty(t, bonds)
+	assert.Equal(t, repo.snapshots[0].ComputedAt, bonds[0].ComputedAt)
+
+	_, err = s.ScreenBonds(ScreenFilter{SortBy: "unknown"})
+	assert.ErrorIs(t, err, ErrIncorrectFilter)
+	_, err = s.ScreenBonds(ScreenFilter{YieldField: "coupon_type"})
+	assert.ErrorIs(t, err, ErrIncorrectFilter)
+}
+
+func TestSecuritiesService_RefreshIndicatorSnapshots(t *testing.T) {
+	repo := newMemRepo()
+	repo.bonds = []gomoex.Security{{ISIN: moextest.ISINOfz}, {ISIN: moextest.ISINAmortizing}}
+	s := New(repo, newTestMoexClient(t), Options{})
+	ctx := context.Background()
+
+	require.NoError(t, s.RefreshIndicatorSnapshots(ctx))
+	require.Len(t, repo.snapshots, 2)
+
+	// Показатели исключённых из списка облигаций удаляются
+	repo.bonds = []gomoex.Security{{ISIN: moextest.ISINOfz}, {ISIN: moextest.ISINNoTrades}}
+	require.NoError(t, s.RefreshIndicatorSnapshots(ctx))
+	require.Len(t, repo.snapshots, 1)
+	assert.Equal(t, moextest.ISINOfz, repo.snapshots[0].Isin)
+
+	// Если не рассчитана ни одна облигация, показатели не изменяются
+	repo.bonds = []gomoex.Security{{ISIN: moextest.ISINNoTrades}}
+	assert.Error(t, s.RefreshIndicatorSnapshots(ctx))
+	assert.Len(t, repo.snapshots, 1)
+
+	// Количество облигаций не ограничено размером пакетного запроса, при отмене показатели не изменяются
+	repo.bonds = make([]gomoex.Security, maxBatchSize+1)
+	for i := range repo.bonds {
+		repo.bonds[i].ISIN = fmt.Sprintf("RU%010d", i)
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, s.RefreshIndicatorSnapshots(cancelled), context.Canceled)
+	assert.Len(t, repo.snapshots, 1)
+}
@@ -6,92 +6,138 @@ package securities
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
 	"math"
-	"net/http"
+	"simple-invest/internal/models"
 	"simple-invest/internal/repository"
+	"simple-invest/internal/tax"
 	"sort"
 	"time"
 
 	"github.com/WLM1ke/gomoex"
 )
 
+const precision = 4 // Точность предоставляемых показателей
+
+// Типы купона облигации
 const (
-	taxRate   = 0.13 // Ставка налога
-	precision = 4    // Точность предоставляемых показателей
+	CouponFixed    = "fixed"    // Постоянный
+	CouponVariable = "variable" // Переменный, заранее установленный эмитентом
+	CouponFloating = "floating" // Плавающий, привязанный к базовой ставке
+	CouponIndexed  = "indexed"  // Постоянный от индексируемого номинала
 )
 
-var (
-	cl            *gomoex.ISSClient
-	errNoMoexData = errors.New("no moex data provided")
-)
+var errNoMoexData = errors.New("no moex data provided")
+
+// ErrIncorrectOptions возвращается, если показатели не рассчитываются при заданных в запросе параметрах
+var ErrIncorrectOptions = errors.New("incorrect calculation options")
+
+// ErrCalculation возвращается, если показатели не удалось рассчитать по данным облигации
+var ErrCalculation = errors.New("calculation failed")
 
 type SecuritiesService struct {
 	repo repository.Repository
+	md   MarketData
+	opts Options
 }
 
-func New(repo repository.Repository) *SecuritiesService {
-	return &SecuritiesService{repo: repo}
+// Options содержит настройки сервиса
+type Options struct {
+	BondizationMaxAge time.Duration // Срок, после которого сохранённый график выплат загружается с Мосбиржи повторно
+	BatchWorkers      int           // Количество одновременных расчётов в пакетном запросе
+	KeyRate           float64       // Ключевая ставка Банка России для прогноза купонов флоатеров, %. 0 - использовать RUONIA
+	Inflation         float64       // Ожидаемая инфляция для индексации номинала линкеров, % годовых
+	FX                FXSource      // Источник курсов валют. nil - курс рассчитывается по графику выплат облигации
+	PriceSources      []string      // Порядок источников цены облигации при отсутствии сделок
+}
+
+// IndicatorOptions содержит параметры расчёта показателей облигации
+type IndicatorOptions struct {
+	Tax       tax.Model // Налоговая модель
+	Base      string    // Базовая ставка флоатера: key_rate или ruonia, по умолчанию - из настроек сервиса
+	Spread    *float64  // Спред флоатера к базовой ставке, п.п., по умолчанию - по последнему известному купону
+	Inflation *float64  // Ожидаемая инфляция для линкеров, % годовых, по умолчанию - из настроек сервиса
+	FXChange  float64   // Ожидаемое изменение курса валюты номинала к рублю, % годовых
+
+	Price      float64   // Чистая цена в процентах от номинала вместо цены по торговым данным
+	SettleDate time.Time // Дата расчётов вместо текущей
+	Quantity   int64     // Количество облигаций для расчёта показателей позиции
+}
+
+func New(repo repository.Repository, md MarketData, opts Options) *SecuritiesService {
+	if opts.BondizationMaxAge <= 0 {
+		opts.BondizationMaxAge = defaultBondizationMaxAge
+	}
+	if opts.BatchWorkers <= 0 {
+		opts.BatchWorkers = defaultBatchWorkers
+	}
+	if opts.Inflation == 0 {
+		opts.Inflation = defaultInflation
+	}
+	if len(opts.PriceSources) == 0 {
+		opts.PriceSources = defaultPriceSources
+	}
+	return &SecuritiesService{repo: repo, md: md, opts: opts}
 }
 
 // Показатели торгуемой облигации
 type bondIndicators struct {
-	Isin            string  `json:"isin"`              // Ценная бумага
-	FaceValue       float64 `json:"facevalue"`         // Текущая номинальная стоимость
-	AccruedInt      float64 `json:"accruedint"`        // НКД
-	Coupon          float64 `json:"coupon"`            // Сумма купона
-	PercentPrice    float64 `json:"percent_price"`     // Цена в процентах
-	Price           float64 `json:"price"`             // Цена
-	DaysToEvent     int64   `json:"days_to_event"`     // Дней до события
-	MatDate         string  `json:"matdate"`           // Дата погашения
-	OfferDate       string  `json:"offerdate"`         // Дата оферты
-	SimpleYield     float64 `json:"simple_yield"`      // Простая доходоность
-	NetSimpleYield  float64 `json:"net_simple_yield"`  // Итоговая простая доходность
-	CurrentYield    float64 `json:"current_yield"`     // Текущая доходность
-	NetCurrentYield float64 `json:"net_current_yield"` // Итоговая текущая доходность
-	MaturityTax     float64 `json:"maturity_tax"`      // Налог при погашении
+	Isin              string              `json:"isin"`                // Ценная бумага
+	FaceValue         float64             `json:"facevalue"`           // Текущая номинальная стоимость
+	AccruedInt        float64             `json:"accruedint"`          // НКД
+	Coupon            float64             `json:"coupon"`              // Сумма купона
+	PercentPrice      float64             `json:"percent_price"`       // Цена в процентах
+	PriceSource       string              `json:"price_source"`        // Источник цены
+	PriceTime         string              `json:"price_time"`          // Время цены по данным Мосбиржи
+	Price             float64             `json:"price"`               // Цена
+	DaysToEvent       int64               `json:"days_to_event"`       // Дней до события
+	MatDate           string              `json:"matdate"`             // Дата погашения
+	OfferDate         string              `json:"offerdate"`           // Дата оферты
+	SimpleYield       float64             `json:"simple_yield"`        // Простая доходоность
+	NetSimpleYield    float64             `json:"net_simple_yield"`    // Итоговая простая доходность
+	CurrentYield      float64             `json:"current_yield"`       // Текущая доходность
+	NetCurrentYield   float64             `json:"net_current_yield"`   // Итоговая текущая доходность
+	MaturityTax       float64             `json:"maturity_tax"`        // Налог при погашении
+	TaxRate           float64             `json:"tax_rate"`            // Ставка налога на купонный доход
+	EffectiveYield    float64             `json:"effective_yield"`     // Эффективная доходность к погашению
+	NetEffectiveYield float64             `json:"net_effective_yield"` // Итоговая эффективная доходность к погашению
+	MoexYield         float64             `json:"moex_yield"`          // Эффективная доходность по данным Мосбиржи
+	MacaulayDuration  float64             `json:"macaulay_duration"`   // Дюрация Маколея, лет
+	ModifiedDuration  float64             `json:"modified_duration"`   // Модифицированная дюрация
+	Convexity         float64             `json:"convexity"`           // Выпуклость
+	DV01              float64             `json:"dv01"`                // Изменение цены при изменении доходности на 1 б.п.
+	FaceUnit          string              `json:"faceunit"`            // Валюта номинала
+	CouponType        string              `json:"coupon_type"`         // Тип купона
+	BondType          string              `json:"bond_type"`           // Тип облигации
+	Assumptions       Assumptions         `json:"assumptions"`         // Допущения расчёта
+	FX                *fxIndicators       `json:"fx,omitempty"`        // Показатели в рублях для облигаций с номиналом в иностранной валюте
+	Offer             *offerIndicators    `json:"offer,omitempty"`     // Доходности к ближайшей оферте и к погашению
+	Quotes            *quoteIndicators    `json:"quotes,omitempty"`    // Доходности по лучшим ценам спроса и предложения
+	Spreads           *spreadIndicators   `json:"spreads,omitempty"`   // Спреды к G-кривой ОФЗ для рублёвых облигаций
+	Position          *positionIndicators `json:"position,omitempty"`  // Показатели позиции при заданном количестве
+	SettleDate        string              `json:"settledate"`          // Дата расчётов
+	HasAmortization   bool                `json:"has_amortization"`    // Наличие амортизации
+	ValToday          float64             `json:"valtoday"`            // Объём торгов за день, руб.
+	NumTrades         int64               `json:"numtrades"`           // Количество сделок за день
 }
 
 // Структура выплат облигации
 type BondPayments struct {
-	Coupons struct {
-		Columns []string        `json:"columns"` // Названия колонок
-		Data    [][]interface{} `json:"data"`    // Данные
-	} `json:"coupons"`
-	Amortizations struct {
-		Columns []string        `json:"columns"` // Названия колонок
-		Data    [][]interface{} `json:"data"`
-	} `json:"amortizations"`
+	Coupons       issTable `json:"coupons"`
+	Amortizations issTable `json:"amortizations"`
+	Offers        issTable `json:"offers"`
 }
 
 // Параметры конкретного купона
-type Coupon struct {
-	Isin             string  `json:"isin"`             // ISIN код
-	Coupondate       string  `json:"coupondate"`       // Дата выплаты купона
-	Recorddate       string  `json:"recorddate"`       // Дата фиксации списка держателей
-	Initialfacevalue float64 `json:"initialfacevalue"` // Первоначальная номинальная стоимость
-	Facevalue        float64 `json:"facevalue"`        // Номинальная стоимость
-	Faceunit         string  `json:"faceunit"`         // Валюта
-	Value            float64 `json:"value"`            // Сумма купона, в валюте номинала
-	Valueprc         float64 `json:"valueprc"`         // Ставка купона, %
-	ValueRub         float64 `json:"value_rub"`        // Сумма купона, руб
-}
+type Coupon = models.Coupon
 
 // Параметры конкретной амортизационной выплаты
-type Amortization struct {
-	Isin             string    `json:"isin"`             // ISIN код
-	Amortdate        string    `json:"amortdate"`        // Дата амортизации
-	Facevalue        float64   `json:"facevalue"`        // Номинальная стоимость
-	Initialfacevalue float64   `json:"initialfacevalue"` // Первоначальная номинальная стоимость
-	Faceunit         string    `json:"faceunit"`         // Валюта
-	Value            float64   `json:"value"`            // Сумма амортизации, в валюте номинала
-	ValueRub         float64   `json:"value_rub"`        // Сумма амортизации, руб
-	Date             time.Time // Дата амортизации в формате time.Time
-}
+type Amortization = models.Amortization
+
+// Параметры оферты по облигации
+type Offer = models.Offer
 
 // Структура основных свойств облигации
 type Bond struct {
@@ -111,11 +157,26 @@ type Bond struct {
 
 // BondMarketData представляет торговые данные облигации:
 type BondMarketData struct {
-	Last float64 `json:"last"` //последняя цена сделки
+	Last        float64 `json:"last"`        //последняя цена сделки
+	MarketPrice float64 `json:"marketprice"` //рыночная цена
+	WAPrice     float64 `json:"waprice"`     //средневзвешенная цена
+	PrevPrice   float64 `json:"prevprice"`   //цена последней сделки предыдущего дня
+	PrevDate    string  `json:"prevdate"`    //дата предыдущего торгового дня
+	Bid         float64 `json:"bid"`         //лучшая цена спроса
+	Offer       float64 `json:"offer"`       //лучшая цена предложения
+	Yield       float64 `json:"yield"`       //доходность по последней сделке, %
+	ValToday    float64 `json:"valtoday"`    //объём торгов за день, руб.
+	NumTrades   int64   `json:"numtrades"`   //количество сделок за день
+	Time        string  `json:"time"`        //время последней сделки
+	SysTime     string  `json:"systime"`     //время формирования данных
 }
 
-func init() {
-	cl = gomoex.NewISSClient(http.DefaultClient)
+// ShareMarketData представляет торговые данные акции
+type ShareMarketData struct {
+	Last      float64 `json:"last"`      //последняя цена сделки
+	Currency  string  `json:"currency"`  //валюта цены
+	ValToday  float64 `json:"valtoday"`  //объём торгов за день, руб.
+	NumTrades int64   `json:"numtrades"` //количество сделок за день
 }
 
 // Shares возвращает список акций в виде JSON
@@ -139,8 +200,8 @@ func (s *SecuritiesService) Bonds() ([]gomoex.Security, error) {
 }
 
 // DownloadShares получает данные по акциям от Мосбиржи и сохраняет в БД.
-func (s *SecuritiesService) DownloadShares() (err error) {
-	secs, err := boardSecuritiesMOEX(gomoex.EngineStock, gomoex.MarketShares)
+func (s *SecuritiesService) DownloadShares(ctx context.Context) (err error) {
+	secs, err := s.boardSecuritiesMOEX(ctx, gomoex.EngineStock, gomoex.MarketShares)
 	if err != nil {
 		return err
 	}
@@ -154,10 +215,10 @@ func (s *SecuritiesService) DownloadShares() (err error) {
 	return nil
 }
 
-// DownloadShares получает данные по облигациям от Мосбиржи и сохраняет в БД.
-func (s *SecuritiesService) DownloadBonds() (err error) {
+// DownloadBonds получает данные по облигациям от Мосбиржи и сохраняет в БД.
+func (s *SecuritiesService) DownloadBonds(ctx context.Context) (err error) {
 
-	secs, err := boardSecuritiesMOEX(gomoex.EngineStock, gomoex.MarketBonds)
+	secs, err := s.boardSecuritiesMOEX(ctx, gomoex.EngineStock, gomoex.MarketBonds)
 	if err != nil {
 		return err
 	}
@@ -174,260 +235,70 @@ func (s *SecuritiesService) DownloadBonds() (err error) {
 
 // Dividends получает данные о дивидидендах акции от Мосбиржи
 func (s *SecuritiesService) Dividends(ctx context.Context, isin string) ([]gomoex.Dividend, error) {
-	dividends, err := cl.Dividends(ctx, isin)
+	dividends, err := s.md.Dividends(ctx, isin)
 	if err != nil {
 		return nil, err
 	}
 	return dividends, nil
 }
 
-// Coupons получает данные о купонах по облигации от Мосбиржи
-func (s *SecuritiesService) Coupons(isin string) ([]Coupon, error) {
-	// Получение общего объёма данных и объёма, получаемого за одно обращение
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(time.Second*10))
-	defer cancel()
-	url := fmt.Sprintf("https://iss.moex.com/iss/statistics/engines/stock/markets/bonds/bondization/%s.json?iss.only=coupons.cursor&iss.meta=off", isin)
-
-	// Выполняем GET-запрос
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	// Читаем тело ответа
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Print("Failed read response:", err)
-		return nil, err
-	}
-
-	type CouponsCursor struct {
-		Cursor struct {
-			Data [][]int64 `json:"data"`
-		} `json:"coupons.cursor"`
-	}
-
-	// Парсим JSON
-	var couponsCursor CouponsCursor
-	err = json.Unmarshal(body, &couponsCursor)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(couponsCursor.Cursor.Data) < 1 || len(couponsCursor.Cursor.Data[0]) < 3 {
-		return nil, errors.New("incorrect data structure")
-	}
-
-	i := couponsCursor.Cursor.Data[0][0]
-	total := couponsCursor.Cursor.Data[0][1]
-	pagesize := couponsCursor.Cursor.Data[0][2]
-
-	var coupons []Coupon
-	// Последовательное получение блоков данных
-	for ; i < total; i += pagesize {
-		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(time.Second*10))
-		defer cancel()
-		url := fmt.Sprintf("https://iss.moex.com/iss/statistics/engines/stock/markets/bonds/bondization/%s.json?iss.only=coupons&iss.meta=off&start=%d", isin, i)
-
-		// Выполняем GET-запрос
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
-		if err != nil {
-			return nil, err
-		}
-
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return nil, err
-		}
-		defer resp.Body.Close()
-
-		// Читаем тело ответа
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			fmt.Println("Failed read response:", err)
-			return nil, err
-		}
-
-		// Парсим JSON
-		var bondPayments BondPayments
-		err = json.Unmarshal(body, &bondPayments)
-		if err != nil {
-			fmt.Println("Failed parse JSON:", err)
-			return nil, err
-		}
-
-		// Преобразуем данные в структуру Coupon
-		for _, row := range bondPayments.Coupons.Data {
-			coupon := Coupon{
-				Isin:             row[0].(string),
-				Coupondate:       row[3].(string),
-				Recorddate:       row[4].(string),
-				Initialfacevalue: row[6].(float64),
-				Facevalue:        row[7].(float64),
-				Faceunit:         row[8].(string),
-				Value:            row[9].(float64),
-				Valueprc:         row[10].(float64),
-				ValueRub:         row[11].(float64),
-			}
-			coupons = append(coupons, coupon)
-		}
-	}
-
-	return coupons, nil
+// Coupons возвращает данные о купонах по облигации. При установленном update данные загружаются от Мосбиржи.
+func (s *SecuritiesService) Coupons(isin string, update bool) ([]Coupon, error) {
+	coupons, _, err := s.bondization(context.Background(), isin, update)
+	return coupons, err
 }
 
-// Amortizations получает данные об амортизационных выплатах по облигации от Мосбиржи
-func (s *SecuritiesService) Amortizations(isin string) ([]Amortization, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(time.Second*10))
-	defer cancel()
-
-	url := fmt.Sprintf("https://iss.moex.com/iss/statistics/engines/stock/markets/bonds/bondization/%s.json?iss.only=amortizations", isin)
-
-	// Выполняем GET-запрос
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	// Читаем тело ответа
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Println("Failed read response:", err)
-		return nil, err
-	}
-
-	// Парсим JSON
-	var bondPayments BondPayments
-	err = json.Unmarshal(body, &bondPayments)
-	if err != nil {
-		fmt.Println("Failed parse JSON:", err)
-		return nil, err
-	}
-
-	// Преобразуем полученные по API данные в структуру Amortization
-	var amortizations []Amortization
-	for _, row := range bondPayments.Amortizations.Data {
-		amortization := Amortization{
-			Isin:             row[0].(string),
-			Amortdate:        row[3].(string),
-			Facevalue:        row[4].(float64),
-			Initialfacevalue: row[5].(float64),
-			Faceunit:         row[6].(string),
-			Value:            row[8].(float64),
-			ValueRub:         row[9].(float64),
-		}
-		amortizations = append(amortizations, amortization)
-	}
-
-	return amortizations, nil
+// Amortizations возвращает данные об амортизационных выплатах по облигации. При установленном update данные загружаются от Мосбиржи.
+func (s *SecuritiesService) Amortizations(isin string, update bool) ([]Amortization, error) {
+	_, amortizations, err := s.bondization(context.Background(), isin, update)
+	return amortizations, err
 }
 
-// BondIndicators возвращает JSON с основными показателями торгуемой облигации
-func (s *SecuritiesService) BondIndicators(isin string) (bondIndicators, error) {
-	bI := bondIndicators{Isin: isin}
-
-	bond, err := moexBond(isin)
-	if err != nil {
-		return bI, err
-	}
-
-	marketData, err := moexBondMarketData(isin)
+// BondIndicators возвращает JSON с основными показателями торгуемой облигации в валюте номинала, а для облигаций
+// с номиналом в иностранной валюте - также в рублях по курсу источника opts.FX сервиса.
+// Итоговые доходности рассчитываются с учётом налога по модели opts.Tax. Неизвестные будущие купоны флоатеров
+// и индексация номинала линкеров прогнозируются по параметрам opts, принятые допущения возвращаются в assumptions.
+// Заданные в opts цена и дата расчётов позволяют оценить заявку до её выставления, неторгуемую облигацию
+// или показатели на прошедшую дату.
+func (s *SecuritiesService) BondIndicators(ctx context.Context, isin string, opts IndicatorOptions) (bondIndicators, error) {
+	d, err := s.pricedBondData(ctx, isin, opts)
 	if err != nil {
-		return bI, err
+		return bondIndicators{Isin: isin}, err
 	}
-
-	eventDateStr := bond.MatDate
-	if bond.OfferDate != "" {
-		eventDateStr = bond.OfferDate
-	}
-	eventDate, err := time.Parse(time.DateOnly, eventDateStr)
-	if err != nil {
-		return bI, err
+	bI, err := calcBondIndicators(d, opts, time.Now().Truncate(time.Hour*24))
+	if err != nil && opts.Price != 0 && errors.Is(err, ErrCalculation) {
+		return bI, fmt.Errorf("%w: price %v: %w", ErrIncorrectOptions, opts.Price, err)
 	}
+	return bI, err
+}
 
-	today := time.Now().Truncate(time.Hour * 24)
-
-	var settleDate time.Time
-	if bond.SettleDate == "" {
-		settleDate = today.AddDate(0, 0, 1)
-	} else {
-		settleDate, err = time.Parse(time.DateOnly, bond.SettleDate)
-		if err != nil {
-			return bI, err
+// pricedBondData загружает данные облигации вместе с торговыми данными Мосбиржи. Без торговых данных
+// данные облигации возвращаются, только если цена задана в opts, остальные ошибки Мосбиржи возвращаются.
+func (s *SecuritiesService) pricedBondData(ctx context.Context, isin string, opts IndicatorOptions) (bondData, error) {
+	var market *BondMarketData
+	var price *marketPrice
+	marketData, err := s.md.BondMarketData(ctx, isin)
+	if err == nil {
+		market = &marketData
+		if p, ok := marketData.price(s.opts.PriceSources); ok {
+			price = &p
+		} else {
+			err = errNoMoexData
 		}
 	}
-
-	bI.FaceValue = bond.FaceValue
-	bI.AccruedInt = bond.AccruedInt
-	bI.Coupon = bond.CouponValue
-	bI.PercentPrice = marketData.Last
-	bI.Price = roundFloat(bond.FaceValue*marketData.Last/100, 2) + bond.AccruedInt
-	bI.DaysToEvent = int64(eventDate.Sub(today).Hours() / 24)
-	bI.MatDate = bond.MatDate
-	bI.OfferDate = bond.OfferDate
-
-	if marketData.Last != 0 {
-		bI.CurrentYield = roundFloat(bond.CouponPercent/marketData.Last, precision)
-		bI.NetCurrentYield = roundFloat(bond.CouponPercent*bond.FaceValue*(1-taxRate)/bI.Price/100, precision)
+	if err != nil && (opts.Price == 0 || !errors.Is(err, errNoMoexData)) {
+		return bondData{}, err
 	}
 
-	couponsAmount := 0.0
-	coupons, err := s.Coupons(isin)
+	d, err := s.bondData(ctx, isin, opts)
 	if err != nil {
-		return bI, err
+		return d, err
 	}
-	for _, c := range coupons {
-		paymentDate, err := time.Parse(time.DateOnly, c.Coupondate)
-		if err != nil {
-			return bI, err
-		}
-		if paymentDate.After(settleDate) {
-			couponsAmount += c.Value
-		}
-	}
-
-	// Для амортизируемых ооблигаций необходимо приведение периода
-	netDaysToEvent := float64(bI.DaysToEvent)
-	amortizations, err := s.Amortizations(isin)
-	if err != nil {
-		return bI, err
-	}
-	if len(amortizations) > 0 {
-		netDaysToEvent, err = amortizationsNetPeriod(amortizations, settleDate)
-		if err != nil {
-			return bI, err
-		}
-	}
-
-	bI.SimpleYield = roundFloat((couponsAmount+bond.FaceValue-bI.Price)/bI.Price*365/netDaysToEvent, precision)
-	creditDate := settleDate.AddDate(3, 0, 0) // дата для ЛДВ
-	matTax := 0.0
-	if !eventDate.After(creditDate) && bI.Price < bond.FaceValue {
-		matTax = roundFloat((bond.FaceValue-bI.Price)*taxRate, 2)
-	}
-	bI.NetSimpleYield = roundFloat((couponsAmount*(1-taxRate)+bond.FaceValue-matTax-bI.Price)/bI.Price*365/netDaysToEvent, precision)
-
-	bI.MaturityTax = matTax
-
-	return bI, nil
+	d.market, d.price = market, price
+	return d, nil
 }
 
-func boardSecuritiesMOEX(engine, market string) ([]gomoex.Security, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
-	defer cancel()
-
+func (s *SecuritiesService) boardSecuritiesMOEX(ctx context.Context, engine, market string) ([]gomoex.Security, error) {
 	var board string
 	if market == gomoex.MarketBonds {
 		board = "TQCB"
@@ -436,7 +307,7 @@ func boardSecuritiesMOEX(engine, market string) ([]gomoex.Security, error) {
 	}
 
 	var err error
-	table, err := cl.BoardSecurities(ctx, engine, market, board)
+	table, err := s.md.BoardSecurities(ctx, engine, market, board)
 	if err != nil {
 		return table, err
 	}
@@ -444,112 +315,24 @@ func boardSecuritiesMOEX(engine, market string) ([]gomoex.Security, error) {
 	return table, nil
 }
 
-func moexBond(isin string) (Bond, error) {
-	b := Bond{Isin: isin}
-
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(time.Second*10))
-	defer cancel()
-	secProperties := "ISIN,SHORTNAME,ACCRUEDINT,FACEVALUE,MATDATE,COUPONPERIOD,COUPONPERCENT,SECNAME,FACEUNIT,COUPONPERCENT,OFFERDATE,SETTLEDATE,COUPONVALUE"
-	url := fmt.Sprintf("https://iss.moex.com/iss/engines/stock/markets/bonds/securities/%s.json?iss.meta=off&iss.only=securities,&securities.columns=%s", isin, secProperties)
-
-	// Выполняем GET-запрос
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
-	if err != nil {
-		return b, err
-	}
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return b, err
-	}
-	defer resp.Body.Close()
-
-	// Читаем тело ответа
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Println("Ошибка при чтении ответа:", err)
-		return b, err
-	}
-
-	// Парсим JSON
-	type moexBond struct {
-		Securities struct {
-			Data [][]interface{}
-		} `json:"securities"`
-	}
-
-	var mBond moexBond
-	err = json.Unmarshal(body, &mBond)
-	if err != nil {
-		return b, err
-	}
-
-	for _, row := range mBond.Securities.Data {
-		b.ShortName, _ = row[1].(string)
-		b.AccruedInt, _ = row[2].(float64)
-		b.FaceValue, _ = row[3].(float64)
-		b.MatDate, _ = row[4].(string)
-		b.CouponPeriod = int32(row[5].(float64))
-		b.CouponPercent, _ = row[6].(float64)
-		b.SecName, _ = row[7].(string)
-		b.FaceUnit, _ = row[8].(string)
-		b.CouponPercent, _ = row[9].(float64)
-		b.OfferDate, _ = row[10].(string)
-		b.SettleDate, _ = row[11].(string)
-		b.CouponValue, _ = row[12].(float64)
-	}
-	return b, nil
-}
-
-func moexBondMarketData(isin string) (BondMarketData, error) {
-	var marketData BondMarketData
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(time.Second*10))
-	defer cancel()
-	secProperties := "LAST"
-	url := fmt.Sprintf("https://iss.moex.com/iss/engines/stock/markets/bonds/securities/%s.json?iss.meta=off&iss.only=marketdata&marketdata.columns=%s", isin, secProperties)
-
-	// Выполняем GET-запрос
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
-	if err != nil {
-		return marketData, err
-	}
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return marketData, err
-	}
-	defer resp.Body.Close()
-
-	// Читаем тело ответа
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Println("Ошибка при чтении ответа:", err)
-		return marketData, err
-	}
-
-	type moexMarketData struct {
-		MarketData struct {
-			Data [][]interface{}
-		} `json:"marketdata"`
-	}
-
-	var moexData moexMarketData
-	err = json.Unmarshal(body, &moexData)
-	if err != nil {
-		return marketData, err
-	}
-
-	for _, row := range moexData.MarketData.Data {
-		if row[0] == nil {
-			return marketData, errNoMoexData
+// couponType определяет тип купона по будущим выплатам: постоянный, если ставки всех будущих купонов
+// известны и совпадают, иначе - переменный.
+func couponType(coupons []Coupon, settleDate time.Time) (string, error) {
+	rate := -1.0
+	for _, c := range coupons {
+		date, err := time.Parse(time.DateOnly, c.Coupondate)
+		if err != nil {
+			return "", err
+		}
+		if !date.After(settleDate) {
+			continue
 		}
-		var ok bool
-		marketData.Last, ok = row[0].(float64)
-		if !ok {
-			return marketData, fmt.Errorf("cannot convert data %v to float64", row[0])
+		if c.Value == 0 || (rate >= 0 && c.Valueprc != rate) {
+			return CouponVariable, nil
 		}
+		rate = c.Valueprc
 	}
-	return marketData, nil
+	return CouponFixed, nil
 }
 
 func roundFloat(val float64, precision uint) float64 {
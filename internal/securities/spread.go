@@ -0,0 +1,92 @@
+package securities
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"simple-invest/internal/curves"
+	"simple-invest/internal/models"
+)
+
+// maxCurveLag - максимальное количество торговых дней между датой G-кривой и датой расчётов
+const maxCurveLag = 5
+
+// Спреды доходности облигации к G-кривой ОФЗ
+type spreadIndicators struct {
+	CurveDate  string  `json:"curve_date"`  // Дата G-кривой
+	CurveYield float64 `json:"curve_yield"` // Доходность G-кривой на срок дюрации Маколея облигации
+	GSpread    float64 `json:"g_spread"`    // Разница эффективной доходности и доходности G-кривой, б.п.
+	ZSpread    float64 `json:"z_spread"`    // Постоянная надбавка к G-кривой, при которой приведённая стоимость выплат равна цене, б.п.
+}
+
+// curveSpreads рассчитывает G-спред при эффективной доходности y и дюрации duration и Z-спред
+// по графику выплат flows и цене price (с НКД)
+func curveSpreads(flows []cashFlow, price, y, duration float64, settleDate time.Time, zcyc models.ZCYC) (*spreadIndicators, error) {
+	z, err := zSpread(flows, price, settleDate, zcyc)
+	if err != nil {
+		return nil, err
+	}
+	curveYield := curves.Yield(zcyc, duration)
+	return &spreadIndicators{
+		CurveDate:  zcyc.Date,
+		CurveYield: roundFloat(curveYield, precision),
+		GSpread:    roundFloat((y-curveYield)*10000, 2),
+		ZSpread:    roundFloat(z*10000, 2),
+	}, nil
+}
+
+// curvePresentValue дисконтирует выплаты на дату расчётов по доходностям G-кривой, увеличенным на спред z
+func curvePresentValue(flows []cashFlow, z float64, settleDate time.Time, zcyc models.ZCYC) float64 {
+	pv := 0.0
+	for _, cf := range flows {
+		t := yearFraction(settleDate, cf.Date)
+		base := 1 + curves.Yield(zcyc, t) + z
+		if base <= 0 {
+			return math.Inf(1)
+		}
+		pv += cf.amount() / math.Pow(base, t)
+	}
+	return pv
+}
+
+// zSpread подбирает постоянный спред к G-кривой, при котором приведённая стоимость выплат равна цене price (с НКД)
+func zSpread(flows []cashFlow, price float64, settleDate time.Time, zcyc models.ZCYC) (float64, error) {
+	if len(flows) == 0 {
+		return 0, ErrNoCashFlows
+	}
+	if price <= 0 {
+		return 0, fmt.Errorf("%w: price must be positive", ErrCalculation)
+	}
+
+	low, high := minYield, maxYield
+	if curvePresentValue(flows, low, settleDate, zcyc) < price || curvePresentValue(flows, high, settleDate, zcyc) > price {
+		return 0, fmt.Errorf("%w: z-spread out of range", ErrCalculation)
+	}
+	for high-low > yieldAccuracy {
+		mid := (low + high) / 2
+		if curvePresentValue(flows, mid, settleDate, zcyc) > price {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+
+	return (low + high) / 2, nil
+}
+
+// curveFresh определяет, рассчитана ли кривая zcyc не более чем за maxCurveLag торговых дней до даты расчётов.
+// Торговыми днями считаются будние дни.
+func curveFresh(zcyc models.ZCYC, settleDate time.Time) (bool, error) {
+	date, err := time.Parse(time.DateOnly, zcyc.Date)
+	if err != nil {
+		return false, err
+	}
+	lag := 0
+	for d := date.AddDate(0, 0, 1); !d.After(settleDate); d = d.AddDate(0, 0, 1) {
+		if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+			lag++
+		}
+	}
+	return lag <= maxCurveLag, nil
+}
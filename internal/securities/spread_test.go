@@ -0,0 +1,138 @@
+package securities
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"simple-invest/internal/models"
+	"simple-invest/internal/securities/moextest"
+	"simple-invest/internal/tax"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_curveSpreads(t *testing.T) {
+	settle := time.Date(2024, 6, 4, 0, 0, 0, 0, time.UTC)
+	flows := []cashFlow{
+		{Date: settle.AddDate(0, 6, 0), Coupon: 50},
+		{Date: settle.AddDate(1, 0, 0), Coupon: 50},
+		{Date: settle.AddDate(1, 6, 0), Coupon: 50},
+		{Date: settle.AddDate(2, 0, 0), Coupon: 50, Principal: 1000},
+	}
+	// Плоская кривая с непрерывной доходностью 10%
+	flat := models.ZCYC{Date: "2024-06-03", B1: 1000, T1: 1}
+	curveYield := math.Exp(0.1) - 1
+
+	tests := []struct {
+		name   string
+		spread float64
+	}{
+		{"above curve", 0.02},
+		{"below curve", -0.015},
+		{"on curve", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			y := curveYield + tt.spread
+			price := presentValue(flows, y, settle)
+			duration := rateSensitivity(flows, y, settle).MacaulayDuration
+
+			got, err := curveSpreads(flows, price, y, duration, settle, flat)
+			require.NoError(t, err)
+			assert.Equal(t, "2024-06-03", got.CurveDate)
+			assert.Equal(t, roundFloat(curveYield, precision), got.CurveYield)
+			// На плоской кривой G-спред и Z-спред совпадают
+			assert.InDelta(t, tt.spread*10000, got.GSpread, 0.01)
+			assert.InDelta(t, tt.spread*10000, got.ZSpread, 0.01)
+		})
+	}
+
+	_, err := zSpread(nil, 1000, settle, flat)
+	assert.ErrorIs(t, err, ErrNoCashFlows)
+}
+
+func TestSecuritiesService_BondIndicatorsSpreads(t *testing.T) {
+	repo := newMemRepo()
+	s := New(repo, newTestMoexClient(t), Options{})
+	ctx := context.Background()
+	opts := IndicatorOptions{Tax: tax.Default()}
+
+	// Без сохранённой G-кривой спреды не рассчитываются
+	got, err := s.BondIndicators(ctx, moextest.ISINOfz, opts)
+	require.NoError(t, err)
+	assert.Nil(t, got.Spreads)
+
+	repo.zcyc = &moextest.ZCYC
+	ofz, err := s.BondIndicators(ctx, moextest.ISINOfz, opts)
+	require.NoError(t, err)
+	require.NotNil(t, ofz.Spreads)
+	assert.Equal(t, "2024-06-04", ofz.Spreads.CurveDate)
+	assert.InDelta(t, ofz.EffectiveYield*10000-ofz.Spreads.CurveYield*10000, ofz.Spreads.GSpread, 1)
+	// Доходность ОФЗ близка к G-кривой
+	assert.Less(t, math.Abs(ofz.Spreads.GSpread), 100.0)
+	assert.Less(t, math.Abs(ofz.Spreads.ZSpread), 100.0)
+
+	// Более низкая цена - более высокие спреды
+	cheaper := opts
+	cheaper.Price = ofz.PercentPrice - 5
+	got, err = s.BondIndicators(ctx, moextest.ISINOfz, cheaper)
+	require.NoError(t, err)
+	require.NotNil(t, got.Spreads)
+	assert.Greater(t, got.Spreads.GSpread, ofz.Spreads.GSpread)
+	assert.Greater(t, got.Spreads.ZSpread, ofz.Spreads.ZSpread)
+
+	// Кривая, рассчитанная после даты расчётов, не используется
+	later := moextest.ZCYC
+	later.Date = "2024-06-05"
+	repo.zcyc = &later
+	got, err = s.BondIndicators(ctx, moextest.ISINOfz, opts)
+	require.NoError(t, err)
+	assert.Nil(t, got.Spreads)
+
+	// Кривая, рассчитанная более чем за maxCurveLag торговых дней до даты расчётов, не используется
+	stale := moextest.ZCYC
+	stale.Date = "2024-05-27"
+	repo.zcyc = &stale
+	got, err = s.BondIndicators(ctx, moextest.ISINOfz, opts)
+	require.NoError(t, err)
+	assert.Nil(t, got.Spreads)
+
+	// Если Z-спред не подбирается, показатели возвращаются без спредов
+	d, err := s.bondData(ctx, moextest.ISINOfz, opts)
+	require.NoError(t, err)
+	d.curve = &models.ZCYC{Date: "2024-06-04", B1: 200000, T1: 1}
+	d.price = &marketPrice{Source: ofz.PriceSource, Price: ofz.PercentPrice}
+	got, err = calcBondIndicators(d, opts, time.Now())
+	require.NoError(t, err)
+	assert.Nil(t, got.Spreads)
+	assert.Equal(t, ofz.EffectiveYield, got.EffectiveYield)
+
+	// Для облигаций с номиналом в иностранной валюте спреды к G-кривой не рассчитываются
+	repo.zcyc = &moextest.ZCYC
+	got, err = s.BondIndicators(ctx, moextest.ISINCurrency, opts)
+	require.NoError(t, err)
+	assert.Nil(t, got.Spreads)
+}
+
+func Test_curveFresh(t *testing.T) {
+	settle := time.Date(2024, 6, 4, 0, 0, 0, 0, time.UTC) // Вторник
+	tests := []struct {
+		date string
+		want bool
+	}{
+		{"2024-06-04", true},
+		{"2024-05-31", true}, // Выходные не учитываются
+		{"2024-05-28", true},
+		{"2024-05-27", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			got, err := curveFresh(models.ZCYC{Date: tt.date}, settle)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
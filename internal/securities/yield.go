@@ -0,0 +1,167 @@
+package securities
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	daysInYear    = 365.0 // База расчёта доходности
+	yieldAccuracy = 1e-10 // Точность подбора доходности
+	minYield      = -0.99 // Нижняя граница поиска доходности
+	maxYield      = 100.0 // Верхняя граница поиска доходности
+)
+
+// ErrNoCashFlows возвращается, если после даты расчётов нет выплат по облигации
+var ErrNoCashFlows = errors.New("no future cash flows")
+
+// cashFlow представляет будущую выплату по облигации
+type cashFlow struct {
+	Date      time.Time // Дата выплаты
+	Coupon    float64   // Купон
+	Principal float64   // Погашение номинала (амортизация, погашение, выкуп по оферте)
+}
+
+func (cf cashFlow) amount() float64 {
+	return cf.Coupon + cf.Principal
+}
+
+// bondCashFlows формирует график будущих выплат в интервале (settleDate, eventDate].
+// Непогашенная на дату события часть номинала выплачивается в дату события.
+func bondCashFlows(coupons []Coupon, amortizations []Amortization, faceValue float64, settleDate, eventDate time.Time) ([]cashFlow, error) {
+	byDate := make(map[time.Time]*cashFlow)
+	flow := func(date time.Time) *cashFlow {
+		cf, ok := byDate[date]
+		if !ok {
+			cf = &cashFlow{Date: date}
+			byDate[date] = cf
+		}
+		return cf
+	}
+
+	for _, c := range coupons {
+		date, err := time.Parse(time.DateOnly, c.Coupondate)
+		if err != nil {
+			return nil, err
+		}
+		if date.After(settleDate) && !date.After(eventDate) {
+			flow(date).Coupon += c.Value
+		}
+	}
+
+	// Амортизация в дату события учитывается в остатке номинала
+	outstanding := faceValue
+	for _, a := range amortizations {
+		date, err := time.Parse(time.DateOnly, a.Amortdate)
+		if err != nil {
+			return nil, err
+		}
+		if date.After(settleDate) && date.Before(eventDate) {
+			flow(date).Principal += a.Value
+			outstanding -= a.Value
+		}
+	}
+	if outstanding > 0 {
+		flow(eventDate).Principal += outstanding
+	}
+
+	flows := make([]cashFlow, 0, len(byDate))
+	for _, cf := range byDate {
+		flows = append(flows, *cf)
+	}
+	sort.Slice(flows, func(i, j int) bool {
+		return flows[i].Date.Before(flows[j].Date)
+	})
+
+	return flows, nil
+}
+
+// netCashFlows возвращает выплаты за вычетом НДФЛ с купонов и налога при погашении
+func netCashFlows(flows []cashFlow, taxRate, maturityTax float64) []cashFlow {
+	net := make([]cashFlow, len(flows))
+	for i, cf := range flows {
+		net[i] = cashFlow{
+			Date:      cf.Date,
+			Coupon:    cf.Coupon * (1 - taxRate),
+			Principal: cf.Principal,
+		}
+	}
+	if len(net) > 0 {
+		net[len(net)-1].Principal -= maturityTax
+	}
+	return net
+}
+
+// yearFraction возвращает срок от даты расчётов до даты выплаты в годах
+func yearFraction(settleDate, date time.Time) float64 {
+	return date.Sub(settleDate).Hours() / 24 / daysInYear
+}
+
+// presentValue дисконтирует выплаты на дату расчётов по эффективной ставке y
+func presentValue(flows []cashFlow, y float64, settleDate time.Time) float64 {
+	pv := 0.0
+	for _, cf := range flows {
+		pv += cf.amount() / math.Pow(1+y, yearFraction(settleDate, cf.Date))
+	}
+	return pv
+}
+
+// effectiveYield рассчитывает эффективную доходность к погашению (внутреннюю норму доходности)
+// при покупке по цене price (с НКД) в дату settleDate.
+func effectiveYield(flows []cashFlow, price float64, settleDate time.Time) (float64, error) {
+	if len(flows) == 0 {
+		return 0, ErrNoCashFlows
+	}
+	if price <= 0 {
+		return 0, fmt.Errorf("%w: price must be positive", ErrCalculation)
+	}
+
+	// Приведённая стоимость монотонно убывает с ростом ставки, поэтому используется метод деления отрезка пополам
+	low, high := minYield, maxYield
+	if presentValue(flows, low, settleDate) < price || presentValue(flows, high, settleDate) > price {
+		return 0, fmt.Errorf("%w: yield out of range", ErrCalculation)
+	}
+	for high-low > yieldAccuracy {
+		mid := (low + high) / 2
+		if presentValue(flows, mid, settleDate) > price {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+
+	return (low + high) / 2, nil
+}
+
+// sensitivity содержит показатели чувствительности цены облигации к изменению доходности
+type sensitivity struct {
+	MacaulayDuration float64 // Дюрация Маколея, лет
+	ModifiedDuration float64 // Модифицированная дюрация
+	Convexity        float64 // Выпуклость
+	DV01             float64 // Изменение цены при изменении доходности на 1 б.п., руб.
+}
+
+// rateSensitivity рассчитывает дюрацию, выпуклость и DV01 по графику выплат при эффективной доходности y
+func rateSensitivity(flows []cashFlow, y float64, settleDate time.Time) sensitivity {
+	var pv, weighted, convex float64
+	for _, cf := range flows {
+		t := yearFraction(settleDate, cf.Date)
+		discounted := cf.amount() / math.Pow(1+y, t)
+		pv += discounted
+		weighted += t * discounted
+		convex += t * (t + 1) * discounted
+	}
+
+	var s sensitivity
+	if pv == 0 {
+		return s
+	}
+	s.MacaulayDuration = weighted / pv
+	s.ModifiedDuration = s.MacaulayDuration / (1 + y)
+	s.Convexity = convex / (pv * (1 + y) * (1 + y))
+	s.DV01 = s.ModifiedDuration * pv * 0.0001
+	return s
+}
@@ -0,0 +1,99 @@
+package securities
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_bondCashFlows(t *testing.T) {
+	settleDate := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	eventDate := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	coupons := []Coupon{
+		{Coupondate: "2023-07-01", Value: 50},
+		{Coupondate: "2024-07-01", Value: 50},
+		{Coupondate: "2025-01-01", Value: 25},
+		{Coupondate: "2025-07-01", Value: 25},
+	}
+	amortizations := []Amortization{
+		{Amortdate: "2024-07-01", Value: 500},
+		{Amortdate: "2025-01-01", Value: 500},
+	}
+
+	got, err := bondCashFlows(coupons, amortizations, 1000, settleDate, eventDate)
+	require.NoError(t, err)
+	assert.Equal(t, []cashFlow{
+		{Date: time.Date(2024, time.July, 1, 0, 0, 0, 0, time.UTC), Coupon: 50, Principal: 500},
+		{Date: eventDate, Coupon: 25, Principal: 500},
+	}, got)
+}
+
+func Test_effectiveYield(t *testing.T) {
+	settleDate := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		flows   []cashFlow
+		price   float64
+		want    float64
+		wantErr bool
+	}{
+		{
+			name:    "no cash flows",
+			flows:   nil,
+			price:   1000,
+			wantErr: true,
+		},
+		{
+			name: "zero coupon one year",
+			flows: []cashFlow{
+				{Date: settleDate.AddDate(0, 0, 365), Principal: 1000},
+			},
+			price: 900,
+			want:  0.1111,
+		},
+		{
+			name: "par bond with annual coupons",
+			flows: []cashFlow{
+				{Date: settleDate.AddDate(0, 0, 365), Coupon: 100},
+				{Date: settleDate.AddDate(0, 0, 730), Coupon: 100, Principal: 1000},
+			},
+			price: 1000,
+			want:  0.1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := effectiveYield(tt.flows, tt.price, settleDate)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, roundFloat(got, precision))
+		})
+	}
+}
+
+func Test_rateSensitivity(t *testing.T) {
+	settleDate := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	// Для бескупонной облигации дюрация Маколея равна сроку до погашения
+	zero := []cashFlow{{Date: settleDate.AddDate(0, 0, 730), Principal: 1000}}
+	got := rateSensitivity(zero, 0.1, settleDate)
+	assert.Equal(t, 2.0, roundFloat(got.MacaulayDuration, precision))
+	assert.Equal(t, 1.8182, roundFloat(got.ModifiedDuration, precision))
+	assert.Equal(t, 4.9587, roundFloat(got.Convexity, precision))
+	assert.Equal(t, 0.1503, roundFloat(got.DV01, precision))
+
+	par := []cashFlow{
+		{Date: settleDate.AddDate(0, 0, 365), Coupon: 100},
+		{Date: settleDate.AddDate(0, 0, 730), Coupon: 100, Principal: 1000},
+	}
+	got = rateSensitivity(par, 0.1, settleDate)
+	assert.Equal(t, 1.9091, roundFloat(got.MacaulayDuration, precision))
+	assert.Equal(t, 1.7355, roundFloat(got.ModifiedDuration, precision))
+	assert.Equal(t, 0.1736, roundFloat(got.DV01, precision))
+}
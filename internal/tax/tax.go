@@ -0,0 +1,156 @@
+// Пакет tax реализует расчёт налога на доходы физических лиц (НДФЛ) по инвестиционным доходам.
+//
+// Ставка зависит от налогового периода, налогового резидентства и суммы доходов с начала года:
+// до 2021 года для резидентов действует единая ставка 13%, с 2021 года - прогрессивная шкала 13% и 15%.
+package tax
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// Income - вид инвестиционного дохода
+type Income int
+
+const (
+	CapitalGain     Income = iota // Доход от продажи и погашения ценных бумаг
+	Coupon                        // Купоны корпоративных облигаций
+	GovCoupon                     // Купоны государственных, субфедеральных и муниципальных облигаций
+	Dividend                      // Дивиденды российских эмитентов
+	ForeignDividend               // Дивиденды иностранных эмитентов
+)
+
+const (
+	baseRate           = 0.13 // Основная ставка для резидентов
+	highRate           = 0.15 // Ставка для доходов, превышающих порог прогрессивной шкалы
+	nonResidentRate    = 0.30 // Ставка для нерезидентов
+	nonResidentDivRate = 0.15 // Ставка для дивидендов российских эмитентов, выплачиваемых нерезидентам
+
+	progressiveYear = 2021 // Первый год прогрессивной шкалы и налогообложения купонов государственных облигаций
+
+	ldvYears = 3 // Срок владения для льготы на долгосрочное владение (ЛДВ), лет
+)
+
+// ЛДВ применяется к бумагам, приобретённым начиная с этой даты
+var ldvStartDate = time.Date(2014, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Порог прогрессивной шкалы по годам начала действия, руб.
+var thresholds = []struct {
+	year  int
+	limit float64
+}{
+	{2025, 2_400_000},
+	{progressiveYear, 5_000_000},
+}
+
+// Коды типа бумаги (SECTYPE) Мосбиржи для государственных, субфедеральных и муниципальных облигаций
+var govSecTypes = map[string]bool{"3": true, "5": true, "C": true}
+
+// Model - налоговая модель физического лица
+type Model struct {
+	Year     int     `json:"year"`     // Налоговый период
+	Resident bool    `json:"resident"` // Налоговый резидент РФ
+	Income   float64 `json:"income"`   // Доходы с начала года, учитываемые при определении ставки прогрессивной шкалы
+}
+
+// Default возвращает модель для резидента РФ в текущем году
+func Default() Model {
+	return Model{Year: time.Now().Year(), Resident: true}
+}
+
+// Rate возвращает ставку налога для следующего рубля дохода вида kind
+func (m Model) Rate(kind Income) float64 {
+	if m.exempt(kind) {
+		return 0
+	}
+	if !m.Resident {
+		return m.nonResidentRate(kind)
+	}
+	if limit := m.threshold(); limit > 0 && m.Income >= limit {
+		return highRate
+	}
+	return baseRate
+}
+
+// Tax возвращает налог с дохода base вида kind с учётом доходов с начала года
+func (m Model) Tax(kind Income, base float64) float64 {
+	if base <= 0 || m.exempt(kind) {
+		return 0
+	}
+	if !m.Resident {
+		return base * m.nonResidentRate(kind)
+	}
+
+	limit := m.threshold()
+	if limit == 0 {
+		return base * baseRate
+	}
+	low := math.Max(math.Min(base, limit-m.Income), 0)
+	return low*baseRate + (base-low)*highRate
+}
+
+// Add возвращает модель с доходами с начала года, увеличенными на base
+func (m Model) Add(base float64) Model {
+	if base > 0 {
+		m.Income += base
+	}
+	return m
+}
+
+// LongTermExempt определяет применение ЛДВ к доходу от выбытия бумаг, приобретённых в дату bought и выбывших
+// в дату sold. Льгота предоставляется только налоговым резидентам.
+func (m Model) LongTermExempt(bought, sold time.Time) bool {
+	return m.Resident && LongTermHolding(bought, sold)
+}
+
+// LongTermHolding определяет, выполнены ли условия ЛДВ по сроку владения бумагами, приобретёнными в дату bought
+// и выбывшими в дату sold
+func LongTermHolding(bought, sold time.Time) bool {
+	return !bought.Before(ldvStartDate) && sold.After(bought.AddDate(ldvYears, 0, 0))
+}
+
+// exempt определяет освобождение дохода от налога
+func (m Model) exempt(kind Income) bool {
+	switch kind {
+	case GovCoupon:
+		return m.Year < progressiveYear
+	case ForeignDividend:
+		// Нерезиденты не уплачивают в РФ налог с доходов от источников за её пределами
+		return !m.Resident
+	}
+	return false
+}
+
+func (m Model) nonResidentRate(kind Income) float64 {
+	if kind == Dividend {
+		return nonResidentDivRate
+	}
+	return nonResidentRate
+}
+
+// threshold возвращает порог прогрессивной шкалы или 0 для единой ставки
+func (m Model) threshold() float64 {
+	for _, t := range thresholds {
+		if m.Year >= t.year {
+			return t.limit
+		}
+	}
+	return 0
+}
+
+// CouponKind возвращает вид дохода по купонам облигации с типом бумаги Мосбиржи secType и кодом isin
+func CouponKind(secType, isin string) Income {
+	if govSecTypes[secType] || strings.HasPrefix(isin, "SU") {
+		return GovCoupon
+	}
+	return Coupon
+}
+
+// DividendKind возвращает вид дохода по дивидендам акции с кодом isin
+func DividendKind(isin string) Income {
+	if isin != "" && !strings.HasPrefix(isin, "RU") {
+		return ForeignDividend
+	}
+	return Dividend
+}
@@ -0,0 +1,92 @@
+package tax
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModel_Rate(t *testing.T) {
+	tests := []struct {
+		name  string
+		model Model
+		kind  Income
+		want  float64
+	}{
+		{"flat before 2021", Model{Year: 2020, Resident: true, Income: 10_000_000}, CapitalGain, 0.13},
+		{"gov coupon before 2021", Model{Year: 2020, Resident: true}, GovCoupon, 0},
+		{"gov coupon since 2021", Model{Year: 2021, Resident: true}, GovCoupon, 0.13},
+		{"below threshold 2024", Model{Year: 2024, Resident: true, Income: 4_999_999}, Coupon, 0.13},
+		{"above threshold 2024", Model{Year: 2024, Resident: true, Income: 5_000_000}, Coupon, 0.15},
+		{"above threshold 2025", Model{Year: 2025, Resident: true, Income: 3_000_000}, Dividend, 0.15},
+		{"foreign dividend", Model{Year: 2025, Resident: true}, ForeignDividend, 0.13},
+		{"non-resident", Model{Year: 2025}, Coupon, 0.30},
+		{"non-resident dividend", Model{Year: 2025}, Dividend, 0.15},
+		{"non-resident foreign dividend", Model{Year: 2025}, ForeignDividend, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.model.Rate(tt.kind))
+		})
+	}
+}
+
+func TestModel_Tax(t *testing.T) {
+	tests := []struct {
+		name  string
+		model Model
+		kind  Income
+		base  float64
+		want  float64
+	}{
+		{"flat", Model{Year: 2020, Resident: true}, CapitalGain, 10_000_000, 1_300_000},
+		{"progressive", Model{Year: 2025, Resident: true, Income: 2_000_000}, CapitalGain, 1_000_000, 400_000*0.13 + 600_000*0.15},
+		{"above threshold", Model{Year: 2024, Resident: true, Income: 6_000_000}, Coupon, 100_000, 15_000},
+		{"exempt", Model{Year: 2019, Resident: true}, GovCoupon, 100_000, 0},
+		{"loss", Model{Year: 2024, Resident: true}, CapitalGain, -100, 0},
+		{"non-resident", Model{Year: 2024, Income: 10_000_000}, Dividend, 1000, 150},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.want, tt.model.Tax(tt.kind, tt.base), 1e-6)
+		})
+	}
+}
+
+func TestModel_LongTermExempt(t *testing.T) {
+	date := func(s string) time.Time {
+		d, err := time.Parse(time.DateOnly, s)
+		require.NoError(t, err)
+		return d
+	}
+	tests := []struct {
+		name   string
+		model  Model
+		bought string
+		sold   string
+		want   bool
+	}{
+		{"over 3 years", Model{Resident: true}, "2020-03-01", "2023-03-02", true},
+		{"exactly 3 years", Model{Resident: true}, "2020-03-01", "2023-03-01", false},
+		{"bought before 2014", Model{Resident: true}, "2013-12-31", "2020-01-01", false},
+		{"non-resident", Model{}, "2020-03-01", "2024-03-01", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.model.LongTermExempt(date(tt.bought), date(tt.sold)))
+		})
+	}
+}
+
+func TestIncomeKind(t *testing.T) {
+	assert.Equal(t, GovCoupon, CouponKind("3", "RU000A0JX0J2"))
+	assert.Equal(t, GovCoupon, CouponKind("", "SU26238RMFS4"))
+	assert.Equal(t, Coupon, CouponKind("8", "RU000A100YL8"))
+	assert.Equal(t, Dividend, DividendKind("RU0009029540"))
+	assert.Equal(t, ForeignDividend, DividendKind("US88160R1014"))
+}